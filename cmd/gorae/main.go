@@ -3,8 +3,7 @@ package main
 import (
 	"flag"
 	"log"
-	"path/filepath"
-	"strings"
+	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
 
@@ -14,52 +13,65 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		runDoctor(os.Args[2:])
+		return
+	}
+
 	rootFlag := flag.String("root", "", "Root directory to start in (overrides config watch_dir)")
+	libraryFlag := flag.String("library", "", "Library profile to open (see \"libraries\" in the config)")
 	flag.Parse()
 
-	cfg, err := config.LoadOrInit()
+	cfg, store := openLibrary(*libraryFlag, *rootFlag)
+	m := app.NewModel(cfg, store)
+
+	opts := []tea.ProgramOption{tea.WithAltScreen()}
+	if cfg != nil && cfg.EnableMouse {
+		opts = append(opts, tea.WithMouseCellMotion())
+	}
+	p := tea.NewProgram(m, opts...)
+	final, err := p.Run()
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	origWatch := cfg.WatchDir
-	root := cfg.WatchDir
-	if *rootFlag != "" {
-		root = *rootFlag
+	// :library switch replaces the store, so close whichever one is open now.
+	if fm, ok := final.(app.Model); ok {
+		_ = fm.Close()
 	}
-	cfg.WatchDir = root
-	if *rootFlag != "" {
-		defaultOldRecent := filepath.Join(origWatch, "_recent")
-		legacyAdded := filepath.Join(origWatch, "_recently_added")
-		defaultAdded := filepath.Join(origWatch, "Recently Added")
-		trimmedRecent := strings.TrimSpace(cfg.RecentlyAddedDir)
-		if trimmedRecent == "" || trimmedRecent == defaultOldRecent || trimmedRecent == legacyAdded || trimmedRecent == defaultAdded {
-			cfg.RecentlyAddedDir = filepath.Join(root, "Recently Added")
-		}
+}
 
-		legacyOpened := filepath.Join(origWatch, "_recently_opened")
-		defaultOpened := filepath.Join(origWatch, "Recently Read")
-		trimmedOpened := strings.TrimSpace(cfg.RecentlyOpenedDir)
-		if trimmedOpened == "" || trimmedOpened == legacyOpened || trimmedOpened == defaultOpened {
-			cfg.RecentlyOpenedDir = filepath.Join(root, "Recently Read")
-		}
+// runDoctor implements `gorae doctor [--fix] [--library name] [--root dir]`.
+func runDoctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	rootFlag := fs.String("root", "", "Library root to check (overrides config watch_dir)")
+	libraryFlag := fs.String("library", "", "Library profile to check")
+	fix := fs.Bool("fix", false, "Interactively relink, prune or archive each finding")
+	fs.Parse(args)
+
+	cfg, store := openLibrary(*libraryFlag, *rootFlag)
+	defer store.Close()
+	if err := app.RunDoctor(cfg, store, os.Stdin, os.Stdout, *fix); err != nil {
+		log.Fatal(err)
 	}
+}
 
-	dbPath := filepath.Join(cfg.MetaDir, "metadata.db")
-	store, err := meta.Open(dbPath)
+// openLibrary loads the config, switches to the named library profile,
+// applies a --root override and opens the metadata store.
+func openLibrary(library, rootOverride string) (*config.Config, *meta.Store) {
+	cfg, err := config.LoadOrInit()
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer store.Close()
-
-	m := app.NewModel(cfg, store)
-
-	opts := []tea.ProgramOption{tea.WithAltScreen()}
-	if cfg != nil && cfg.EnableMouse {
-		opts = append(opts, tea.WithMouseCellMotion())
+	if library != "" {
+		if cfg, err = cfg.ForLibrary(library); err != nil {
+			log.Fatal(err)
+		}
 	}
-	p := tea.NewProgram(m, opts...)
-	if _, err := p.Run(); err != nil {
+	cfg = cfg.WithRoot(rootOverride)
+
+	store, err := meta.Open(cfg.DBPath())
+	if err != nil {
 		log.Fatal(err)
 	}
+	return cfg, store
 }
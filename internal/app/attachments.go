@@ -0,0 +1,247 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"gorae/internal/meta"
+)
+
+// handleAttachCommand implements :attach, which manages the supplementary
+// files and links of the file under the cursor.
+func (m *Model) handleAttachCommand(args []string) tea.Cmd {
+	if m.meta == nil {
+		m.setStatus("Metadata store not available")
+		return nil
+	}
+	owner := canonicalPath(m.currentEntryPath())
+	if owner == "" {
+		m.setStatus("No file selected")
+		return nil
+	}
+	if len(args) == 0 {
+		return m.listAttachments(owner)
+	}
+	sub := strings.ToLower(args[0])
+	rest := args[1:]
+	switch sub {
+	case "list", "ls":
+		return m.listAttachments(owner)
+	case "selected", "marked":
+		paths := m.selectedPaths()
+		if len(paths) == 0 {
+			m.setStatus("No marked files to attach")
+			return nil
+		}
+		added := 0
+		for _, path := range paths {
+			target := canonicalPath(path)
+			if target == owner {
+				continue
+			}
+			if err := m.meta.AddAttachment(context.Background(), meta.Attachment{Owner: owner, Target: target}); err != nil {
+				m.setStatus("Failed to attach: " + err.Error())
+				return nil
+			}
+			added++
+		}
+		m.setStatus(fmt.Sprintf("Attached %d file(s) to %s", added, filepath.Base(owner)))
+	case "remove", "rm":
+		att, ok := m.attachmentArg(owner, rest, "Usage: :attach remove <n>")
+		if !ok {
+			return nil
+		}
+		if _, err := m.meta.RemoveAttachment(context.Background(), owner, att.Target); err != nil {
+			m.setStatus("Failed to remove attachment: " + err.Error())
+			return nil
+		}
+		m.setStatus("Detached " + attachmentName(att))
+	case "open":
+		att, ok := m.attachmentArg(owner, rest, "Usage: :attach open <n>")
+		if !ok {
+			return nil
+		}
+		m.openAttachment(att)
+	default:
+		target := args[0]
+		if meta.AttachmentKind(target) == meta.AttachmentFile {
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(m.cwd, target)
+			}
+			target = canonicalPath(target)
+			if info, err := os.Stat(target); err != nil {
+				m.setStatus("Cannot attach: " + err.Error())
+				return nil
+			} else if info.IsDir() {
+				m.setStatus("Cannot attach a directory")
+				return nil
+			}
+		}
+		att := meta.Attachment{Owner: owner, Target: target, Label: strings.Join(rest, " ")}
+		if err := m.meta.AddAttachment(context.Background(), att); err != nil {
+			m.setStatus("Failed to attach: " + err.Error())
+			return nil
+		}
+		m.setStatus(fmt.Sprintf("Attached %s to %s", attachmentName(att), filepath.Base(owner)))
+	}
+	return nil
+}
+
+func (m *Model) listAttachments(owner string) tea.Cmd {
+	list, err := m.meta.Attachments(context.Background(), owner)
+	if err != nil {
+		m.setStatus("Failed to load attachments: " + err.Error())
+		return nil
+	}
+	if len(list) == 0 {
+		m.setStatus("No attachments on " + filepath.Base(owner))
+		return nil
+	}
+	lines := []string{fmt.Sprintf("Attachments of %s (%d):", filepath.Base(owner), len(list))}
+	lines = append(lines, attachmentLines(list)...)
+	m.setCommandOutput(lines)
+	m.setStatus("Listed attachments (:attach open <n> to open one)")
+	return nil
+}
+
+// attachmentArg resolves the 1-based attachment number in args.
+func (m *Model) attachmentArg(owner string, args []string, usage string) (meta.Attachment, bool) {
+	if len(args) != 1 {
+		m.setStatus(usage)
+		return meta.Attachment{}, false
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		m.setStatus(usage)
+		return meta.Attachment{}, false
+	}
+	list, err := m.meta.Attachments(context.Background(), owner)
+	if err != nil {
+		m.setStatus("Failed to load attachments: " + err.Error())
+		return meta.Attachment{}, false
+	}
+	if n < 1 || n > len(list) {
+		m.setStatus(fmt.Sprintf("No attachment %d (%d attached)", n, len(list)))
+		return meta.Attachment{}, false
+	}
+	return list[n-1], true
+}
+
+func (m *Model) loadAttachments(path string) []meta.Attachment {
+	if m.meta == nil || path == "" {
+		return nil
+	}
+	list, err := m.meta.Attachments(context.Background(), path)
+	if err != nil {
+		return nil
+	}
+	return list
+}
+
+// openAttachment opens documents in the PDF viewer, like any other paper,
+// and everything else with the system opener.
+func (m *Model) openAttachment(att meta.Attachment) {
+	if att.Kind == meta.AttachmentFile {
+		if _, err := os.Stat(att.Target); err != nil {
+			m.setStatus("Attachment missing: " + att.Target)
+			return
+		}
+		if isDocument(att.Target) {
+			if err := m.openPDF(att.Target); err != nil {
+				m.setStatus("Failed to open attachment: " + err.Error())
+				return
+			}
+			m.setStatus("Opened " + attachmentName(att))
+			return
+		}
+	}
+	parts, err := splitCommandLine(systemOpener())
+	if err != nil || len(parts) == 0 {
+		m.setStatus("No system opener available")
+		return
+	}
+	cmd := exec.Command(parts[0], append(parts[1:], att.Target)...)
+	if err := cmd.Start(); err != nil {
+		m.setStatus("Failed to open attachment: " + err.Error())
+		return
+	}
+	go func() { _ = cmd.Wait() }()
+	m.setStatus("Opened " + attachmentName(att))
+}
+
+func systemOpener() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "open"
+	case "windows":
+		return "rundll32 url.dll,FileProtocolHandler"
+	default:
+		return "xdg-open"
+	}
+}
+
+// moveAttachmentsAlong moves the file attachments that sat next to a paper
+// into the paper's new directory. Attachments kept elsewhere stay put.
+func (m *Model) moveAttachmentsAlong(oldPath, newPath string) error {
+	oldDir, newDir := filepath.Dir(oldPath), filepath.Dir(newPath)
+	if oldDir == newDir {
+		return nil
+	}
+	ctx := context.Background()
+	list, err := m.meta.Attachments(ctx, newPath)
+	if err != nil {
+		return err
+	}
+	for _, att := range list {
+		if att.Kind != meta.AttachmentFile || filepath.Dir(att.Target) != oldDir {
+			continue
+		}
+		if _, err := os.Stat(att.Target); err != nil {
+			continue
+		}
+		dest := avoidNameClash(filepath.Join(newDir, filepath.Base(att.Target)))
+		if err := os.Rename(att.Target, dest); err != nil {
+			return fmt.Errorf("move attachment %s: %w", filepath.Base(att.Target), err)
+		}
+		if err := m.meta.MovePath(ctx, att.Target, dest); err != nil {
+			return err
+		}
+		moveNoteFile(m.notesDir, att.Target, dest)
+	}
+	return nil
+}
+
+func attachmentName(att meta.Attachment) string {
+	if att.Label != "" {
+		return att.Label
+	}
+	if att.Kind == meta.AttachmentFile {
+		return filepath.Base(att.Target)
+	}
+	return att.Target
+}
+
+// attachmentLines renders numbered attachments for the popup and :attach list.
+func attachmentLines(list []meta.Attachment) []string {
+	lines := make([]string, 0, len(list))
+	for i, att := range list {
+		line := fmt.Sprintf("  %d. %s", i+1, attachmentName(att))
+		if att.Kind == meta.AttachmentURL {
+			if att.Label != "" {
+				line += " <" + att.Target + ">"
+			}
+		} else if _, err := os.Stat(att.Target); err != nil {
+			line += " (missing)"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
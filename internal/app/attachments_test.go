@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorae/internal/config"
+	"gorae/internal/meta"
+)
+
+func TestAttachmentsMoveWithTheirPaper(t *testing.T) {
+	base := canonicalPath(t.TempDir())
+	root := filepath.Join(base, "library")
+	archive := filepath.Join(root, "archive")
+	metaDir := filepath.Join(base, "meta")
+	for _, dir := range []string{archive, metaDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+	paper := filepath.Join(root, "paper.pdf")
+	slides := filepath.Join(root, "slides.pdf")
+	elsewhere := filepath.Join(base, "code.zip")
+	writeDummyPDF(t, paper)
+	writeDummyPDF(t, slides)
+	if err := os.WriteFile(elsewhere, []byte("zip"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	// Something already called slides.pdf in the destination.
+	writeDummyPDF(t, filepath.Join(archive, "slides.pdf"))
+
+	cfg := &config.Config{WatchDir: root, MetaDir: metaDir, NotesDir: filepath.Join(metaDir, "notes")}
+	store, err := meta.Open(cfg.DBPath())
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	m := NewModel(cfg, store)
+	ctx := context.Background()
+	for _, target := range []string{slides, elsewhere, "https://example.org/talk"} {
+		if err := store.AddAttachment(ctx, meta.Attachment{Owner: paper, Target: target}); err != nil {
+			t.Fatalf("attach: %v", err)
+		}
+	}
+
+	moved := filepath.Join(archive, "paper.pdf")
+	if err := os.Rename(paper, moved); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if err := m.moveMetadataPaths(paper, moved, false); err != nil {
+		t.Fatalf("move metadata: %v", err)
+	}
+
+	list, err := store.Attachments(ctx, moved)
+	if err != nil || len(list) != 3 {
+		t.Fatalf("expected 3 attachments on the moved paper, got %+v (%v)", list, err)
+	}
+	wantSlides := filepath.Join(archive, "slides (1).pdf")
+	if list[0].Target != wantSlides || list[1].Target != elsewhere {
+		t.Fatalf("unexpected targets: %s, %s", list[0].Target, list[1].Target)
+	}
+	if _, err := os.Stat(wantSlides); err != nil {
+		t.Fatalf("expected slides to move along: %v", err)
+	}
+	if _, err := os.Stat(elsewhere); err != nil {
+		t.Fatalf("expected attachments outside the paper's directory to stay: %v", err)
+	}
+}
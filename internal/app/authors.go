@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (m *Model) handleAuthorsCommand(args []string) tea.Cmd {
+	if m.meta == nil {
+		m.setStatus("Metadata store not available")
+		return nil
+	}
+	ctx := context.Background()
+	name := strings.TrimSpace(strings.Join(args, " "))
+	if name == "" {
+		authors, err := m.meta.ListAuthors(ctx)
+		if err != nil {
+			m.setStatus("Failed to load authors: " + err.Error())
+			return nil
+		}
+		if len(authors) == 0 {
+			m.setStatus("No authors recorded yet")
+			return nil
+		}
+		lines := make([]string, 0, len(authors)+1)
+		lines = append(lines, fmt.Sprintf("Authors (%d):", len(authors)))
+		for _, a := range authors {
+			lines = append(lines, fmt.Sprintf("  %-40s %d", a.Full, a.Papers))
+		}
+		m.setCommandOutput(lines)
+		m.setStatus(fmt.Sprintf("Listed %d author(s)", len(authors)))
+		return nil
+	}
+
+	list, err := m.meta.ListByAuthor(ctx, name)
+	if err != nil {
+		m.setStatus("Failed to load metadata: " + err.Error())
+		return nil
+	}
+	matches := metadataMatches(list)
+	summary := fmt.Sprintf("Author %q: %d file(s)", name, len(matches))
+	if len(matches) == 0 {
+		summary = fmt.Sprintf("Author %q: no files", name)
+	}
+	m.enterSearchResults(searchResultMsg{
+		req:     searchRequest{},
+		matches: matches,
+		summary: summary,
+	})
+	if len(matches) == 0 {
+		m.setStatus(summary)
+	} else {
+		m.setPersistentStatus(fmt.Sprintf("%s (Esc/q to exit)", summary))
+	}
+	return nil
+}
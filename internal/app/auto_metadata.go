@@ -53,11 +53,18 @@ type fetchedPaperMetadata struct {
 	Identifier string
 	Title      string
 	Authors    []string
+	People     []meta.Author
 	Published  string
 	Year       int
 	URL        string
 	DOI        string
 	Abstract   string
+	// Identifiers are recorded alongside the DOI and URL, e.g. the arXiv ID
+	// in the filename of a paper found through its DOI.
+	Identifiers []meta.Identifier
+	// References is only set for Crossref lookups; arXiv has no reference
+	// lists.
+	References []meta.Reference
 }
 
 type paperIdentifiers struct {
@@ -86,7 +93,7 @@ func (m *Model) handleAutoMetadataCommand(args []string) tea.Cmd {
 		return nil
 	}
 	if len(files) == 0 {
-		m.setStatus("Auto metadata works on PDF files only; select or specify a PDF")
+		m.setStatus("Auto metadata works on PDF and EPUB files only; select or specify one")
 		return nil
 	}
 	m.setPersistentStatus(fmt.Sprintf("Detecting metadata for %d file(s)...", len(files)))
@@ -121,8 +128,8 @@ func (m *Model) resolveAutoMetadataTargets(args []string) ([]string, error) {
 			if err != nil {
 				return nil, err
 			}
-			if !isPDF(resolved) {
-				return nil, fmt.Errorf("%s is not a PDF", filepath.Base(resolved))
+			if !isDocument(resolved) {
+				return nil, fmt.Errorf("%s is not a PDF or EPUB", filepath.Base(resolved))
 			}
 			files = append(files, resolved)
 		}
@@ -131,7 +138,7 @@ func (m *Model) resolveAutoMetadataTargets(args []string) ([]string, error) {
 		if useSelection {
 			targets = m.selectedPaths()
 			if len(targets) == 0 {
-				return nil, fmt.Errorf("Select at least one PDF first")
+				return nil, fmt.Errorf("Select at least one PDF or EPUB first")
 			}
 		} else {
 			targets = m.selectionOrCurrent()
@@ -141,7 +148,7 @@ func (m *Model) resolveAutoMetadataTargets(args []string) ([]string, error) {
 		}
 		files = filterPDFPaths(targets)
 		if len(files) == 0 {
-			return nil, fmt.Errorf("Auto metadata works on PDF files only; select a PDF")
+			return nil, fmt.Errorf("Auto metadata works on PDF and EPUB files only; select one")
 		}
 	}
 	return uniquePaths(files), nil
@@ -154,10 +161,19 @@ func (m *Model) runAutoMetadata(files []string) tea.Cmd {
 		ctx := context.Background()
 		results := make([]autoMetadataResult, 0, len(paths))
 		for _, path := range paths {
-			data, err := detectMetadataForFile(path)
+			data, known, err := detectMetadataForFile(path)
 			res := autoMetadataResult{Path: path}
 			if err != nil {
 				res.Err = err
+				// Identifiers the file carries itself, such as the ISBN of a
+				// book, are kept even when nothing could be fetched online.
+				if len(known) > 0 {
+					if saveErr := saveFileIdentifiers(ctx, store, path, known); saveErr != nil {
+						res.Err = fmt.Errorf("%w; save identifiers: %v", err, saveErr)
+					} else {
+						res.Err = fmt.Errorf("kept %s; %w", meta.FormatIdentifiers(known), err)
+					}
+				}
 				results = append(results, res)
 				continue
 			}
@@ -274,49 +290,74 @@ func (m *Model) autoMetadataSkipDirs() []string {
 	if m.toReadDir != "" {
 		skip = append(skip, m.toReadDir)
 	}
+	if m.collectionsDir != "" {
+		skip = append(skip, m.collectionsDir)
+	}
+	if m.savedSearchesDir != "" {
+		skip = append(skip, m.savedSearchesDir)
+	}
 	if m.notesDir != "" {
 		skip = append(skip, m.notesDir)
 	}
 	return skip
 }
 
-func detectMetadataForFile(path string) (*fetchedPaperMetadata, error) {
-	text, err := samplePDFText(path, autoMetadataMaxPages)
-	if err != nil {
-		return nil, err
+// detectMetadataForFile fetches metadata for the DOI or arXiv ID found in
+// path. known lists the identifiers the file names itself (EPUB OPF
+// identifiers and an arXiv ID in the filename); they are returned even when
+// the fetch fails.
+func detectMetadataForFile(path string) (data *fetchedPaperMetadata, known []meta.Identifier, err error) {
+	var ids paperIdentifiers
+	if isEPUB(path) {
+		found, err := epubIdentifiers(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		known = found
+		ids = identifiersForFetch(found)
+	} else {
+		text, err := samplePDFText(path, autoMetadataMaxPages)
+		if err != nil {
+			return nil, nil, err
+		}
+		ids = extractIdentifiersFromText(text)
 	}
-	ids := extractIdentifiersFromText(text)
-	if ids.Arxiv == "" {
-		if id := extractArxivIDFromFilename(path); id != "" {
+	if id := extractArxivIDFromFilename(path); id != "" {
+		// A filename ID names the file itself, unlike IDs found in the text,
+		// which may belong to a cited paper.
+		known = append(known, meta.Identifier{Scheme: meta.SchemeArxiv, Value: id})
+		if ids.Arxiv == "" {
 			ids.Arxiv = id
 		}
 	}
 	if ids.DOI == "" && ids.Arxiv == "" {
-		return nil, fmt.Errorf("no DOI or arXiv identifier detected")
+		return nil, known, fmt.Errorf("no DOI or arXiv identifier detected")
 	}
 
 	var lastErr error
 	if ids.DOI != "" {
 		meta, err := fetchDOIMetadata(ids.DOI)
 		if err == nil {
-			return meta, nil
+			meta.Identifiers = append(meta.Identifiers, known...)
+			return meta, known, nil
 		}
 		lastErr = fmt.Errorf("DOI %s: %w", ids.DOI, err)
 	}
 	if ids.Arxiv != "" {
 		meta, err := fetchArxivMetadata(ids.Arxiv)
 		if err == nil {
-			return meta, nil
+			meta.Identifiers = append(meta.Identifiers, known...)
+			return meta, known, nil
 		}
 		if lastErr != nil {
-			return nil, fmt.Errorf("%v; arXiv %s: %w", lastErr, ids.Arxiv, err)
+			return nil, known, fmt.Errorf("%v; arXiv %s: %w", lastErr, ids.Arxiv, err)
 		}
-		return nil, fmt.Errorf("arXiv %s: %w", ids.Arxiv, err)
+		return nil, known, fmt.Errorf("arXiv %s: %w", ids.Arxiv, err)
 	}
 	if lastErr != nil {
-		return nil, lastErr
+		return nil, known, lastErr
 	}
-	return nil, fmt.Errorf("no DOI or arXiv identifier detected")
+	return nil, known, fmt.Errorf("no DOI or arXiv identifier detected")
 }
 
 func samplePDFText(path string, maxPages int) (string, error) {
@@ -358,11 +399,13 @@ func fetchDOIMetadata(doi string) (*fetchedPaperMetadata, error) {
 		Identifier: meta.DOI,
 		Title:      meta.Title,
 		Authors:    meta.Authors,
+		People:     crossrefPeopleToAuthors(meta.People),
 		Published:  meta.Published,
 		Year:       meta.Year,
 		URL:        meta.URL,
 		DOI:        meta.DOI,
 		Abstract:   meta.Abstract,
+		References: crossrefReferences(meta.References),
 	}, nil
 }
 
@@ -401,8 +444,9 @@ func applyFetchedMetadata(ctx context.Context, store *meta.Store, path string, d
 	if strings.TrimSpace(data.Title) != "" {
 		md.Title = data.Title
 	}
-	if len(data.Authors) > 0 {
-		md.Author = strings.Join(data.Authors, ", ")
+	if authors := data.authorRecords(); len(authors) > 0 {
+		md.Authors = authors
+		md.Author = meta.FormatAuthors(authors)
 	}
 	if data.Published != "" {
 		md.Published = data.Published
@@ -419,7 +463,54 @@ func applyFetchedMetadata(ctx context.Context, store *meta.Store, path string, d
 	if strings.TrimSpace(data.Abstract) != "" {
 		md.Abstract = data.Abstract
 	}
-	return store.Upsert(ctx, &md)
+	if err := store.UpsertFrom(ctx, &md, data.Source.historySource()); err != nil {
+		return err
+	}
+	if err := store.AddIdentifiers(ctx, path, data.Identifiers); err != nil {
+		return err
+	}
+	if data.Source == metadataSourceDOI {
+		return store.SetReferences(ctx, path, meta.CitationSourceCrossref, data.References)
+	}
+	return nil
+}
+
+// historySource maps a fetch source onto the source recorded in the
+// metadata history.
+func (s metadataSource) historySource() string {
+	if s == metadataSourceArxiv {
+		return meta.SourceArxiv
+	}
+	return meta.SourceCrossref
+}
+
+// authorRecords prefers the structured names a source reports and falls
+// back to the plain name list.
+func (data *fetchedPaperMetadata) authorRecords() []meta.Author {
+	if len(data.People) > 0 {
+		return data.People
+	}
+	return namesToAuthors(data.Authors)
+}
+
+func namesToAuthors(names []string) []meta.Author {
+	out := make([]meta.Author, 0, len(names))
+	for _, name := range names {
+		if a := meta.NewAuthor("", "", name); a.Full != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func crossrefPeopleToAuthors(people []crossref.Person) []meta.Author {
+	out := make([]meta.Author, 0, len(people))
+	for _, p := range people {
+		if a := meta.NewAuthor(p.Given, p.Family, p.Name); a.Full != "" {
+			out = append(out, a)
+		}
+	}
+	return out
 }
 
 func extractIdentifiersFromText(text string) paperIdentifiers {
@@ -433,6 +524,20 @@ func extractIdentifiersFromText(text string) paperIdentifiers {
 	return ids
 }
 
+// identifiersForFetch picks the DOI and arXiv ID to look a paper up by.
+func identifiersForFetch(list []meta.Identifier) paperIdentifiers {
+	ids := paperIdentifiers{}
+	for _, id := range list {
+		switch {
+		case id.Scheme == meta.SchemeDOI && ids.DOI == "":
+			ids.DOI = id.Value
+		case id.Scheme == meta.SchemeArxiv && ids.Arxiv == "":
+			ids.Arxiv = id.Value
+		}
+	}
+	return ids
+}
+
 func extractDOIFromText(text string) string {
 	if match := doiURLPattern.FindStringSubmatch(text); len(match) > 1 {
 		if doi := sanitizeDetectedDOI(match[1]); doi != "" {
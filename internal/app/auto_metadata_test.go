@@ -1,6 +1,13 @@
 package app
 
-import "testing"
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorae/internal/meta"
+)
 
 func TestExtractDOIFromText(t *testing.T) {
 	t.Parallel()
@@ -70,3 +77,36 @@ func TestExtractArxivIDFromString(t *testing.T) {
 		})
 	}
 }
+
+func TestAutoMetadataKeepsEPUBIdentifiers(t *testing.T) {
+	dir := canonicalPath(t.TempDir())
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	book := filepath.Join(dir, "book.epub")
+	writeTestEPUBWithMetadata(t, book, `<dc:identifier opf:scheme="ISBN">978-0-262-03384-8</dc:identifier>`, "Chapter one.")
+
+	m := &Model{cwd: dir, meta: store}
+	files, err := m.resolveAutoMetadataTargets([]string{"book.epub"})
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected :autofetch to accept the EPUB, got %v err=%v", files, err)
+	}
+	msg := m.runAutoMetadata(files)().(autoMetadataMsg)
+	if len(msg.Results) != 1 || msg.Results[0].Err == nil || !strings.Contains(msg.Results[0].Err.Error(), "isbn:") {
+		t.Fatalf("expected a failed fetch that kept the ISBN, got %+v", msg.Results)
+	}
+	md, err := store.Get(context.Background(), book)
+	if err != nil || md == nil {
+		t.Fatalf("expected a metadata row, got %v err=%v", md, err)
+	}
+	if got := meta.FormatIdentifiers(md.Identifiers); got != "isbn:9780262033848" {
+		t.Fatalf("identifiers = %q, want isbn:9780262033848", got)
+	}
+	revisions, err := store.History(context.Background(), book)
+	if err != nil || len(revisions) == 0 || revisions[0].Source != meta.SourceEPUB {
+		t.Fatalf("expected the identifiers to be credited to the EPUB, got %+v (%v)", revisions, err)
+	}
+}
@@ -224,7 +224,11 @@ func determineBibtexType(author, published string) string {
 func buildBibtexKey(md *meta.Metadata, title, path string) string {
 	var parts []string
 	if md != nil {
-		if author := firstAuthorKey(md.Author); author != "" {
+		author := firstAuthorKey(md.Author)
+		if len(md.Authors) > 0 && strings.TrimSpace(md.Authors[0].Family) != "" {
+			author = sanitizeIdentifier(md.Authors[0].Family)
+		}
+		if author != "" {
 			parts = append(parts, author)
 		}
 		if year := extractYear(md.Year); year != "" {
@@ -337,19 +341,5 @@ func normalizeSpaces(s string) string {
 }
 
 func normalizeKeywords(raw string) string {
-	raw = strings.TrimSpace(raw)
-	if raw == "" {
-		return ""
-	}
-	raw = strings.NewReplacer(";", ",", "|", ",").Replace(raw)
-	parts := strings.Split(raw, ",")
-	clean := make([]string, 0, len(parts))
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if part == "" {
-			continue
-		}
-		clean = append(clean, part)
-	}
-	return strings.Join(clean, ", ")
+	return meta.FormatTags(meta.SplitTags(raw))
 }
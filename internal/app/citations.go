@@ -0,0 +1,224 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"gorae/internal/crossref"
+	"gorae/internal/meta"
+)
+
+// citationPanelLimit caps each citation panel of the metadata popup; the
+// full lists are one key away.
+const citationPanelLimit = 8
+
+// arxivCitationPattern only accepts arXiv IDs written as such ("arXiv:1706.03762",
+// "arxiv.org/abs/1706.03762"), since bare numbers like 1706.03762 are too
+// common in running text.
+var arxivCitationPattern = regexp.MustCompile(`(?i)arxiv(?:\.org/abs/|\.org/pdf/|[:\s]\s*)([0-9]{4}\.[0-9]{4,5}|[a-z-]+(?:\.[a-z-]+)?/[0-9]{7})(v[0-9]+)?`)
+
+// citationPanels holds the citation graph around the paper in the popup.
+type citationPanels struct {
+	references []meta.Metadata // cited papers in the library
+	total      int             // all cited works, in the library or not
+	citedBy    []meta.Metadata
+}
+
+type citationScanMsg struct {
+	scanned int
+	found   int
+	err     error
+}
+
+// extractReferencesFromText returns every DOI and arXiv ID mentioned in text.
+func extractReferencesFromText(text string) []meta.Reference {
+	seen := make(map[meta.Reference]bool)
+	refs := make([]meta.Reference, 0)
+	add := func(kind, id string) {
+		r, ok := meta.NormalizeReference(meta.Reference{Kind: kind, ID: id})
+		if !ok || seen[r] {
+			return
+		}
+		seen[r] = true
+		refs = append(refs, r)
+	}
+	for _, match := range doiBarePattern.FindAllString(text, -1) {
+		add(meta.RefDOI, sanitizeDetectedDOI(match))
+	}
+	for _, match := range arxivCitationPattern.FindAllStringSubmatch(text, -1) {
+		add(meta.RefArxiv, match[1])
+	}
+	return refs
+}
+
+// crossrefReferences turns a Crossref reference list into identifiers.
+// Entries without a DOI fall back to identifiers in their citation text.
+func crossrefReferences(list []crossref.Reference) []meta.Reference {
+	refs := make([]meta.Reference, 0, len(list))
+	for _, r := range list {
+		if r.DOI != "" {
+			refs = append(refs, meta.Reference{Kind: meta.RefDOI, ID: r.DOI})
+			continue
+		}
+		refs = append(refs, extractReferencesFromText(r.Unstructured)...)
+	}
+	return refs
+}
+
+func (m *Model) loadCitationPanels(path string) citationPanels {
+	var panels citationPanels
+	if m.meta == nil || path == "" {
+		return panels
+	}
+	ctx := context.Background()
+	if refs, err := m.meta.References(ctx, path); err == nil {
+		panels.references = refs
+	}
+	if n, err := m.meta.ReferenceCount(ctx, path); err == nil {
+		panels.total = n
+	}
+	if citing, err := m.meta.CitedBy(ctx, path); err == nil {
+		panels.citedBy = citing
+	}
+	return panels
+}
+
+// citationPanelLines renders the two citation panels of the popup.
+func citationPanelLines(panels citationPanels) []string {
+	lines := []string{"", fmt.Sprintf("References in library (%d of %d cited):", len(panels.references), panels.total)}
+	lines = append(lines, citationListLines(panels.references, "(none - :citations scan reads them from the PDF)")...)
+	lines = append(lines, "", fmt.Sprintf("Cited by (in library): %d", len(panels.citedBy)))
+	lines = append(lines, citationListLines(panels.citedBy, "(none)")...)
+	return lines
+}
+
+func citationListLines(list []meta.Metadata, empty string) []string {
+	if len(list) == 0 {
+		return []string{"  " + empty}
+	}
+	lines := make([]string, 0, min(len(list), citationPanelLimit)+1)
+	for i, md := range list {
+		if i == citationPanelLimit {
+			lines = append(lines, fmt.Sprintf("  ... %d more", len(list)-citationPanelLimit))
+			break
+		}
+		lines = append(lines, "  "+citationLabel(md))
+	}
+	return lines
+}
+
+func citationLabel(md meta.Metadata) string {
+	title := strings.TrimSpace(md.Title)
+	if title == "" {
+		title = filepath.Base(md.Path)
+	}
+	if year := strings.TrimSpace(md.Year); year != "" {
+		return fmt.Sprintf("%s (%s)", title, year)
+	}
+	return title
+}
+
+func (m *Model) handleCitationsCommand(args []string) tea.Cmd {
+	if m.meta == nil {
+		m.setStatus("Metadata store not available")
+		return nil
+	}
+	if len(args) == 0 {
+		m.setStatus("Usage: :citations refs | cited | scan")
+		return nil
+	}
+	switch strings.ToLower(args[0]) {
+	case "refs", "references":
+		return m.showCitations(canonicalPath(m.currentEntryPath()), false)
+	case "cited", "citedby", "cited-by":
+		return m.showCitations(canonicalPath(m.currentEntryPath()), true)
+	case "scan":
+		var paths []string
+		for _, path := range m.canonicalFilePaths(m.selectionOrCurrent()) {
+			if isDocument(path) {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) == 0 {
+			m.setStatus("No documents selected")
+			return nil
+		}
+		m.setPersistentStatus(fmt.Sprintf("Scanning %d file(s) for references...", len(paths)))
+		return scanCitationsCmd(m.meta, paths)
+	default:
+		m.setStatus(fmt.Sprintf("Unknown citations command: %s", args[0]))
+		return nil
+	}
+}
+
+// showCitations lists the papers path cites (or that cite it) in the results
+// view, so Enter jumps to them.
+func (m *Model) showCitations(path string, citedBy bool) tea.Cmd {
+	if path == "" {
+		m.setStatus("No file selected")
+		return nil
+	}
+	ctx := context.Background()
+	var (
+		list  []meta.Metadata
+		err   error
+		label = "References in library"
+	)
+	if citedBy {
+		list, err = m.meta.CitedBy(ctx, path)
+		label = "Cited by (in library)"
+	} else {
+		list, err = m.meta.References(ctx, path)
+	}
+	if err != nil {
+		m.setStatus("Failed to load citations: " + err.Error())
+		return nil
+	}
+	summary := fmt.Sprintf("%s of %s: %d file(s)", label, filepath.Base(path), len(list))
+	if len(list) == 0 {
+		m.setStatus(summary)
+		return nil
+	}
+	m.state = stateNormal
+	m.metaEditingPath = ""
+	m.enterSearchResults(searchResultMsg{matches: metadataMatches(list), summary: summary})
+	m.setPersistentStatus(summary + " (Esc/q to exit)")
+	return nil
+}
+
+// scanCitationsCmd extracts the full text of each file and stores the
+// identifiers it mentions as the file's text references.
+func scanCitationsCmd(store *meta.Store, paths []string) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		msg := citationScanMsg{}
+		for _, path := range paths {
+			text, err := readDocumentText(path)
+			if err != nil {
+				msg.err = fmt.Errorf("%s: %w", filepath.Base(path), err)
+				continue
+			}
+			refs := extractReferencesFromText(text)
+			if err := store.SetReferences(ctx, path, meta.CitationSourceText, refs); err != nil {
+				msg.err = err
+				return msg
+			}
+			msg.scanned++
+			msg.found += len(refs)
+		}
+		return msg
+	}
+}
+
+func (m *Model) handleCitationScanFinished(msg citationScanMsg) {
+	status := fmt.Sprintf("Scanned %d file(s), %d reference(s) found", msg.scanned, msg.found)
+	if msg.err != nil {
+		status += "; last error: " + msg.err.Error()
+	}
+	m.setStatus(status)
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"testing"
+
+	"gorae/internal/crossref"
+	"gorae/internal/meta"
+)
+
+func TestExtractReferencesFromText(t *testing.T) {
+	text := `References
+[1] A. Vaswani et al. Attention is all you need. arXiv:1706.03762v5, 2017.
+[2] K. He et al. Deep residual learning. In CVPR, 2016. doi:10.1109/CVPR.2016.90.
+[3] See https://arxiv.org/abs/1810.04805 and https://doi.org/10.1109/cvpr.2016.90
+Table 3 reports 2017.12345 tokens per second.`
+
+	got := extractReferencesFromText(text)
+	want := []meta.Reference{
+		{Kind: meta.RefDOI, ID: "10.1109/cvpr.2016.90"},
+		{Kind: meta.RefArxiv, ID: "1706.03762"},
+		{Kind: meta.RefArxiv, ID: "1810.04805"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCrossrefReferencesFallBackToCitationText(t *testing.T) {
+	got := crossrefReferences([]crossref.Reference{
+		{DOI: "10.1000/XYZ"},
+		{Unstructured: "Devlin et al. BERT. arXiv preprint arXiv:1810.04805, 2018."},
+		{Unstructured: "An old book without identifiers."},
+	})
+	if len(got) != 2 || got[0].ID != "10.1000/XYZ" || got[1] != (meta.Reference{Kind: meta.RefArxiv, ID: "1810.04805"}) {
+		t.Fatalf("unexpected references: %v", got)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	tea "github.com/charmbracelet/bubbletea"
+
 	"gorae/internal/meta"
 )
 
@@ -23,6 +25,173 @@ func (m *Model) syncCollectionDirectories() error {
 	if err := syncMetadataLinkDirectory(ctx, m.toReadDir, m.meta.ListToRead); err != nil {
 		return err
 	}
+	return syncNamedCollectionDirectories(ctx, m.collectionsDir, m.meta)
+}
+
+// syncNamedCollectionDirectories mirrors every named collection into its own
+// folder under dir and removes folders of collections that no longer exist.
+func syncNamedCollectionDirectories(ctx context.Context, dir string, store *meta.Store) error {
+	if dir == "" || store == nil {
+		return nil
+	}
+	collections, err := store.ListCollections(ctx)
+	if err != nil {
+		return err
+	}
+	if len(collections) == 0 {
+		if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+	}
+	keep := make(map[string]bool, len(collections))
+	for _, c := range collections {
+		name := c.Name
+		keep[name] = true
+		fetch := func(ctx context.Context) ([]meta.Metadata, error) {
+			return store.ListCollection(ctx, name)
+		}
+		if err := syncMetadataLinkDirectory(ctx, filepath.Join(dir, name), fetch); err != nil {
+			return err
+		}
+	}
+	return pruneLinkDirectories(dir, keep)
+}
+
+// pruneLinkDirectories empties and removes the folders under dir whose name
+// is not in keep.
+func pruneLinkDirectories(dir string, keep map[string]bool) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || keep[entry.Name()] {
+			continue
+		}
+		stale := filepath.Join(dir, entry.Name())
+		if err := reconcileLinkDirectory(stale, nil); err != nil {
+			return err
+		}
+		// Only succeeds once the folder holds nothing but our symlinks.
+		_ = os.Remove(stale)
+	}
+	return nil
+}
+
+func (m *Model) handleCollectionCommand(args []string) tea.Cmd {
+	if m.meta == nil {
+		m.setStatus("Metadata store not available")
+		return nil
+	}
+	if len(args) == 0 {
+		return m.listCollections()
+	}
+	ctx := context.Background()
+	sub := strings.ToLower(args[0])
+	name := strings.TrimSpace(strings.Join(args[1:], " "))
+	if sub != "list" && sub != "ls" && name == "" {
+		m.setStatus(fmt.Sprintf("Usage: :collection %s <name>", sub))
+		return nil
+	}
+	switch sub {
+	case "list", "ls":
+		return m.listCollections()
+	case "create", "new":
+		if err := m.meta.CreateCollection(ctx, name); err != nil {
+			m.setStatus("Failed to create collection: " + err.Error())
+			return nil
+		}
+		m.setStatus(fmt.Sprintf("Created collection %q", name))
+	case "delete", "rm":
+		if err := m.meta.DeleteCollection(ctx, name); err != nil {
+			m.setStatus("Failed to delete collection: " + err.Error())
+			return nil
+		}
+		m.setStatus(fmt.Sprintf("Deleted collection %q", name))
+	case "add", "remove":
+		paths := m.canonicalFilePaths(m.selectionOrCurrent())
+		if len(paths) == 0 {
+			m.setStatus("No files selected")
+			return nil
+		}
+		var (
+			n   int
+			err error
+		)
+		if sub == "add" {
+			n, err = m.meta.AddToCollection(ctx, name, paths...)
+		} else {
+			n, err = m.meta.RemoveFromCollection(ctx, name, paths...)
+		}
+		if err != nil {
+			if errors.Is(err, meta.ErrCollectionNotFound) && sub == "add" {
+				m.setStatus(fmt.Sprintf("No collection %q (create it with :collection create %s)", name, name))
+				return nil
+			}
+			m.setStatus(fmt.Sprintf("Failed to %s files: %v", sub, err))
+			return nil
+		}
+		if sub == "add" {
+			m.setStatus(fmt.Sprintf("Added %d file(s) to %q", n, name))
+		} else {
+			m.setStatus(fmt.Sprintf("Removed %d file(s) from %q", n, name))
+		}
+	case "show":
+		return m.showCollection(name)
+	default:
+		m.setStatus(fmt.Sprintf("Unknown collection command: %s", sub))
+		return nil
+	}
+	if err := m.syncCollectionDirectories(); err != nil {
+		m.setStatus("Failed to sync collection directories: " + err.Error())
+	}
+	return nil
+}
+
+func (m *Model) listCollections() tea.Cmd {
+	collections, err := m.meta.ListCollections(context.Background())
+	if err != nil {
+		m.setStatus("Failed to load collections: " + err.Error())
+		return nil
+	}
+	if len(collections) == 0 {
+		m.setStatus("No collections yet (create one with :collection create <name>)")
+		return nil
+	}
+	lines := make([]string, 0, len(collections)+1)
+	lines = append(lines, fmt.Sprintf("Collections (%d):", len(collections)))
+	for _, c := range collections {
+		lines = append(lines, fmt.Sprintf("  %-40s %d", c.Name, c.Papers))
+	}
+	m.setCommandOutput(lines)
+	m.setStatus(fmt.Sprintf("Listed %d collection(s)", len(collections)))
+	return nil
+}
+
+func (m *Model) showCollection(name string) tea.Cmd {
+	list, err := m.meta.ListCollection(context.Background(), name)
+	if err != nil {
+		m.setStatus("Failed to load collection: " + err.Error())
+		return nil
+	}
+	matches := metadataMatches(list)
+	summary := fmt.Sprintf("Collection %q: %d file(s)", name, len(matches))
+	if len(matches) == 0 {
+		summary = fmt.Sprintf("Collection %q: no files", name)
+	}
+	m.enterSearchResults(searchResultMsg{
+		req:     searchRequest{},
+		matches: matches,
+		summary: summary,
+	})
+	if len(matches) == 0 {
+		m.setStatus(summary)
+	} else {
+		m.setPersistentStatus(fmt.Sprintf("%s (Esc/q to exit)", summary))
+	}
 	return nil
 }
 
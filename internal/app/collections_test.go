@@ -0,0 +1,48 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestSyncNamedCollectionDirectories(t *testing.T) {
+	dir := t.TempDir()
+	collections := filepath.Join(dir, "Collections")
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	paper := filepath.Join(dir, "paper.pdf")
+	writeDummyPDF(t, paper)
+	if err := store.CreateCollection(ctx, "reading group"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := store.AddToCollection(ctx, "reading group", canonicalPath(paper)); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	if err := syncNamedCollectionDirectories(ctx, collections, store); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	targets := listSymlinkTargets(t, filepath.Join(collections, "reading group"))
+	if len(targets) != 1 || targets[0] != canonicalPath(paper) {
+		t.Fatalf("unexpected collection links: %v", targets)
+	}
+
+	if err := store.DeleteCollection(ctx, "reading group"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := syncNamedCollectionDirectories(ctx, collections, store); err != nil {
+		t.Fatalf("sync after delete: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(collections, "reading group")); !os.IsNotExist(err) {
+		t.Fatalf("expected folder of deleted collection to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,569 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"gorae/internal/config"
+	"gorae/internal/meta"
+)
+
+// doctorKind classifies a library integrity problem found by :doctor.
+type doctorKind string
+
+const (
+	doctorOrphanMetadata doctorKind = "orphan metadata"
+	doctorOrphanNote     doctorKind = "orphan note"
+	doctorDanglingLink   doctorKind = "dangling link"
+	doctorDuplicatePath  doctorKind = "duplicate path"
+	doctorUnreadable     doctorKind = "unreadable"
+)
+
+// doctorAction is a fix that can be applied to a finding.
+type doctorAction string
+
+const (
+	doctorRelink  doctorAction = "relink"
+	doctorPrune   doctorAction = "prune"
+	doctorArchive doctorAction = "archive"
+)
+
+type doctorFinding struct {
+	Kind   doctorKind
+	Path   string
+	Detail string
+	// RelinkTo is the file an orphan metadata row can be moved to, found by
+	// content hash, or the canonical path of a duplicate with no twin there.
+	RelinkTo string
+}
+
+// actions lists the fixes that make sense for the finding.
+func (f doctorFinding) actions() []doctorAction {
+	switch f.Kind {
+	case doctorOrphanMetadata, doctorDuplicatePath:
+		if f.RelinkTo != "" {
+			return []doctorAction{doctorRelink, doctorPrune, doctorArchive}
+		}
+		return []doctorAction{doctorPrune, doctorArchive}
+	case doctorOrphanNote:
+		return []doctorAction{doctorPrune, doctorArchive}
+	case doctorDanglingLink:
+		return []doctorAction{doctorPrune}
+	case doctorUnreadable:
+		return []doctorAction{doctorArchive}
+	}
+	return nil
+}
+
+func (f doctorFinding) String() string {
+	line := fmt.Sprintf("[%s] %s", f.Kind, f.Path)
+	if f.Detail != "" {
+		line += " — " + f.Detail
+	}
+	if f.RelinkTo != "" && f.Kind == doctorOrphanMetadata {
+		line += " (moved to " + f.RelinkTo + "?)"
+	}
+	return line
+}
+
+// doctor checks the library, the metadata store, notes and helper folders
+// against each other.
+type doctor struct {
+	root       string
+	notesDir   string
+	archiveDir string
+	helperDirs []string
+	store      *meta.Store
+}
+
+var noteFileName = regexp.MustCompile(`^[0-9a-f]{40}\.md$`)
+
+func (m *Model) newDoctor() *doctor {
+	d := &doctor{
+		root:       canonicalPath(m.root),
+		notesDir:   m.notesDir,
+		helperDirs: m.searchSkipDirs(),
+		store:      m.meta,
+	}
+	if m.cfg != nil && strings.TrimSpace(m.cfg.MetaDir) != "" {
+		d.archiveDir = filepath.Join(m.cfg.MetaDir, "archive")
+	}
+	return d
+}
+
+func newDoctorFromConfig(cfg *config.Config, store *meta.Store) *doctor {
+	root := canonicalPath(cfg.WatchDir)
+	d := &doctor{
+		root:     root,
+		notesDir: resolveNotesDir(cfg),
+		store:    store,
+	}
+	if strings.TrimSpace(cfg.MetaDir) != "" {
+		d.archiveDir = filepath.Join(cfg.MetaDir, "archive")
+	}
+	for _, dir := range []string{
+		resolveHelperDir(root, cfg.RecentlyAddedDir),
+		resolveHelperDir(root, cfg.RecentlyOpenedDir),
+		filepath.Join(root, favoritesDirName),
+		filepath.Join(root, toReadDirName),
+		filepath.Join(root, collectionsDirName),
+		filepath.Join(root, savedSearchesDirName),
+	} {
+		if dir != "" {
+			d.helperDirs = append(d.helperDirs, dir)
+		}
+	}
+	return d
+}
+
+// scan returns every finding, grouped by kind.
+func (d *doctor) scan(ctx context.Context) ([]doctorFinding, error) {
+	if d.store == nil {
+		return nil, fmt.Errorf("metadata store not available")
+	}
+	records, err := d.store.Query(ctx, meta.Filter{Sort: []meta.Sort{{Field: meta.SortPath}}})
+	if err != nil {
+		return nil, err
+	}
+	files, _, err := collectDocumentFiles(d.root, d.helperDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Listing a directory adds a bare row for each file, so a moved file
+	// usually has one; it is as good as unknown for relinking.
+	bare, err := d.store.BarePaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(records))
+	for _, md := range records {
+		known[md.Path] = !bare[md.Path]
+	}
+	var unknown []string
+	for _, path := range files {
+		if !known[canonicalPath(path)] {
+			unknown = append(unknown, canonicalPath(path))
+		}
+	}
+
+	var findings []doctorFinding
+	notes := make(map[string]bool)
+	byCanonical := make(map[string][]string)
+	for _, md := range records {
+		if name, err := noteFilePathIn(d.notesDir, md.Path); err == nil {
+			notes[filepath.Base(name)] = true
+		}
+		if _, err := os.Stat(md.Path); err != nil {
+			f := doctorFinding{Kind: doctorOrphanMetadata, Path: md.Path, Detail: "file not found"}
+			f.RelinkTo = d.relinkCandidate(ctx, md.Path, unknown)
+			findings = append(findings, f)
+			continue
+		}
+		canonical := canonicalPath(md.Path)
+		byCanonical[canonical] = append(byCanonical[canonical], md.Path)
+	}
+	for _, path := range files {
+		if name, err := noteFilePathIn(d.notesDir, path); err == nil {
+			notes[filepath.Base(name)] = true
+		}
+	}
+
+	canonicals := make([]string, 0, len(byCanonical))
+	for canonical := range byCanonical {
+		canonicals = append(canonicals, canonical)
+	}
+	sort.Strings(canonicals)
+	for _, canonical := range canonicals {
+		// Without a filled-in twin at the canonical path, the first alias
+		// can be moved there.
+		relink := !known[canonical]
+		for _, path := range byCanonical[canonical] {
+			if path == canonical {
+				continue
+			}
+			f := doctorFinding{Kind: doctorDuplicatePath, Path: path, Detail: "same file as " + canonical}
+			if relink {
+				f.Detail = "file is at " + canonical
+				f.RelinkTo = canonical
+				relink = false
+			}
+			findings = append(findings, f)
+		}
+	}
+
+	findings = append(findings, d.orphanNotes(notes)...)
+	findings = append(findings, d.danglingLinks()...)
+	for _, path := range files {
+		if err := checkDocumentReadable(path); err != nil {
+			findings = append(findings, doctorFinding{Kind: doctorUnreadable, Path: path, Detail: err.Error()})
+		}
+	}
+	return findings, nil
+}
+
+// relinkCandidate looks for a file gorae does not know yet with the content
+// hash recorded for path.
+func (d *doctor) relinkCandidate(ctx context.Context, path string, unknown []string) string {
+	id, ok, err := d.store.Identity(ctx, path)
+	if err != nil || !ok || id.Hash == "" {
+		return ""
+	}
+	for _, candidate := range unknown {
+		info, err := os.Stat(candidate)
+		if err != nil || info.Size() != id.Size {
+			continue
+		}
+		if hash, err := hashFile(candidate); err == nil && hash == id.Hash {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func (d *doctor) orphanNotes(used map[string]bool) []doctorFinding {
+	if strings.TrimSpace(d.notesDir) == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(d.notesDir)
+	if err != nil {
+		return nil
+	}
+	var findings []doctorFinding
+	for _, entry := range entries {
+		if entry.IsDir() || !noteFileName.MatchString(entry.Name()) || used[entry.Name()] {
+			continue
+		}
+		findings = append(findings, doctorFinding{
+			Kind:   doctorOrphanNote,
+			Path:   filepath.Join(d.notesDir, entry.Name()),
+			Detail: "matches no paper",
+		})
+	}
+	return findings
+}
+
+func (d *doctor) danglingLinks() []doctorFinding {
+	var findings []doctorFinding
+	for _, dir := range d.helperDirs {
+		_ = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.Type()&fs.ModeSymlink == 0 {
+				return nil
+			}
+			if _, err := os.Stat(path); err != nil {
+				target, _ := os.Readlink(path)
+				findings = append(findings, doctorFinding{
+					Kind:   doctorDanglingLink,
+					Path:   path,
+					Detail: "points to missing " + target,
+				})
+			}
+			return nil
+		})
+	}
+	return findings
+}
+
+// checkDocumentReadable does a cheap structural check: PDFs must start with
+// the PDF header and EPUBs must contain a readable package document.
+func checkDocumentReadable(path string) error {
+	if strings.EqualFold(filepath.Ext(path), ".epub") {
+		_, err := extractEPUBPackage(path)
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	header := make([]byte, 1024)
+	n, err := io.ReadFull(f, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if !bytes.Contains(header[:n], []byte("%PDF-")) {
+		return fmt.Errorf("missing PDF header")
+	}
+	return nil
+}
+
+// fix applies action to f.
+func (d *doctor) fix(ctx context.Context, f doctorFinding, action doctorAction) error {
+	switch {
+	case action == doctorRelink && f.RelinkTo != "":
+		adopted, err := d.store.AdoptPath(ctx, f.Path, f.RelinkTo)
+		if err != nil {
+			return err
+		}
+		if !adopted {
+			return fmt.Errorf("%s already has metadata", f.RelinkTo)
+		}
+		moveNoteFile(d.notesDir, f.Path, f.RelinkTo)
+		return nil
+	case action == doctorPrune:
+		switch f.Kind {
+		case doctorOrphanMetadata, doctorDuplicatePath:
+			if err := d.store.DeletePath(ctx, f.Path); err != nil {
+				return err
+			}
+			if f.Kind == doctorOrphanMetadata {
+				if note, err := noteFilePathIn(d.notesDir, f.Path); err == nil {
+					_ = os.Remove(note)
+				}
+			}
+			return nil
+		case doctorOrphanNote, doctorDanglingLink:
+			return os.Remove(f.Path)
+		}
+	case action == doctorArchive:
+		switch f.Kind {
+		case doctorOrphanMetadata, doctorDuplicatePath:
+			if err := d.archiveMetadata(ctx, f.Path); err != nil {
+				return err
+			}
+			return d.fix(ctx, f, doctorPrune)
+		case doctorOrphanNote:
+			return d.archiveFile(f.Path, "notes")
+		case doctorUnreadable:
+			dest, err := d.archiveDest(f.Path, "files")
+			if err != nil {
+				return err
+			}
+			if err := os.Rename(f.Path, dest); err != nil {
+				return err
+			}
+			return d.store.MovePath(ctx, canonicalPath(f.Path), dest)
+		}
+	}
+	return fmt.Errorf("cannot %s a %s finding", action, f.Kind)
+}
+
+// archiveMetadata writes the record of path, with its note, to the archive
+// directory as JSON.
+func (d *doctor) archiveMetadata(ctx context.Context, path string) error {
+	md, err := d.store.Get(ctx, path)
+	if err != nil || md == nil {
+		return err
+	}
+	record := struct {
+		meta.Metadata
+		Note string `json:",omitempty"`
+	}{Metadata: *md}
+	if note, err := noteFilePathIn(d.notesDir, path); err == nil {
+		if data, err := os.ReadFile(note); err == nil {
+			record.Note = string(data)
+		}
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	dest, err := d.archiveDest(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))+".json", "metadata")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dest, append(data, '\n'), 0o644)
+}
+
+func (d *doctor) archiveFile(path, kind string) error {
+	dest, err := d.archiveDest(path, kind)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path, dest)
+}
+
+// archiveDest returns a free path for name under the archive subdirectory
+// kind, creating the directory.
+func (d *doctor) archiveDest(name, kind string) (string, error) {
+	if d.archiveDir == "" {
+		return "", fmt.Errorf("archive directory not configured (set meta_dir)")
+	}
+	return freeDestIn(filepath.Join(d.archiveDir, kind), name)
+}
+
+// freeDestIn returns a path in dir for the base name of name that does not
+// exist yet, numbering it when taken. dir is created.
+func freeDestIn(dir, name string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	base := filepath.Base(name)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	dest := filepath.Join(dir, base)
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(dest); errors.Is(err, os.ErrNotExist) {
+			return dest, nil
+		}
+		dest = filepath.Join(dir, fmt.Sprintf("%s-%d%s", stem, i, ext))
+	}
+}
+
+func formatDoctorReport(findings []doctorFinding) []string {
+	if len(findings) == 0 {
+		return []string{"Library check: no problems found"}
+	}
+	counts := make(map[doctorKind]int)
+	for _, f := range findings {
+		counts[f.Kind]++
+	}
+	kinds := []doctorKind{doctorOrphanMetadata, doctorDuplicatePath, doctorOrphanNote, doctorDanglingLink, doctorUnreadable}
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		if counts[kind] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[kind], kind))
+		}
+	}
+	lines := []string{fmt.Sprintf("Library check: %d problem(s): %s", len(findings), strings.Join(parts, ", "))}
+	for _, f := range findings {
+		lines = append(lines, "  "+f.String())
+	}
+	return lines
+}
+
+// RunDoctor checks the library described by cfg and prints a report to out.
+// With fix set, it asks on in how to resolve each finding.
+func RunDoctor(cfg *config.Config, store *meta.Store, in io.Reader, out io.Writer, fix bool) error {
+	ctx := context.Background()
+	d := newDoctorFromConfig(cfg, store)
+	findings, err := d.scan(ctx)
+	if err != nil {
+		return err
+	}
+	for _, line := range formatDoctorReport(findings) {
+		fmt.Fprintln(out, line)
+	}
+	if !fix || len(findings) == 0 {
+		return nil
+	}
+
+	reader := bufio.NewReader(in)
+	fixed := 0
+	for i, f := range findings {
+		actions := f.actions()
+		choices := make([]string, 0, len(actions)+1)
+		for _, a := range actions {
+			choices = append(choices, fmt.Sprintf("[%c]%s", a[0], a[1:]))
+		}
+		choices = append(choices, "[s]kip", "[q]uit")
+		fmt.Fprintf(out, "\n(%d/%d) %s\n%s? ", i+1, len(findings), f, strings.Join(choices, " "))
+		answer, err := reader.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if err != nil && answer == "" {
+			break
+		}
+		if answer == "q" || answer == "quit" {
+			break
+		}
+		var chosen doctorAction
+		for _, a := range actions {
+			if answer == string(a) || answer == string(a[0]) {
+				chosen = a
+			}
+		}
+		if chosen == "" {
+			continue
+		}
+		if err := d.fix(ctx, f, chosen); err != nil {
+			fmt.Fprintf(out, "  failed: %v\n", err)
+			continue
+		}
+		fixed++
+		fmt.Fprintf(out, "  %s done\n", chosen)
+	}
+	fmt.Fprintf(out, "\nFixed %d of %d finding(s)\n", fixed, len(findings))
+	return nil
+}
+
+type doctorFinishedMsg struct {
+	err error
+}
+
+// doctorScanMsg carries the findings of a background :doctor scan.
+type doctorScanMsg struct {
+	findings []doctorFinding
+	err      error
+}
+
+func (m *Model) handleDoctorCommand(args []string) tea.Cmd {
+	if m.meta == nil {
+		m.setStatus("Metadata store not available")
+		return nil
+	}
+	if len(args) > 0 && strings.EqualFold(args[0], "fix") {
+		return m.launchDoctorFix()
+	}
+	// Scanning reads every document and hashes relink candidates; keep it
+	// off the update loop.
+	d := m.newDoctor()
+	m.setPersistentStatus("Checking library...")
+	return func() tea.Msg {
+		findings, err := d.scan(context.Background())
+		return doctorScanMsg{findings: findings, err: err}
+	}
+}
+
+func (m *Model) handleDoctorScan(msg doctorScanMsg) {
+	if msg.err != nil {
+		m.setStatus("Library check failed: " + msg.err.Error())
+		return
+	}
+	findings := msg.findings
+	m.setCommandOutput(formatDoctorReport(findings))
+	if len(findings) == 0 {
+		m.setStatus("Library check: no problems found")
+	} else {
+		m.setStatus(fmt.Sprintf("Library check: %d problem(s); :doctor fix to resolve", len(findings)))
+	}
+}
+
+// launchDoctorFix suspends the UI and runs the interactive `gorae doctor
+// --fix` in the terminal, like the external editors.
+func (m *Model) launchDoctorFix() tea.Cmd {
+	exe, err := os.Executable()
+	if err != nil {
+		m.setStatus("Cannot locate gorae executable: " + err.Error())
+		return nil
+	}
+	args := []string{"doctor", "--fix", "--root", m.root}
+	if m.cfg != nil {
+		args = append(args, "--library", m.cfg.ActiveLibrary())
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	m.setPersistentStatus("Running library check (exit to return)")
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return doctorFinishedMsg{err: err}
+	})
+}
+
+func (m *Model) handleDoctorFinished(msg doctorFinishedMsg) {
+	if msg.err != nil {
+		m.setStatus("Library fix failed: " + msg.err.Error())
+		return
+	}
+	m.loadEntries()
+	m.reloadCurrentMetadata()
+	if err := m.syncCollectionDirectories(); err != nil {
+		m.setStatus("Failed to sync helper directories: " + err.Error())
+		return
+	}
+	m.setStatus("Library check finished")
+}
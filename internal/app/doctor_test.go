@@ -0,0 +1,174 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorae/internal/config"
+	"gorae/internal/meta"
+)
+
+func TestDoctorFindsAndFixesProblems(t *testing.T) {
+	base := canonicalPath(t.TempDir())
+	root := filepath.Join(base, "library")
+	metaDir := filepath.Join(base, "meta")
+	notesDir := filepath.Join(metaDir, "notes")
+	for _, dir := range []string{root, notesDir, filepath.Join(root, favoritesDirName)} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+	store, err := meta.Open(filepath.Join(metaDir, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	// A paper moved outside gorae: the old row is orphaned but its content
+	// hash points at the new file.
+	moved := filepath.Join(root, "moved.pdf")
+	writeDummyPDF(t, moved)
+	hash, err := hashFile(moved)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	info, _ := os.Stat(moved)
+	gone := filepath.Join(root, "gone.pdf")
+	if err := store.Upsert(ctx, &meta.Metadata{Path: gone, Title: "Moved away"}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := store.SetIdentity(ctx, gone, meta.FileIdentity{Hash: hash, Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+		t.Fatalf("set identity: %v", err)
+	}
+	// Listing the new location already added a bare row there.
+	if err := store.UpsertFrom(ctx, &meta.Metadata{Path: moved, ReadingState: readingStateUnread}, meta.SourceImport); err != nil {
+		t.Fatalf("upsert bare: %v", err)
+	}
+	// A paper deleted for good, with a note.
+	deleted := filepath.Join(root, "deleted.pdf")
+	if err := store.Upsert(ctx, &meta.Metadata{Path: deleted, Title: "Deleted"}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	deletedNote, _ := noteFilePathIn(notesDir, deleted)
+	if err := os.WriteFile(deletedNote, []byte("kept thoughts"), 0o644); err != nil {
+		t.Fatalf("write note: %v", err)
+	}
+	// The same file recorded through a symlinked directory.
+	real := filepath.Join(root, "real.pdf")
+	writeDummyPDF(t, real)
+	if err := os.Symlink(root, filepath.Join(base, "alias")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	for _, path := range []string{real, filepath.Join(base, "alias", "real.pdf")} {
+		if err := store.Upsert(ctx, &meta.Metadata{Path: path, Title: "Real"}); err != nil {
+			t.Fatalf("upsert %s: %v", path, err)
+		}
+	}
+	// A file recorded only through the symlink.
+	solo := filepath.Join(root, "solo.pdf")
+	writeDummyPDF(t, solo)
+	if err := store.Upsert(ctx, &meta.Metadata{Path: filepath.Join(base, "alias", "solo.pdf"), Title: "Solo"}); err != nil {
+		t.Fatalf("upsert solo: %v", err)
+	}
+	orphanNote := filepath.Join(notesDir, strings.Repeat("a", 40)+".md")
+	if err := os.WriteFile(orphanNote, []byte("stray"), 0o644); err != nil {
+		t.Fatalf("write orphan note: %v", err)
+	}
+	link := filepath.Join(root, favoritesDirName, "old.pdf")
+	if err := os.Symlink(filepath.Join(root, "missing.pdf"), link); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	broken := filepath.Join(root, "broken.pdf")
+	if err := os.WriteFile(broken, []byte("not a pdf"), 0o644); err != nil {
+		t.Fatalf("write broken: %v", err)
+	}
+
+	cfg := &config.Config{WatchDir: root, MetaDir: metaDir}
+	var out bytes.Buffer
+	if err := RunDoctor(cfg, store, strings.NewReader(""), &out, false); err != nil {
+		t.Fatalf("doctor: %v", err)
+	}
+	report := out.String()
+	for _, want := range []string{
+		"[orphan metadata] " + gone, "(moved to " + moved + "?)",
+		"[orphan metadata] " + deleted,
+		"[duplicate path] " + filepath.Join(base, "alias", "real.pdf"),
+		"[duplicate path] " + filepath.Join(base, "alias", "solo.pdf") + " — file is at " + solo,
+		"[orphan note] " + orphanNote,
+		"[dangling link] " + link,
+		"[unreadable] " + broken,
+	} {
+		if !strings.Contains(report, want) {
+			t.Fatalf("report misses %q:\n%s", want, report)
+		}
+	}
+
+	// Findings are listed orphans first, sorted by path: deleted, gone,
+	// duplicates of real and solo, note, link, unreadable.
+	answers := "a\nr\np\nr\np\np\ns\n"
+	out.Reset()
+	if err := RunDoctor(cfg, store, strings.NewReader(answers), &out, true); err != nil {
+		t.Fatalf("doctor fix: %v", err)
+	}
+	if !strings.Contains(out.String(), "Fixed 6 of 7") {
+		t.Fatalf("unexpected fix output:\n%s", out.String())
+	}
+	if md, _ := store.Get(ctx, moved); md == nil || md.Title != "Moved away" {
+		t.Fatalf("expected metadata relinked to %s, got %+v", moved, md)
+	}
+	if md, _ := store.Get(ctx, solo); md == nil || md.Title != "Solo" {
+		t.Fatalf("expected metadata relinked to %s, got %+v", solo, md)
+	}
+	archived, err := os.ReadFile(filepath.Join(metaDir, "archive", "metadata", "deleted.json"))
+	if err != nil || !strings.Contains(string(archived), "kept thoughts") {
+		t.Fatalf("expected archived record with note, got %q (%v)", archived, err)
+	}
+	for _, path := range []string{deletedNote, orphanNote, link} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected %s removed, err=%v", path, err)
+		}
+	}
+
+	out.Reset()
+	if err := RunDoctor(cfg, store, strings.NewReader(""), &out, false); err != nil {
+		t.Fatalf("doctor rerun: %v", err)
+	}
+	if !strings.Contains(out.String(), "1 problem(s): 1 unreadable") {
+		t.Fatalf("expected only the unreadable file left:\n%s", out.String())
+	}
+}
+
+func TestDoctorCommandScansInBackground(t *testing.T) {
+	root := canonicalPath(t.TempDir())
+	store, err := meta.Open(filepath.Join(root, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	gone := filepath.Join(root, "gone.pdf")
+	if err := store.Upsert(context.Background(), &meta.Metadata{Path: gone, Title: "Gone"}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	m := &Model{root: root, meta: store}
+	cmd := m.handleDoctorCommand(nil)
+	if cmd == nil {
+		t.Fatalf("expected :doctor to return a scan command")
+	}
+	if len(m.commandOutput) != 0 {
+		t.Fatalf("expected no report before the scan ran, got %v", m.commandOutput)
+	}
+	msg, ok := cmd().(doctorScanMsg)
+	if !ok || msg.err != nil {
+		t.Fatalf("unexpected scan result %#v", msg)
+	}
+	m.handleDoctorScan(msg)
+	if !strings.Contains(strings.Join(m.commandOutput, "\n"), "[orphan metadata] "+gone) {
+		t.Fatalf("report misses the orphan: %v", m.commandOutput)
+	}
+}
@@ -0,0 +1,440 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"gorae/internal/meta"
+)
+
+// duplicateMerge is a merge waiting for confirmation in the results view.
+type duplicateMerge struct {
+	survivor string
+	others   []string
+}
+
+func (m *Model) handleDuplicatesCommand(args []string) tea.Cmd {
+	if m.meta == nil {
+		m.setStatus("Metadata store not available")
+		return nil
+	}
+	if len(args) > 0 {
+		m.setStatus("Usage: :duplicates")
+		return nil
+	}
+	// Hashing the library takes a while; do it off the update loop, under
+	// the same lock as the fingerprinting pass.
+	store, root, skipDirs, notesDir, mu := m.meta, canonicalPath(m.root), m.searchSkipDirs(), m.notesDir, m.identitySync
+	m.setPersistentStatus("Looking for duplicates...")
+	return func() tea.Msg {
+		if mu != nil {
+			mu.Lock()
+			defer mu.Unlock()
+		}
+		groups, reconnected, err := findDuplicates(context.Background(), store, root, skipDirs, notesDir)
+		return duplicatesFoundMsg{groups: groups, reconnected: reconnected, err: err}
+	}
+}
+
+// duplicatesFoundMsg carries the result of a :duplicates scan.
+type duplicatesFoundMsg struct {
+	groups      []meta.DuplicateGroup
+	reconnected int
+	err         error
+}
+
+func (m *Model) handleDuplicatesFound(msg duplicatesFoundMsg) {
+	m.handleIdentitiesReconciled(identitiesReconciledMsg{reconnected: msg.reconnected})
+	if msg.err != nil {
+		m.setStatus("Duplicate check failed: " + msg.err.Error())
+		return
+	}
+	if len(msg.groups) == 0 {
+		m.setStatus("No duplicates found")
+		return
+	}
+	m.showDuplicates(msg.groups)
+}
+
+// findDuplicates fingerprints every document below root that has changed
+// since it was last hashed and groups the likely duplicates. Fingerprinting
+// can reconnect moved rows, so the papers are listed again afterwards.
+func findDuplicates(ctx context.Context, store *meta.Store, root string, skipDirs []string, notesDir string) ([]meta.DuplicateGroup, int, error) {
+	inScope := func() ([]meta.Metadata, error) {
+		list, err := store.Query(ctx, meta.Filter{PathPrefix: root})
+		if err != nil {
+			return nil, err
+		}
+		keep := make(map[string]bool)
+		for _, path := range storedDocumentFiles(root, skipDirs, list) {
+			keep[path] = true
+		}
+		papers := make([]meta.Metadata, 0, len(keep))
+		for _, md := range list {
+			if keep[md.Path] {
+				papers = append(papers, md)
+			}
+		}
+		return papers, nil
+	}
+	papers, err := inScope()
+	if err != nil {
+		return nil, 0, err
+	}
+	reconnected := 0
+	for _, md := range papers {
+		info, err := os.Stat(md.Path)
+		if err != nil {
+			continue
+		}
+		if moved, err := reconcileFileIdentity(ctx, store, md.Path, info, notesDir); err == nil && moved != "" {
+			reconnected++
+		}
+	}
+	if reconnected > 0 {
+		if papers, err = inScope(); err != nil {
+			return nil, reconnected, err
+		}
+	}
+	hashes := make(map[string]string, len(papers))
+	for _, md := range papers {
+		if id, _, err := store.Identity(ctx, md.Path); err == nil && id.Hash != "" {
+			hashes[md.Path] = id.Hash
+		}
+	}
+	return meta.FindDuplicates(papers, hashes), reconnected, nil
+}
+
+func (m *Model) showDuplicates(groups []meta.DuplicateGroup) {
+	summary := fmt.Sprintf("Duplicates: %d group(s)", len(groups))
+	m.state = stateNormal
+	m.metaEditingPath = ""
+	m.enterSearchResults(searchResultMsg{matches: duplicateMatches(groups), summary: summary})
+	m.duplicatesView = true
+	m.setPersistentStatus(summary + " (m merge into selected, Esc/q to exit)")
+}
+
+// duplicateMatches lists every group's papers one after another, tagged with
+// the group number and the reasons they were grouped.
+func duplicateMatches(groups []meta.DuplicateGroup) []searchMatch {
+	var matches []searchMatch
+	for i, group := range groups {
+		reason := fmt.Sprintf("Duplicate group %d: %s", i+1, strings.Join(group.Reasons, ", "))
+		for _, match := range metadataMatches(group.Papers) {
+			match.Group = i + 1
+			match.MatchCount = 0
+			match.Snippets = append([]string{reason}, match.Snippets...)
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// promptDuplicateMerge asks to merge the rest of the cursor's group into the
+// paper under the cursor.
+func (m *Model) promptDuplicateMerge() {
+	current := m.currentSearchMatch()
+	if current == nil || current.Group == 0 {
+		return
+	}
+	var others []string
+	for _, match := range m.searchResults {
+		if match.Group == current.Group && match.Path != current.Path {
+			others = append(others, match.Path)
+		}
+	}
+	if len(others) == 0 {
+		return
+	}
+	m.pendingMerge = &duplicateMerge{survivor: current.Path, others: others}
+	m.setPersistentStatus(fmt.Sprintf("Merge %d duplicate(s) into %s and move them to the trash? (y/N)",
+		len(others), filepath.Base(current.Path)))
+}
+
+func (m *Model) confirmDuplicateMerge(key string) {
+	pending := m.pendingMerge
+	m.pendingMerge = nil
+	if key != "y" && key != "Y" {
+		m.setStatus("Merge cancelled")
+		return
+	}
+	trashDir, err := m.duplicatesTrashDir()
+	if err != nil {
+		m.setStatus("Merge failed: " + err.Error())
+		return
+	}
+	if err := mergeDuplicateFiles(context.Background(), m.meta, m.notesDir, trashDir, pending.survivor, pending.others); err != nil {
+		m.setStatus("Merge failed: " + err.Error())
+		return
+	}
+
+	for _, path := range pending.others {
+		delete(m.selected, path)
+		m.removeFromCut(path)
+	}
+	m.dropDuplicateGroup(pending.survivor)
+	m.loadEntries()
+	m.reloadCurrentMetadata()
+	status := fmt.Sprintf("Merged %d duplicate(s) into %s; copies moved to %s",
+		len(pending.others), filepath.Base(pending.survivor), trashDir)
+	if err := m.syncCollectionDirectories(); err != nil {
+		status += "; favorites/to-read: " + err.Error()
+	}
+	if err := m.syncRecentlyOpenedDirectory(); err != nil {
+		status += "; recently read: " + err.Error()
+	}
+	if err := m.maybeSyncRecentlyAddedDir(true); err != nil {
+		status += "; recently added: " + err.Error()
+	}
+	if len(m.searchResults) == 0 {
+		m.exitSearchResults()
+	}
+	m.setStatus(status)
+}
+
+// dropDuplicateGroup removes the merged group of survivor from the results.
+func (m *Model) dropDuplicateGroup(survivor string) {
+	group := 0
+	for _, match := range m.searchResults {
+		if match.Path == survivor {
+			group = match.Group
+			break
+		}
+	}
+	m.searchResults = slices.DeleteFunc(m.searchResults, func(match searchMatch) bool {
+		return match.Group == group
+	})
+	if m.searchResultCursor >= len(m.searchResults) {
+		m.searchResultCursor = max(len(m.searchResults)-1, 0)
+	}
+	m.ensureSearchResultVisible()
+}
+
+// duplicatesTrashDir is where merged copies are moved: meta_dir/trash.
+func (m *Model) duplicatesTrashDir() (string, error) {
+	if m.cfg == nil || strings.TrimSpace(m.cfg.MetaDir) == "" {
+		return "", fmt.Errorf("trash directory not configured (set meta_dir)")
+	}
+	return filepath.Join(m.cfg.MetaDir, "trash"), nil
+}
+
+// mergeDuplicateFiles folds the metadata, notes, collections and reading
+// history of others into survivor and moves their files to trashDir.
+func mergeDuplicateFiles(ctx context.Context, store *meta.Store, notesDir, trashDir, survivor string, others []string) error {
+	into, err := store.Get(ctx, survivor)
+	if err != nil {
+		return err
+	}
+	if into == nil {
+		into = &meta.Metadata{Path: survivor, ReadingState: readingStateUnread}
+	}
+	var records []meta.Metadata
+	for _, path := range others {
+		md, err := store.Get(ctx, path)
+		if err != nil {
+			return err
+		}
+		if md != nil {
+			records = append(records, *md)
+		}
+	}
+
+	// Move the files first and put them back if a move or the merge fails,
+	// so the records never point at a file that is already in the trash.
+	moved := make(map[string]string, len(others))
+	restore := func() {
+		for path, dest := range moved {
+			_ = moveFile(dest, path)
+		}
+	}
+	for _, path := range others {
+		dest, err := freeDestIn(trashDir, path)
+		if err == nil {
+			err = moveFile(path, dest)
+		}
+		if err != nil {
+			restore()
+			return err
+		}
+		moved[path] = dest
+	}
+
+	merged := mergeDuplicateMetadata(*into, records)
+	if err := store.MergeInto(ctx, survivor, others...); err != nil {
+		restore()
+		return err
+	}
+	// Custom fields were carried over by MergeInto; leave them as they are.
+	merged.Fields = nil
+	if err := store.UpsertFrom(ctx, &merged, meta.SourceMerge); err != nil {
+		return err
+	}
+	if merged.ProgressPage != into.ProgressPage || merged.ProgressPercent != into.ProgressPercent {
+		if err := store.SetProgress(ctx, survivor, merged.ProgressPage, merged.ProgressPercent); err != nil {
+			return err
+		}
+	}
+	return mergeDuplicateNotes(notesDir, survivor, others)
+}
+
+// moveFile renames src to dst, copying it when they are on different file
+// systems, as meta_dir and an external library drive often are.
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	return copyAndRemove(src, dst)
+}
+
+// copyAndRemove copies src to dst, keeping its mode and times, and removes
+// src once the copy is complete.
+func copyAndRemove(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+	_ = os.Chtimes(dst, info.ModTime(), info.ModTime())
+	return os.Remove(src)
+}
+
+// mergeDuplicateMetadata fills the gaps of into from others: empty fields are
+// taken from the first copy that has them, tags are combined, and flags,
+// rating, priority, reading state and progress keep the strongest value.
+func mergeDuplicateMetadata(into meta.Metadata, others []meta.Metadata) meta.Metadata {
+	merged := into
+	tags := meta.SplitTags(into.Tag)
+	for _, other := range others {
+		for _, field := range []struct{ dst, src *string }{
+			{&merged.Title, &other.Title},
+			{&merged.Year, &other.Year},
+			{&merged.Published, &other.Published},
+			{&merged.URL, &other.URL},
+			{&merged.DOI, &other.DOI},
+			{&merged.Abstract, &other.Abstract},
+		} {
+			if strings.TrimSpace(*field.dst) == "" {
+				*field.dst = *field.src
+			}
+		}
+		if len(merged.Authors) == 0 && strings.TrimSpace(merged.Author) == "" {
+			merged.Author = other.Author
+			merged.Authors = other.Authors
+		}
+		for _, tag := range meta.SplitTags(other.Tag) {
+			if !slices.ContainsFunc(tags, func(t string) bool { return strings.EqualFold(t, tag) }) {
+				tags = append(tags, tag)
+			}
+		}
+		merged.Favorite = merged.Favorite || other.Favorite
+		merged.ToRead = merged.ToRead || other.ToRead
+		merged.Rating = max(merged.Rating, other.Rating)
+		if meta.PriorityRank(other.Priority) > meta.PriorityRank(merged.Priority) {
+			merged.Priority = other.Priority
+		}
+		if readingStateRank(other.ReadingState) > readingStateRank(merged.ReadingState) {
+			merged.ReadingState = normalizeReadingStateValue(other.ReadingState)
+		}
+		if other.ProgressPage > merged.ProgressPage {
+			merged.ProgressPage = other.ProgressPage
+		}
+		if other.ProgressPercent > merged.ProgressPercent {
+			merged.ProgressPercent = other.ProgressPercent
+		}
+	}
+	merged.Tag = meta.FormatTags(tags)
+	return merged
+}
+
+func readingStateRank(state string) int {
+	switch normalizeReadingStateValue(state) {
+	case readingStateRead:
+		return 2
+	case readingStateReading:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// mergeDuplicateNotes appends the notes of others to the note of survivor,
+// each under a heading naming the file it came from, and removes them.
+func mergeDuplicateNotes(notesDir, survivor string, others []string) error {
+	if strings.TrimSpace(notesDir) == "" {
+		return nil
+	}
+	target, err := noteFilePathIn(notesDir, survivor)
+	if err != nil {
+		return err
+	}
+	existing, err := os.ReadFile(target)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	note := strings.TrimRight(string(existing), "\n")
+	var merged []string
+	for _, path := range others {
+		source, err := noteFilePathIn(notesDir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(source)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if text := strings.TrimSpace(string(data)); text != "" {
+			if note != "" {
+				note += "\n\n"
+			}
+			note += fmt.Sprintf("## Merged from %s\n\n%s", filepath.Base(path), text)
+		}
+		merged = append(merged, source)
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(notesDir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(target, []byte(note+"\n"), 0o644); err != nil {
+		return err
+	}
+	for _, source := range merged {
+		if err := os.Remove(source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
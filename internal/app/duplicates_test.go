@@ -0,0 +1,212 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestMergeDuplicateMetadataKeepsStrongestValues(t *testing.T) {
+	into := meta.Metadata{
+		Path:         "/lib/a.pdf",
+		Title:        "Attention Is All You Need",
+		Tag:          "nlp",
+		Rating:       2,
+		Priority:     meta.PriorityLow,
+		ReadingState: readingStateReading,
+	}
+	others := []meta.Metadata{
+		{Path: "/lib/b.pdf", Title: "attention", Year: "2017", DOI: "10.1/x", Tag: "NLP, transformers", Favorite: true, Rating: 4, ReadingState: readingStateUnread},
+		{Path: "/lib/c.pdf", Abstract: "We propose...", Priority: meta.PriorityHigh, ReadingState: readingStateRead, ProgressPage: 12},
+	}
+
+	got := mergeDuplicateMetadata(into, others)
+	if got.Path != "/lib/a.pdf" || got.Title != "Attention Is All You Need" {
+		t.Fatalf("survivor identity changed: %+v", got)
+	}
+	if got.Year != "2017" || got.DOI != "10.1/x" || got.Abstract != "We propose..." {
+		t.Fatalf("expected empty fields to be filled, got %+v", got)
+	}
+	if got.Tag != "nlp, transformers" {
+		t.Fatalf("unexpected tags %q", got.Tag)
+	}
+	if !got.Favorite || got.ToRead || got.Rating != 4 || got.Priority != meta.PriorityHigh {
+		t.Fatalf("unexpected flags: %+v", got)
+	}
+	if got.ReadingState != readingStateRead || got.ProgressPage != 12 {
+		t.Fatalf("unexpected reading state: %+v", got)
+	}
+}
+
+func TestDuplicatesCommandReconnectsBeforeGrouping(t *testing.T) {
+	root := canonicalPath(t.TempDir())
+	store, err := meta.Open(filepath.Join(root, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	paper := filepath.Join(root, "paper.pdf")
+	writeDummyPDF(t, paper)
+	if err := store.Upsert(ctx, &meta.Metadata{Path: paper, Title: "Paper"}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	info, _ := os.Stat(paper)
+	if _, err := reconcileFileIdentity(ctx, store, paper, info, ""); err != nil {
+		t.Fatalf("fingerprint: %v", err)
+	}
+	// Moved in the shell and listed again, plus an unrelated copy.
+	moved := filepath.Join(root, "moved.pdf")
+	if err := os.Rename(paper, moved); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if err := store.UpsertFrom(ctx, &meta.Metadata{Path: moved, ReadingState: readingStateUnread}, meta.SourceImport); err != nil {
+		t.Fatalf("upsert bare: %v", err)
+	}
+	copyPath := filepath.Join(root, "copy.pdf")
+	writeDummyPDF(t, copyPath)
+	if err := store.Upsert(ctx, &meta.Metadata{Path: copyPath, Title: "Copy"}); err != nil {
+		t.Fatalf("upsert copy: %v", err)
+	}
+
+	m := &Model{root: root, meta: store, identitySync: new(sync.Mutex)}
+	cmd := m.handleDuplicatesCommand(nil)
+	if cmd == nil {
+		t.Fatalf("expected :duplicates to return a scan command")
+	}
+	msg, ok := cmd().(duplicatesFoundMsg)
+	if !ok || msg.err != nil || msg.reconnected != 1 {
+		t.Fatalf("unexpected scan result %#v", msg)
+	}
+	if len(msg.groups) != 1 || len(msg.groups[0].Papers) != 2 {
+		t.Fatalf("expected one group of two, got %+v", msg.groups)
+	}
+	group := msg.groups[0].Papers
+	if group[0].Path != copyPath || group[1].Path != moved || group[1].Title != "Paper" {
+		t.Fatalf("expected the reconnected row in the group, got %+v", group)
+	}
+}
+
+func TestMergeDuplicateFiles(t *testing.T) {
+	base := canonicalPath(t.TempDir())
+	root := filepath.Join(base, "library")
+	notesDir := filepath.Join(base, "notes")
+	trashDir := filepath.Join(base, "trash")
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	store, err := meta.Open(filepath.Join(base, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	keep := filepath.Join(root, "paper.pdf")
+	copyPath := filepath.Join(root, "paper (1).pdf")
+	for _, path := range []string{keep, copyPath} {
+		writeDummyPDF(t, path)
+	}
+	if err := store.Upsert(ctx, &meta.Metadata{Path: keep, Title: "Paper"}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := store.Upsert(ctx, &meta.Metadata{Path: copyPath, Title: "Paper", Year: "2020", Favorite: true}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	for path, text := range map[string]string{keep: "first thoughts", copyPath: "second thoughts"} {
+		note, _ := noteFilePathIn(notesDir, path)
+		if err := os.MkdirAll(notesDir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(note, []byte(text+"\n"), 0o644); err != nil {
+			t.Fatalf("write note: %v", err)
+		}
+	}
+
+	if err := mergeDuplicateFiles(ctx, store, notesDir, trashDir, keep, []string{copyPath}); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	if _, err := os.Stat(copyPath); !os.IsNotExist(err) {
+		t.Fatalf("expected the copy to leave the library, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(trashDir, "paper (1).pdf")); err != nil {
+		t.Fatalf("expected the copy in the trash: %v", err)
+	}
+	md, err := store.Get(ctx, keep)
+	if err != nil || md == nil || md.Year != "2020" || !md.Favorite {
+		t.Fatalf("expected merged metadata, got %+v (%v)", md, err)
+	}
+	if gone, err := store.Get(ctx, copyPath); err != nil || gone != nil {
+		t.Fatalf("expected the copy's record to be removed, got %+v (%v)", gone, err)
+	}
+	note, _ := noteFilePathIn(notesDir, keep)
+	data, err := os.ReadFile(note)
+	if err != nil {
+		t.Fatalf("read note: %v", err)
+	}
+	if text := string(data); !strings.HasPrefix(text, "first thoughts") || !strings.Contains(text, "## Merged from paper (1).pdf\n\nsecond thoughts") {
+		t.Fatalf("unexpected merged note %q", text)
+	}
+	oldNote, _ := noteFilePathIn(notesDir, copyPath)
+	if _, err := os.Stat(oldNote); !os.IsNotExist(err) {
+		t.Fatalf("expected the copy's note to be removed, got %v", err)
+	}
+}
+
+func TestMergeDuplicateFilesRestoresMovesOnFailure(t *testing.T) {
+	base := canonicalPath(t.TempDir())
+	trashDir := filepath.Join(base, "trash")
+	store, err := meta.Open(filepath.Join(base, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	keep := filepath.Join(base, "paper.pdf")
+	first := filepath.Join(base, "paper (1).pdf")
+	writeDummyPDF(t, keep)
+	writeDummyPDF(t, first)
+	if err := store.Upsert(ctx, &meta.Metadata{Path: first, Title: "Paper", Favorite: true}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	// The second copy is gone, so its move fails after the first one.
+	missing := filepath.Join(base, "paper (2).pdf")
+	if err := mergeDuplicateFiles(ctx, store, "", trashDir, keep, []string{first, missing}); err == nil {
+		t.Fatalf("expected the merge to fail")
+	}
+	if _, err := os.Stat(first); err != nil {
+		t.Fatalf("expected the first copy to be moved back: %v", err)
+	}
+	if md, err := store.Get(ctx, first); err != nil || md == nil || !md.Favorite {
+		t.Fatalf("expected the first copy's record to stay, got %+v (%v)", md, err)
+	}
+}
+
+func TestCopyAndRemove(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "paper.pdf")
+	dst := filepath.Join(dir, "trash.pdf")
+	writeDummyPDF(t, src)
+	want, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if err := copyAndRemove(src, dst); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Fatalf("expected the source to be removed, got %v", err)
+	}
+	if got, err := os.ReadFile(dst); err != nil || string(got) != string(want) {
+		t.Fatalf("expected an identical copy, err=%v", err)
+	}
+}
@@ -54,9 +54,16 @@ type opfPackage struct {
 	Creator      string
 	Date         string
 	Subject      string
+	Identifiers  []opfIdentifier
 	rootfilePath string
 }
 
+// opfIdentifier is a dc:identifier with its optional opf:scheme attribute.
+type opfIdentifier struct {
+	Scheme string
+	Value  string
+}
+
 // extractEPUBPreview returns up to maxLines of text for preview purposes.
 func extractEPUBPreview(path string, maxLines int) ([]string, error) {
 	if maxLines <= 0 {
@@ -226,6 +233,21 @@ func parseOPF(data []byte) (opfPackage, error) {
 						}
 					}
 				}
+			case "identifier":
+				if st.inMetadata {
+					var scheme string
+					for _, a := range t.Attr {
+						if strings.ToLower(a.Name.Local) == "scheme" {
+							scheme = strings.TrimSpace(a.Value)
+						}
+					}
+					var text string
+					if err := decoder.DecodeElement(&text, &t); err == nil {
+						if txt := strings.TrimSpace(text); txt != "" {
+							opf.Identifiers = append(opf.Identifiers, opfIdentifier{Scheme: scheme, Value: txt})
+						}
+					}
+				}
 			case "subject":
 				if st.inMetadata {
 					var text string
@@ -1,9 +1,12 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"strings"
+
+	"gorae/internal/meta"
 )
 
 // parseEPUBMetadata parses basic metadata from the EPUB OPF.
@@ -34,3 +37,51 @@ func extractEPUBPackage(path string) (opfPackage, error) {
 	defer rc.Close()
 	return pkg, nil
 }
+
+// epubIdentifiers returns the ISBNs, DOIs and other identifiers listed as
+// dc:identifier in the EPUB OPF.
+func epubIdentifiers(path string) ([]meta.Identifier, error) {
+	opf, err := extractEPUBPackage(path)
+	if err != nil {
+		return nil, err
+	}
+	return opfIdentifiersToMeta(opf.Identifiers), nil
+}
+
+func opfIdentifiersToMeta(raw []opfIdentifier) []meta.Identifier {
+	ids := make([]meta.Identifier, 0, len(raw))
+	for _, r := range raw {
+		id, ok := meta.ParseIdentifier(r.Value)
+		if !ok && r.Scheme != "" {
+			id, ok = meta.NormalizeIdentifier(r.Scheme, r.Value)
+		}
+		// Package UUIDs name the file, not the work.
+		if !ok || id.Scheme == "uuid" {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// saveFileIdentifiers records the identifiers a file names itself. The
+// metadata history credits the EPUB package they were read from; for a PDF
+// they come from its file name.
+func saveFileIdentifiers(ctx context.Context, store *meta.Store, path string, ids []meta.Identifier) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	md, err := store.Get(ctx, path)
+	if err != nil {
+		return err
+	}
+	if md == nil {
+		md = &meta.Metadata{Path: path, ReadingState: readingStateUnread}
+	}
+	md.Identifiers = append(md.Identifiers, ids...)
+	source := meta.SourceImport
+	if isEPUB(path) {
+		source = meta.SourceEPUB
+	}
+	return store.UpsertFrom(ctx, md, source)
+}
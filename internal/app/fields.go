@@ -0,0 +1,205 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorae/internal/config"
+	"gorae/internal/meta"
+)
+
+// fieldFilter restricts a search to papers whose custom field compares to
+// value, e.g. --field dataset=imagenet or --field epochs>=100.
+type fieldFilter struct {
+	field config.CustomField
+	op    string
+	value string
+}
+
+// fieldFilterOps lists the supported operators, longest first so that ">="
+// is not read as ">".
+var fieldFilterOps = []string{">=", "<=", "!=", "=", ">", "<"}
+
+func (m *Model) customFields() []config.CustomField {
+	if m.cfg == nil {
+		return nil
+	}
+	return m.cfg.CustomFields
+}
+
+// parseFieldFilter parses "name<op>value" against the configured fields.
+func parseFieldFilter(cfg *config.Config, expr string) (fieldFilter, error) {
+	expr = strings.TrimSpace(expr)
+	for _, op := range fieldFilterOps {
+		idx := strings.Index(expr, op)
+		if idx <= 0 {
+			continue
+		}
+		name := strings.TrimSpace(expr[:idx])
+		def, ok := cfg.CustomField(name)
+		if !ok {
+			return fieldFilter{}, fmt.Errorf("Unknown field: %s", name)
+		}
+		value := strings.TrimSpace(expr[idx+len(op):])
+		if op != "=" && op != "!=" && def.Kind() != config.FieldTypeNumber && def.Kind() != config.FieldTypeDate {
+			return fieldFilter{}, fmt.Errorf("Field %s only supports = and !=", def.Name)
+		}
+		normalized, err := def.Normalize(value)
+		if err != nil {
+			return fieldFilter{}, err
+		}
+		return fieldFilter{field: def, op: op, value: normalized}, nil
+	}
+	return fieldFilter{}, fmt.Errorf("Invalid field filter %q (expected name=value)", expr)
+}
+
+func (f fieldFilter) String() string {
+	return f.field.Name + f.op + f.value
+}
+
+// matches reports whether a stored value satisfies the filter. Papers without
+// a value only match "!=" filters.
+func (f fieldFilter) matches(value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return f.op == "!=" && f.value != ""
+	}
+	cmp, ok := compareFieldValues(f.field, value, f.value)
+	if !ok {
+		return false
+	}
+	switch f.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+func compareFieldValues(def config.CustomField, a, b string) (int, bool) {
+	switch def.Kind() {
+	case config.FieldTypeNumber:
+		x, errA := strconv.ParseFloat(a, 64)
+		y, errB := strconv.ParseFloat(b, 64)
+		if errA != nil || errB != nil {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case config.FieldTypeDate:
+		x, errA := config.ParseFieldDate(a)
+		y, errB := config.ParseFieldDate(b)
+		if errA != nil || errB != nil {
+			return 0, false
+		}
+		return x.Compare(y), true
+	default:
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b)), true
+	}
+}
+
+// filterByFields keeps the files whose custom fields satisfy every filter.
+func filterByFields(store *meta.Store, files []string, filters []fieldFilter) ([]string, error) {
+	if len(filters) == 0 {
+		return files, nil
+	}
+	values := make([]map[string]string, len(filters))
+	for i, f := range filters {
+		v, err := store.FieldValues(context.Background(), f.field.Name)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	kept := make([]string, 0, len(files))
+	for _, path := range files {
+		key := canonicalPath(path)
+		ok := true
+		for i, f := range filters {
+			if !f.matches(values[i][key]) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			kept = append(kept, path)
+		}
+	}
+	return kept, nil
+}
+
+// customFieldRows returns the custom fields of md for display: configured
+// fields first, in declaration order, then any other stored values by name.
+func customFieldRows(defs []config.CustomField, md meta.Metadata) [][2]string {
+	rows := make([][2]string, 0, len(defs)+len(md.Fields))
+	seen := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		seen[def.Name] = true
+		rows = append(rows, [2]string{def.Name, md.Fields[def.Name]})
+	}
+	extra := make([]string, 0)
+	for name := range md.Fields {
+		if !seen[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		rows = append(rows, [2]string{name, md.Fields[name]})
+	}
+	return rows
+}
+
+// searchStoredFields answers a search made of filters alone from the
+// metadata store, without walking the library.
+func searchStoredFields(req searchRequest) (searchAggregate, string, error) {
+	list, err := req.metaStore.Query(context.Background(), req.metadataFilter())
+	if err != nil {
+		return searchAggregate{}, "", fmt.Errorf("query metadata: %w", err)
+	}
+	files, err := filterByFields(req.metaStore, storedDocumentFiles(req.root, req.skipDirs, list), req.fieldFilters)
+	if err != nil {
+		return searchAggregate{}, "", err
+	}
+	agg := searchAggregate{filesScanned: len(files)}
+	for _, path := range files {
+		agg.matches = append(agg.matches, metadataMatchForPath(req.metaStore, path))
+		agg.filesMatched++
+		agg.totalMatches++
+	}
+	return agg, formatSearchSummary(req, agg), nil
+}
+
+// metadataMatchForPath builds a result row for a paper matched on metadata
+// alone, such as a field-only search.
+func metadataMatchForPath(store *meta.Store, path string) searchMatch {
+	data, err := store.Get(context.Background(), canonicalPath(path))
+	if err != nil || data == nil {
+		match := searchMatch{Path: path, Mode: searchModeTitle, MatchCount: 1}
+		populateMatchDisplay(&match, store)
+		return match
+	}
+	match := metadataMatches([]meta.Metadata{*data})[0]
+	match.Path = path
+	for _, row := range customFieldRows(nil, *data) {
+		match.Snippets = append(match.Snippets, fmt.Sprintf("%s: %s", row[0], row[1]))
+	}
+	return match
+}
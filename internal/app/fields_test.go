@@ -0,0 +1,108 @@
+package app
+
+import (
+	"testing"
+
+	"gorae/internal/config"
+	"gorae/internal/meta"
+)
+
+func TestParseMetadataEditorDataValidatesCustomFields(t *testing.T) {
+	cfg := &config.Config{CustomFields: []config.CustomField{
+		{Name: "epochs", Type: "number"},
+		{Name: "rank", Type: "enum", Options: []string{"A*", "A", "B"}},
+		{Name: "reviewed", Type: "date"},
+	}}
+
+	md, err := parseMetadataEditorData([]byte(`{"title":"T","fields":{"Epochs":" 90.0 ","rank":"a*","reviewed":"2024-03-01","notes":" free "}}`), "/lib/p.pdf", cfg)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := map[string]string{"epochs": "90", "rank": "A*", "reviewed": "2024-03-01", "notes": "free"}
+	for name, value := range want {
+		if md.Fields[name] != value {
+			t.Fatalf("field %s = %q, want %q (all: %+v)", name, md.Fields[name], value, md.Fields)
+		}
+	}
+
+	for _, raw := range []string{
+		`{"fields":{"epochs":"many"}}`,
+		`{"fields":{"rank":"C"}}`,
+		`{"fields":{"reviewed":"March"}}`,
+	} {
+		if _, err := parseMetadataEditorData([]byte(raw), "/lib/p.pdf", cfg); err == nil {
+			t.Fatalf("expected validation error for %s", raw)
+		}
+	}
+
+	md, err = parseMetadataEditorData([]byte(`{"title":"T"}`), "/lib/p.pdf", cfg)
+	if err != nil || md.Fields != nil {
+		t.Fatalf("expected untouched fields without a fields key, got %+v (err %v)", md.Fields, err)
+	}
+}
+
+func TestFieldFilterMatching(t *testing.T) {
+	cfg := &config.Config{CustomFields: []config.CustomField{
+		{Name: "dataset"},
+		{Name: "epochs", Type: "number"},
+		{Name: "reviewed", Type: "date"},
+	}}
+
+	cases := []struct {
+		expr  string
+		value string
+		want  bool
+	}{
+		{"dataset=imagenet", "ImageNet", true},
+		{"dataset=imagenet", "COCO", false},
+		{"dataset!=imagenet", "", true},
+		{"epochs>=100", "100", true},
+		{"epochs>=100", "90", false},
+		{"epochs<100", "90", true},
+		{"epochs<100", "", false},
+		{"reviewed>2024-01", "2024-02-15", true},
+		{"reviewed<=2023", "2024-02-15", false},
+	}
+	for _, tc := range cases {
+		filter, err := parseFieldFilter(cfg, tc.expr)
+		if err != nil {
+			t.Fatalf("parse %s: %v", tc.expr, err)
+		}
+		if got := filter.matches(tc.value); got != tc.want {
+			t.Fatalf("%s against %q = %v, want %v", tc.expr, tc.value, got, tc.want)
+		}
+	}
+
+	for _, expr := range []string{"dataset>a", "unknown=1", "epochs=many", "dataset"} {
+		if _, err := parseFieldFilter(cfg, expr); err == nil {
+			t.Fatalf("expected error for %q", expr)
+		}
+	}
+}
+
+func TestParseMetadataEditorDataIdentifiers(t *testing.T) {
+	md, err := parseMetadataEditorData([]byte(`{"title":"T","identifiers":["isbn:0-262-03384-4"," ","https://arxiv.org/abs/2301.01234v2"]}`), "/lib/p.pdf", nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := meta.FormatIdentifiers(md.Identifiers); got != "arxiv:2301.01234, isbn:9780262033848" {
+		t.Fatalf("unexpected identifiers: %s", got)
+	}
+	if _, err := parseMetadataEditorData([]byte(`{"identifiers":["isbn:123"]}`), "/lib/p.pdf", nil); err == nil {
+		t.Fatalf("expected an error for an invalid ISBN")
+	}
+	md, err = parseMetadataEditorData([]byte(`{"title":"T"}`), "/lib/p.pdf", nil)
+	if err != nil || md.Identifiers != nil {
+		t.Fatalf("expected untouched identifiers without the key, got %+v (err %v)", md.Identifiers, err)
+	}
+
+	ids := opfIdentifiersToMeta([]opfIdentifier{
+		{Scheme: "ISBN", Value: "978-0-262-03384-8"},
+		{Value: "urn:uuid:0b3c8f0e-2f4a-4a8e-9d7e-1a2b3c4d5e6f"},
+		{Value: "doi:10.1000/Book"},
+		{Scheme: "ISBN", Value: "not an isbn"},
+	})
+	if got := meta.FormatIdentifiers(ids); got != "doi:10.1000/book, isbn:9780262033848" {
+		t.Fatalf("unexpected OPF identifiers: %s", got)
+	}
+}
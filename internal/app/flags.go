@@ -108,6 +108,14 @@ func (m *Model) refreshMetadataCache(path string, md meta.Metadata) {
 	}
 }
 
+// reloadCurrentMetadata drops the cached metadata and re-sorts the listing so
+// changes written behind the UI's back (tag rewrites, reverts) show up.
+func (m *Model) reloadCurrentMetadata() {
+	m.currentMetaPath = ""
+	m.currentMeta = nil
+	m.resortAndPreserveSelection()
+}
+
 func (m *Model) cycleReadingState() {
 	if m.meta == nil {
 		m.setStatus("Metadata store not available")
@@ -282,6 +290,16 @@ func (m *Model) showQuickFilter(mode quickFilterMode) tea.Cmd {
 		list, err = m.meta.ListByReadingState(ctx, readingStateReading)
 	case quickFilterRead:
 		list, err = m.meta.ListByReadingState(ctx, readingStateRead)
+	case quickFilterTopRated:
+		list, err = m.meta.Query(ctx, meta.Filter{
+			RatingMin: topRatedMin,
+			Sort:      []meta.Sort{{Field: meta.SortRating, Desc: true}, {Field: meta.SortTitle}},
+		})
+	case quickFilterPriority:
+		list, err = m.meta.Query(ctx, meta.Filter{
+			Priorities: []string{meta.PriorityHigh, meta.PriorityUrgent},
+			Sort:       []meta.Sort{{Field: meta.SortPriority, Desc: true}, {Field: meta.SortRating, Desc: true}, {Field: meta.SortTitle}},
+		})
 	default:
 		return nil
 	}
@@ -289,21 +307,7 @@ func (m *Model) showQuickFilter(mode quickFilterMode) tea.Cmd {
 		m.setStatus("Failed to load metadata: " + err.Error())
 		return nil
 	}
-	matches := make([]searchMatch, 0, len(list))
-	for _, md := range list {
-		match := searchMatch{
-			Path:       md.Path,
-			Mode:       searchModeTitle,
-			MatchCount: 1,
-			Snippets:   metadataSnippets(md),
-			Title:      strings.TrimSpace(md.Title),
-			Year:       strings.TrimSpace(md.Year),
-		}
-		if match.Title == "" {
-			match.Title = untitledPlaceholder
-		}
-		matches = append(matches, match)
-	}
+	matches := metadataMatches(list)
 	summary := quickFilterSummary(mode, len(matches))
 	msg := searchResultMsg{
 		req:      searchRequest{},
@@ -321,6 +325,25 @@ func (m *Model) showQuickFilter(mode quickFilterMode) tea.Cmd {
 	return nil
 }
 
+func metadataMatches(list []meta.Metadata) []searchMatch {
+	matches := make([]searchMatch, 0, len(list))
+	for _, md := range list {
+		match := searchMatch{
+			Path:       md.Path,
+			Mode:       searchModeTitle,
+			MatchCount: 1,
+			Snippets:   metadataSnippets(md),
+			Title:      strings.TrimSpace(md.Title),
+			Year:       strings.TrimSpace(md.Year),
+		}
+		if match.Title == "" {
+			match.Title = untitledPlaceholder
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
 func metadataSnippets(md meta.Metadata) []string {
 	lines := make([]string, 0, 5)
 	if strings.TrimSpace(md.Title) != "" {
@@ -348,6 +371,12 @@ func metadataSnippets(md meta.Metadata) []string {
 	if len(status) > 0 {
 		lines = append(lines, "Status: "+strings.Join(status, ", "))
 	}
+	if md.Rating > 0 {
+		lines = append(lines, "Rating: "+formatRating(md.Rating))
+	}
+	if p, _ := meta.NormalizePriority(md.Priority); p != meta.PriorityNormal {
+		lines = append(lines, "Priority: "+priorityLabel(p))
+	}
 	lines = append(lines, "Reading: "+readingStateLabel(md.ReadingState))
 	if !md.LastOpenedAt.IsZero() {
 		lines = append(lines, "Opened: "+formatTimestamp(md.LastOpenedAt))
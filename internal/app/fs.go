@@ -89,6 +89,7 @@ func (m *Model) loadEntries() {
 		filtered = append(filtered, e)
 	}
 
+	m.queueFileIdentities(filtered)
 	m.ensureDefaultReadingState(filtered)
 	m.entries = filtered
 	if m.cursor >= len(m.entries) {
@@ -103,10 +104,12 @@ const (
 	badgeColumnWidth = 1
 )
 
-func formatEntryColumns(state, favorite, toRead, year, title string) string {
+func formatEntryColumns(state, favorite, toRead, rating, priority, year, title string) string {
 	state = formatStateField(state)
 	favorite = formatBadgeField(favorite)
 	toRead = formatBadgeField(toRead)
+	rating = formatBadgeField(rating)
+	priority = formatBadgeField(priority)
 	year = strings.TrimSpace(year)
 	if year == "" {
 		year = "-"
@@ -115,7 +118,7 @@ func formatEntryColumns(state, favorite, toRead, year, title string) string {
 	if title == "" {
 		title = "-"
 	}
-	parts := []string{state, favorite, toRead, year, title}
+	parts := []string{state, favorite, toRead, rating, priority, year, title}
 	return strings.Join(parts, " ")
 }
 
@@ -237,7 +240,7 @@ func (m *Model) refreshEntryTitlesWithInfo(entryInfo map[string]entrySortInfo) {
 				if data.toRead {
 					toReadIcon = m.toReadIcon()
 				}
-				m.entryTitles[full] = formatEntryColumns(icon, favIcon, toReadIcon, data.year, data.title)
+				m.entryTitles[full] = formatEntryColumns(icon, favIcon, toReadIcon, ratingBadge(data.rating), priorityBadge(data.priority), data.year, data.title)
 				continue
 			}
 		}
@@ -246,7 +249,7 @@ func (m *Model) refreshEntryTitlesWithInfo(entryInfo map[string]entrySortInfo) {
 			continue
 		}
 		name := m.normalizedEntryBase(e.Name(), full)
-		m.entryTitles[full] = formatEntryColumns(m.readingStateIcon(""), "", "", "-", name)
+		m.entryTitles[full] = formatEntryColumns(m.readingStateIcon(""), "", "", "", "", "-", name)
 	}
 }
 
@@ -265,13 +268,13 @@ func (m *Model) resolveEntryTitle(ctx context.Context, fullPath string, entry fs
 	}
 	name := m.normalizedEntryBase(baseName, fullPath)
 	if m.meta == nil {
-		return formatEntryColumns(m.readingStateIcon(""), "", "", "-", name)
+		return formatEntryColumns(m.readingStateIcon(""), "", "", "", "", "-", name)
 	}
 
 	path := canonicalPath(fullPath)
 	md, err := m.meta.Get(ctx, path)
 	if err != nil || md == nil {
-		return formatEntryColumns(m.readingStateIcon(""), "", "", "-", name)
+		return formatEntryColumns(m.readingStateIcon(""), "", "", "", "", "-", name)
 	}
 	stateIcon := m.readingStateIcon(md.ReadingState)
 	title := strings.TrimSpace(md.Title)
@@ -290,7 +293,7 @@ func (m *Model) resolveEntryTitle(ctx context.Context, fullPath string, entry fs
 	if md.ToRead {
 		toReadIcon = m.toReadIcon()
 	}
-	return formatEntryColumns(stateIcon, favIcon, toReadIcon, year, title)
+	return formatEntryColumns(stateIcon, favIcon, toReadIcon, ratingBadge(md.Rating), priorityBadge(md.Priority), year, title)
 }
 
 func (m *Model) resortEntries() {
@@ -344,6 +347,8 @@ func (m *Model) buildEntrySortInfo(entries []fs.DirEntry) map[string]entrySortIn
 				data.state = normalizeReadingStateValue(md.ReadingState)
 				data.favorite = md.Favorite
 				data.toRead = md.ToRead
+				data.rating = md.Rating
+				data.priority = md.Priority
 			}
 		}
 		sortInfo[full] = data
@@ -376,6 +381,10 @@ func (m *Model) sortEntries(entries []fs.DirEntry, info map[string]entrySortInfo
 			return m.compareByTitle(a, b, info)
 		case sortByYear:
 			return m.compareByYear(a, b, info)
+		case sortByRating:
+			return m.compareByRating(a, b, info)
+		case sortByPriority:
+			return m.compareByPriority(a, b, info)
 		default:
 			return strings.ToLower(a.Name()) < strings.ToLower(b.Name())
 		}
@@ -409,6 +418,26 @@ func (m *Model) compareByYear(a, b fs.DirEntry, info map[string]entrySortInfo) b
 	return strings.ToLower(a.Name()) < strings.ToLower(b.Name())
 }
 
+func (m *Model) compareByRating(a, b fs.DirEntry, info map[string]entrySortInfo) bool {
+	ia := m.lookupSortInfo(a, info)
+	ib := m.lookupSortInfo(b, info)
+	if ia.rating != ib.rating {
+		return ia.rating > ib.rating
+	}
+	return m.compareByTitle(a, b, info)
+}
+
+func (m *Model) compareByPriority(a, b fs.DirEntry, info map[string]entrySortInfo) bool {
+	ia := m.lookupSortInfo(a, info)
+	ib := m.lookupSortInfo(b, info)
+	pa := meta.PriorityRank(ia.priority)
+	pb := meta.PriorityRank(ib.priority)
+	if pa != pb {
+		return pa > pb
+	}
+	return m.compareByTitle(a, b, info)
+}
+
 func (m *Model) lookupSortInfo(entry fs.DirEntry, info map[string]entrySortInfo) entrySortInfo {
 	if info != nil {
 		full := filepath.Join(m.cwd, entry.Name())
@@ -437,6 +466,8 @@ type entrySortInfo struct {
 	state    string
 	favorite bool
 	toRead   bool
+	rating   int
+	priority string
 }
 
 func (m *Model) normalizedEntryBase(name, fullPath string) string {
@@ -481,6 +512,6 @@ func (m *Model) ensureDefaultReadingState(entries []fs.DirEntry) {
 			Path:         canonical,
 			ReadingState: readingStateUnread,
 		}
-		_ = m.meta.Upsert(ctx, &record)
+		_ = m.meta.UpsertFrom(ctx, &record, meta.SourceImport)
 	}
 }
@@ -0,0 +1,183 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gorae/internal/meta"
+)
+
+// searchIndexedContent answers a content search from the full-text index in
+// the metadata store. Files that are new or changed since they were indexed
+// are extracted first; everything else is a lookup.
+func searchIndexedContent(scan *searchScan, req searchRequest, files []string, agg *searchAggregate) {
+	ctx := scan.ctx
+	store := req.metaStore
+	root := canonicalPath(req.root)
+	if root == "" {
+		root = req.root
+	}
+
+	agg.warnings = append(agg.warnings, refreshFullTextIndex(scan, store, root, files)...)
+	if scan.stopped() {
+		return
+	}
+
+	candidates := files
+	// The index only knows words, so a regex is matched against the indexed
+	// text of every file.
+	if req.pattern == nil {
+		if paths, ok, err := store.SearchFullText(ctx, root, req.query); err != nil {
+			agg.warnings = append(agg.warnings, fmt.Sprintf("[WARN] full-text index: %v", err))
+		} else if ok {
+			inScope := make(map[string]bool, len(files))
+			for _, path := range files {
+				inScope[path] = true
+			}
+			candidates = candidates[:0:0]
+			for _, path := range paths {
+				if inScope[path] {
+					candidates = append(candidates, path)
+				}
+			}
+		}
+	}
+
+	scan.begin(len(candidates), false)
+	for _, path := range candidates {
+		if scan.stopped() {
+			return
+		}
+		text, indexed, err := store.IndexedText(ctx, path)
+		if err != nil {
+			agg.warnings = append(agg.warnings, fmt.Sprintf("[WARN] %s: %v", path, err))
+			scan.checked(nil)
+			continue
+		}
+		if !indexed {
+			scan.checked(nil)
+			continue
+		}
+		match, ok := buildContentMatch(path, text, req.matcher(), req.wrapWidth, store)
+		if !ok {
+			scan.checked(nil)
+			continue
+		}
+		agg.matches = append(agg.matches, match)
+		agg.filesMatched++
+		agg.totalMatches += match.MatchCount
+		scan.checked(&match)
+	}
+}
+
+// refreshFullTextIndex extracts and stores the text of every file that is
+// missing from the index or changed since, and drops entries under root whose
+// file is gone. files may have been narrowed by filters, so entries outside
+// it are only dropped once their file no longer exists. Extraction failures
+// are returned as warnings and stamped, so the file is not retried until it
+// changes. Extraction is reported to scan and stops when it is cancelled.
+func refreshFullTextIndex(scan *searchScan, store *meta.Store, root string, files []string) []string {
+	ctx := scan.ctx
+	var warnings []string
+	stamps, err := store.FullTextStamps(ctx, root)
+	if err != nil {
+		return []string{fmt.Sprintf("[WARN] full-text index: %v", err)}
+	}
+
+	type job struct {
+		path  string
+		stamp meta.IndexStamp
+	}
+	var stale []job
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("[WARN] %s: %v", path, err))
+			continue
+		}
+		if st, ok := stamps[path]; ok && st.Fresh(info.Size(), info.ModTime()) {
+			continue
+		}
+		stale = append(stale, job{path: path, stamp: meta.IndexStamp{Size: info.Size(), ModTime: info.ModTime()}})
+	}
+	keep := make(map[string]bool, len(stamps))
+	for path := range stamps {
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			keep[path] = true
+		}
+	}
+	if err := store.PruneFullText(ctx, root, keep); err != nil {
+		warnings = append(warnings, fmt.Sprintf("[WARN] full-text index: %v", err))
+	}
+	if len(stale) == 0 {
+		return warnings
+	}
+
+	scan.begin(len(stale), true)
+	workerCount := searchWorkerCount()
+	jobs := make(chan job, workerCount*2)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	// Extraction runs in parallel; index writes are serialized so concurrent
+	// SQLite write transactions never have to contend for the lock.
+	var writeMu sync.Mutex
+	for i := 0; i < workerCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if scan.stopped() {
+					continue
+				}
+				text, err := readDocumentText(j.path)
+				writeMu.Lock()
+				if err == nil {
+					err = indexDocumentText(ctx, store, j.path, j.stamp, text)
+				} else if markErr := store.MarkIndexFailed(ctx, j.path, j.stamp); markErr != nil {
+					err = fmt.Errorf("%w (%v)", err, markErr)
+				}
+				writeMu.Unlock()
+				if err != nil {
+					mu.Lock()
+					warnings = append(warnings, fmt.Sprintf("[WARN] %s: %v", j.path, err))
+					mu.Unlock()
+				}
+				scan.checked(nil)
+			}
+		}()
+	}
+	for _, j := range stale {
+		if scan.stopped() {
+			break
+		}
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+	return warnings
+}
+
+// indexDocumentText stores text in the index and, for papers that already
+// have metadata, the references found in it. Searching never adds papers.
+func indexDocumentText(ctx context.Context, store *meta.Store, path string, stamp meta.IndexStamp, text string) error {
+	if err := store.IndexText(ctx, path, stamp, text); err != nil {
+		return err
+	}
+	md, err := store.Get(ctx, path)
+	if err != nil || md == nil {
+		return err
+	}
+	return store.SetReferences(ctx, path, meta.CitationSourceText, extractReferencesFromText(text))
+}
+
+func readDocumentText(path string) (string, error) {
+	if strings.EqualFold(filepath.Ext(path), ".epub") {
+		return readEPUBText(path)
+	}
+	return readPDFText(path)
+}
@@ -0,0 +1,191 @@
+package app
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorae/internal/meta"
+)
+
+func TestIndexedContentSearchUsesAndRefreshesIndex(t *testing.T) {
+	dir := canonicalPath(t.TempDir())
+	libDir := filepath.Join(dir, "library")
+	if err := os.MkdirAll(libDir, 0o755); err != nil {
+		t.Fatalf("mkdir library: %v", err)
+	}
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	first := filepath.Join(libDir, "first.epub")
+	second := filepath.Join(libDir, "second.epub")
+	writeTestEPUB(t, first, "Attention is all you need.")
+	writeTestEPUB(t, second, "Convolutional networks for vision.")
+
+	req := searchRequest{root: libDir, mode: searchModeContent, query: "attention", metaStore: store}
+	agg, _, err := performSearch(req)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if agg.filesMatched != 1 || agg.matches[0].Path != first {
+		t.Fatalf("expected only first.epub to match, got %+v", agg.matches)
+	}
+	if _, indexed, _ := store.IndexedText(ctx, second); !indexed {
+		t.Fatalf("expected second.epub to be indexed")
+	}
+	// The index finds substrings, as the scan it replaces did.
+	mid := req
+	mid.query = "tention is"
+	if agg, _, err := performSearch(mid); err != nil || agg.filesMatched != 1 {
+		t.Fatalf("expected a mid-word query to match first.epub, got %+v (%v)", agg.matches, err)
+	}
+
+	// A changed file is re-extracted; a removed file drops out of the index.
+	writeTestEPUB(t, second, "Self-attention for vision.")
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(second, future, future); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	if err := os.Remove(first); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	agg, _, err = performSearch(req)
+	if err != nil {
+		t.Fatalf("search after change: %v", err)
+	}
+	if agg.filesMatched != 1 || agg.matches[0].Path != second {
+		t.Fatalf("expected second.epub to match after re-index, got %+v", agg.matches)
+	}
+	if _, indexed, _ := store.IndexedText(ctx, first); indexed {
+		t.Fatalf("expected removed file to be pruned from the index")
+	}
+}
+
+func TestFilteredContentSearchKeepsIndex(t *testing.T) {
+	dir := canonicalPath(t.TempDir())
+	libDir := filepath.Join(dir, "library")
+	if err := os.MkdirAll(libDir, 0o755); err != nil {
+		t.Fatalf("mkdir library: %v", err)
+	}
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	rated := filepath.Join(libDir, "rated.epub")
+	other := filepath.Join(libDir, "other.epub")
+	writeTestEPUB(t, rated, "Attention is all you need.")
+	writeTestEPUB(t, other, "Attention for vision.")
+	if err := store.Upsert(ctx, &meta.Metadata{Path: rated, Title: "Rated", Rating: 5}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	if _, _, err := performSearch(searchRequest{root: libDir, mode: searchModeContent, query: "attention", metaStore: store}); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	agg, _, err := performSearch(searchRequest{root: libDir, mode: searchModeContent, query: "attention", metaStore: store, ratingMin: 4})
+	if err != nil {
+		t.Fatalf("filtered search: %v", err)
+	}
+	if agg.filesMatched != 1 || agg.matches[0].Path != rated {
+		t.Fatalf("expected only rated.epub to match, got %+v", agg.matches)
+	}
+	if _, indexed, _ := store.IndexedText(ctx, other); !indexed {
+		t.Fatalf("expected a filtered search to keep other.epub in the index")
+	}
+}
+
+func TestContentSearchStampsFailuresAndAddsNoPapers(t *testing.T) {
+	dir := canonicalPath(t.TempDir())
+	libDir := filepath.Join(dir, "library")
+	if err := os.MkdirAll(libDir, 0o755); err != nil {
+		t.Fatalf("mkdir library: %v", err)
+	}
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	known := filepath.Join(libDir, "known.epub")
+	unknown := filepath.Join(libDir, "unknown.epub")
+	broken := filepath.Join(libDir, "broken.pdf")
+	writeTestEPUB(t, known, "Attention, see doi:10.1000/cited.1 for details.")
+	writeTestEPUB(t, unknown, "Attention, see doi:10.1000/cited.2 as well.")
+	if err := os.WriteFile(broken, []byte("not a pdf"), 0o644); err != nil {
+		t.Fatalf("write broken: %v", err)
+	}
+	if err := store.Upsert(ctx, &meta.Metadata{Path: known, Title: "Known"}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	search := func() []string {
+		t.Helper()
+		agg, _, err := performSearch(searchRequest{root: libDir, mode: searchModeContent, query: "attention", metaStore: store})
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if agg.filesMatched != 2 {
+			t.Fatalf("expected both EPUBs to match, got %+v", agg.matches)
+		}
+		return agg.warnings
+	}
+	if warnings := search(); len(warnings) != 1 || !strings.Contains(warnings[0], broken) {
+		t.Fatalf("expected one warning for broken.pdf, got %v", warnings)
+	}
+	if warnings := search(); len(warnings) != 0 {
+		t.Fatalf("expected the failed file not to be extracted again, got %v", warnings)
+	}
+
+	if md, err := store.Get(ctx, unknown); err != nil || md != nil {
+		t.Fatalf("expected searching to add no paper, got %+v (%v)", md, err)
+	}
+	if n, err := store.ReferenceCount(ctx, known); err != nil || n != 1 {
+		t.Fatalf("expected the known paper's reference, got %d (%v)", n, err)
+	}
+}
+
+func writeTestEPUB(t *testing.T, path, body string) {
+	t.Helper()
+	writeTestEPUBWithMetadata(t, path, "", body)
+}
+
+// writeTestEPUBWithMetadata writes an EPUB whose OPF <metadata> element holds
+// metadata.
+func writeTestEPUBWithMetadata(t *testing.T, path, metadata, body string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create epub: %v", err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	files := []struct{ name, data string }{
+		{"META-INF/container.xml", `<?xml version="1.0"?><container><rootfiles><rootfile full-path="OEBPS/content.opf"/></rootfiles></container>`},
+		{"OEBPS/content.opf", `<?xml version="1.0"?><package xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf"><metadata>` + metadata + `</metadata><manifest><item id="c1" href="c1.xhtml"/></manifest><spine><itemref idref="c1"/></spine></package>`},
+		{"OEBPS/c1.xhtml", "<html><body><p>" + body + "</p></body></html>"},
+	}
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			t.Fatalf("zip create %s: %v", file.name, err)
+		}
+		if _, err := w.Write([]byte(file.data)); err != nil {
+			t.Fatalf("zip write %s: %v", file.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package app
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// textSpan is a byte range of a string to highlight.
+type textSpan struct{ start, end int }
+
+// textMatcher decides whether a field value matches a query and where. A
+// regex matcher matches re; a fuzzy matcher folds case and diacritics and
+// forgives typos; otherwise the query must occur as written, up to case
+// unless caseSensitive.
+type textMatcher struct {
+	query         string
+	caseSensitive bool
+	fuzzy         bool
+	re            *regexp.Regexp
+}
+
+func (t textMatcher) match(value string) bool {
+	switch {
+	case t.re != nil:
+		return t.re.MatchString(value)
+	case t.fuzzy:
+		_, ok := fuzzyFind(value, t.query)
+		return ok
+	}
+	return containsFold(value, t.query, t.caseSensitive)
+}
+
+// find returns the spans of value that t matches, in order.
+func (t textMatcher) find(value string) []textSpan {
+	switch {
+	case t.re != nil:
+		var spans []textSpan
+		for _, loc := range t.re.FindAllStringIndex(value, -1) {
+			if loc[1] > loc[0] {
+				spans = append(spans, textSpan{loc[0], loc[1]})
+			}
+		}
+		return spans
+	case t.fuzzy:
+		spans, _ := fuzzyFind(value, t.query)
+		return spans
+	}
+	var spans []textSpan
+	for _, pos := range findAllMatches(value, t.query, t.caseSensitive) {
+		spans = append(spans, textSpan{pos, pos + len(t.query)})
+	}
+	return spans
+}
+
+// matchTags is matchTags for t; a pattern is matched against each tag on
+// its own, so ^ and $ anchor to tag boundaries.
+func (t textMatcher) matchTags(stored string) bool {
+	if t.re == nil {
+		return matchTags(stored, t.query, t.caseSensitive)
+	}
+	for _, tag := range splitTags(stored) {
+		if t.re.MatchString(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// exact is t without typo tolerance, for fields other than the one searched.
+func (t textMatcher) exact() textMatcher {
+	t.fuzzy = false
+	return t
+}
+
+// highlight marks the parts of value that t matches, like highlightField.
+func (t textMatcher) highlight(value string) string {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return "(empty)"
+	}
+	return t.mark(trimmed)
+}
+
+// mark highlights the matches in s without trimming it.
+func (t textMatcher) mark(s string) string {
+	if t.re == nil && !t.fuzzy {
+		return highlight(s, t.query, t.caseSensitive)
+	}
+	return highlightSpans(s, t.find(s))
+}
+
+// foldMap spells letters with diacritics and ligatures the way they are
+// commonly typed without them.
+var foldMap = func() map[rune]string {
+	m := make(map[rune]string)
+	for base, letters := range map[string]string{
+		"a": "àáâãäåāăą", "c": "çćĉċč", "d": "ďđð", "e": "èéêëēĕėęě",
+		"g": "ĝğġģ", "h": "ĥħ", "i": "ìíîïĩīĭįı", "j": "ĵ", "k": "ķ",
+		"l": "ĺļľŀł", "n": "ñńņňŉ", "o": "òóôõöøōŏő", "r": "ŕŗř",
+		"s": "śŝşšș", "t": "ţťŧț", "u": "ùúûüũūŭůűų", "w": "ŵ",
+		"y": "ýÿŷ", "z": "źżž",
+		"ss": "ß", "ae": "æ", "oe": "œ", "th": "þ",
+	} {
+		for _, r := range letters {
+			m[r] = base
+		}
+	}
+	return m
+}()
+
+// foldedText is a lowercased string without diacritics. src holds, for each
+// folded rune, the byte range of the rune in the original it came from.
+type foldedText struct {
+	runes []rune
+	src   []textSpan
+}
+
+func foldText(s string) foldedText {
+	var ft foldedText
+	for i, r := range s {
+		span := textSpan{i, i + len(string(r))}
+		if unicode.Is(unicode.Mn, r) {
+			// A combining accent of an already decomposed letter.
+			continue
+		}
+		r = unicode.ToLower(r)
+		if repl, ok := foldMap[r]; ok {
+			for _, f := range repl {
+				ft.runes = append(ft.runes, f)
+				ft.src = append(ft.src, span)
+			}
+			continue
+		}
+		ft.runes = append(ft.runes, r)
+		ft.src = append(ft.src, span)
+	}
+	return ft
+}
+
+// span maps folded runes [from, to) back to a byte range of the original.
+func (ft foldedText) span(from, to int) textSpan {
+	return textSpan{ft.src[from].start, ft.src[to-1].end}
+}
+
+// words returns the [start, end) rune ranges of the letter and digit runs.
+func (ft foldedText) words() [][2]int {
+	var words [][2]int
+	start := -1
+	for i, r := range ft.runes {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			words = append(words, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, [2]int{start, len(ft.runes)})
+	}
+	return words
+}
+
+// fuzzyFind matches query against text ignoring case and diacritics. The
+// whole query may appear as is; otherwise every query word must be within a
+// few typos of a word in text (or of its beginning), so "bengoi" finds
+// "Bengio" and "atention" finds "Attention". It returns the spans of text
+// that matched.
+func fuzzyFind(text, query string) ([]textSpan, bool) {
+	ft, fq := foldText(text), foldText(strings.TrimSpace(query))
+	if len(fq.runes) == 0 || len(ft.runes) == 0 {
+		return nil, false
+	}
+	if spans := findRuneRuns(ft, fq.runes); len(spans) > 0 {
+		return spans, true
+	}
+
+	textWords := ft.words()
+	var spans []textSpan
+	for _, qw := range fq.words() {
+		q := fq.runes[qw[0]:qw[1]]
+		allowed := fuzzyTolerance(len(q))
+		best, bestDist := -1, allowed+1
+		for i, tw := range textWords {
+			w := ft.runes[tw[0]:tw[1]]
+			d := osaDistance(q, w)
+			if len(w) > len(q) {
+				d = min(d, osaDistance(q, w[:len(q)]))
+			}
+			if d < bestDist {
+				best, bestDist = i, d
+			}
+		}
+		if best < 0 {
+			return nil, false
+		}
+		spans = append(spans, ft.span(textWords[best][0], textWords[best][1]))
+	}
+	return spans, len(spans) > 0
+}
+
+// fuzzyTolerance is how many typos a query word of n letters may contain.
+func fuzzyTolerance(n int) int {
+	switch {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	}
+	return 2
+}
+
+func findRuneRuns(ft foldedText, needle []rune) []textSpan {
+	var spans []textSpan
+	for i := 0; i+len(needle) <= len(ft.runes); i++ {
+		if string(ft.runes[i:i+len(needle)]) == string(needle) {
+			spans = append(spans, ft.span(i, i+len(needle)))
+			i += len(needle) - 1
+		}
+	}
+	return spans
+}
+
+// osaDistance is the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and swaps of neighbours count one.
+func osaDistance(a, b []rune) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+// highlightSpans marks spans of value, merging overlaps.
+func highlightSpans(value string, spans []textSpan) string {
+	if len(spans) == 0 {
+		return value
+	}
+	spans = append([]textSpan(nil), spans...)
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	const (
+		startHL = "\033[1;31m"
+		endHL   = "\033[0m"
+	)
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.end <= last {
+			continue
+		}
+		if s.start < last {
+			s.start = last
+		}
+		b.WriteString(value[last:s.start])
+		b.WriteString(startHL)
+		b.WriteString(value[s.start:s.end])
+		b.WriteString(endHL)
+		last = s.end
+	}
+	b.WriteString(value[last:])
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFuzzyFind(t *testing.T) {
+	cases := []struct {
+		text, query string
+		want        bool
+		hit         string
+	}{
+		{"Yoshua Bengio", "bengoi", true, "Bengio"},
+		{"Attention Is All You Need", "atention", true, "Attention"},
+		{"Bernhard Schölkopf", "Scholkopf", true, "Schölkopf"},
+		{"Bernhard Schölkopf", "schoelkopf", true, "Schölkopf"},
+		{"Deep Residual Learning", "resid", true, "resid"},
+		{"Geoffrey Hinton", "bengio", false, ""},
+		{"Convolutional Networks", "cnn", false, ""},
+	}
+	for _, tc := range cases {
+		spans, ok := fuzzyFind(tc.text, tc.query)
+		if ok != tc.want {
+			t.Fatalf("fuzzyFind(%q, %q) = %v, want %v", tc.text, tc.query, ok, tc.want)
+		}
+		if !ok {
+			continue
+		}
+		if len(spans) == 0 {
+			t.Fatalf("fuzzyFind(%q, %q) returned no spans", tc.text, tc.query)
+		}
+		if got := tc.text[spans[0].start:spans[0].end]; !strings.EqualFold(got, tc.hit) {
+			t.Fatalf("fuzzyFind(%q, %q) matched %q, want %q", tc.text, tc.query, got, tc.hit)
+		}
+	}
+}
+
+func TestTextMatcherExact(t *testing.T) {
+	fuzzy := textMatcher{query: "bengoi", fuzzy: true}
+	if !fuzzy.match("Yoshua Bengio") {
+		t.Fatalf("expected the fuzzy matcher to forgive the swap")
+	}
+	if got := fuzzy.highlight("Yoshua Bengio"); !strings.Contains(got, "\033[1;31mBengio\033[0m") {
+		t.Fatalf("expected Bengio highlighted, got %q", got)
+	}
+	if (textMatcher{query: "bengoi"}).match("Yoshua Bengio") {
+		t.Fatalf("expected the exact matcher to need the query as written")
+	}
+
+	m := &Model{cwd: t.TempDir(), root: t.TempDir()}
+	req, err := m.buildSearchRequest([]string{"-a", "bengoi"})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if !req.fuzzy() {
+		t.Fatalf("expected author search to be fuzzy by default")
+	}
+	for _, line := range [][]string{{"-a", "-e", "bengoi"}, {"-a", "-case", "bengoi"}, {"-c", "bengoi"}} {
+		req, err := m.buildSearchRequest(line)
+		if err != nil {
+			t.Fatalf("build %q: %v", line, err)
+		}
+		if req.fuzzy() {
+			t.Fatalf("expected %q to match exactly", line)
+		}
+	}
+}
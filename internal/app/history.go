@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const historyValueWidth = 60
+
+func (m *Model) handleHistoryCommand(args []string) tea.Cmd {
+	if m.meta == nil {
+		m.setStatus("Metadata store not available")
+		return nil
+	}
+	target := canonicalPath(m.currentEntryPath())
+	if target == "" {
+		m.setStatus("No file selected")
+		return nil
+	}
+	if len(args) == 0 {
+		return m.showHistory(target)
+	}
+	switch strings.ToLower(args[0]) {
+	case "revert", "undo":
+		if len(args) < 2 {
+			m.setStatus("Usage: :history revert <revision> [field...]")
+			return nil
+		}
+		rev, err := strconv.ParseInt(strings.TrimPrefix(args[1], "r"), 10, 64)
+		if err != nil {
+			m.setStatus(fmt.Sprintf("Invalid revision: %s", args[1]))
+			return nil
+		}
+		fields := make([]string, 0, len(args)-2)
+		for _, f := range args[2:] {
+			fields = append(fields, strings.ToLower(f))
+		}
+		n, err := m.meta.RevertRevision(context.Background(), target, rev, fields...)
+		if err != nil {
+			m.setStatus("Revert failed: " + err.Error())
+			return nil
+		}
+		m.reloadCurrentMetadata()
+		if err := m.syncCollectionDirectories(); err != nil {
+			m.setStatus("Failed to sync Favorites/To-read directories: " + err.Error())
+			return nil
+		}
+		m.setStatus(fmt.Sprintf("Reverted %d field(s) from r%d", n, rev))
+	default:
+		m.setStatus(fmt.Sprintf("Unknown history command: %s", args[0]))
+	}
+	return nil
+}
+
+func (m *Model) showHistory(path string) tea.Cmd {
+	revisions, err := m.meta.History(context.Background(), path)
+	if err != nil {
+		m.setStatus("Failed to load history: " + err.Error())
+		return nil
+	}
+	if len(revisions) == 0 {
+		m.setStatus("No metadata history for " + filepath.Base(path))
+		return nil
+	}
+	lines := []string{fmt.Sprintf("History of %s (newest first):", filepath.Base(path))}
+	for _, rev := range revisions {
+		lines = append(lines, fmt.Sprintf("  r%d  %s  %s", rev.ID, formatTimestamp(rev.ChangedAt), rev.Source))
+		for _, ch := range rev.Changes {
+			lines = append(lines, fmt.Sprintf("      %-13s %s → %s", ch.Field, historyValue(ch.Old), historyValue(ch.New)))
+		}
+	}
+	lines = append(lines, "", "Revert with :history revert <revision> [field...]")
+	m.setCommandOutput(lines)
+	m.setStatus(fmt.Sprintf("%d revision(s)", len(revisions)))
+	return nil
+}
+
+func historyValue(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return "(empty)"
+	}
+	if runes := []rune(value); len(runes) > historyValueWidth {
+		value = string(runes[:historyValueWidth-1]) + "…"
+	}
+	return strconv.Quote(value)
+}
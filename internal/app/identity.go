@@ -0,0 +1,171 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"gorae/internal/meta"
+)
+
+// identitiesReconciledMsg reports a finished fingerprinting pass.
+type identitiesReconciledMsg struct {
+	reconnected int
+}
+
+// queueFileIdentities remembers the documents of the current directory for
+// the next background fingerprinting pass; hashing them on the update path
+// would freeze the UI in large or freshly copied directories.
+func (m *Model) queueFileIdentities(entries []fs.DirEntry) {
+	if m.meta == nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if canonical := canonicalPath(filepath.Join(m.cwd, entry.Name())); canonical != "" {
+			m.pendingIdentities = append(m.pendingIdentities, canonical)
+		}
+	}
+}
+
+// reconcileFileIdentitiesCmd fingerprints the queued documents in the
+// background and reconnects metadata rows whose file was moved outside gorae.
+func (m *Model) reconcileFileIdentitiesCmd() tea.Cmd {
+	paths := m.pendingIdentities
+	m.pendingIdentities = nil
+	if m.meta == nil || len(paths) == 0 {
+		return nil
+	}
+	store, notesDir, mu := m.meta, m.notesDir, m.identitySync
+	return func() tea.Msg {
+		if mu != nil {
+			mu.Lock()
+			defer mu.Unlock()
+		}
+		ctx := context.Background()
+		reconnected := 0
+		for _, path := range paths {
+			// Stat the document itself: paths are canonical, so entries of
+			// the symlink folders resolve to their target.
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			oldPath, err := reconcileFileIdentity(ctx, store, path, info, notesDir)
+			if err == nil && oldPath != "" {
+				reconnected++
+			}
+		}
+		return identitiesReconciledMsg{reconnected: reconnected}
+	}
+}
+
+// handleIdentitiesReconciled refreshes what a reconnected row changes.
+func (m *Model) handleIdentitiesReconciled(msg identitiesReconciledMsg) {
+	if msg.reconnected == 0 {
+		return
+	}
+	m.setStatus(fmt.Sprintf("Reconnected metadata for %d moved file(s)", msg.reconnected))
+	if err := m.syncCollectionDirectories(); err != nil {
+		m.setStatus("Failed to sync Favorites/To-read directories: " + err.Error())
+	}
+	m.loadEntries()
+}
+
+// reconcileFileIdentity records the content hash of path. When path has no
+// fingerprinted metadata yet but a row with the same hash points at a file
+// that no longer exists, that row (and its note) is moved to path and the old
+// path returned. A bare row already at path, such as the default one
+// loadEntries adds, gives way to the moved row.
+func reconcileFileIdentity(ctx context.Context, store *meta.Store, path string, info os.FileInfo, notesDir string) (string, error) {
+	current, exists, err := store.Identity(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	if exists && current.Matches(info.Size(), info.ModTime()) {
+		return "", nil
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+	id := meta.FileIdentity{Hash: hash, Size: info.Size(), ModTime: info.ModTime()}
+
+	moved := ""
+	if !exists || current.Hash == "" {
+		candidates, err := store.PathsByHash(ctx, hash)
+		if err != nil {
+			return "", err
+		}
+		for _, candidate := range candidates {
+			if candidate == path {
+				continue
+			}
+			if _, err := os.Stat(candidate); !os.IsNotExist(err) {
+				continue
+			}
+			adopted, err := store.AdoptPath(ctx, candidate, path)
+			if err != nil {
+				return "", err
+			}
+			if !adopted {
+				break
+			}
+			moveNoteFile(notesDir, candidate, path)
+			moved = candidate
+			break
+		}
+		if moved == "" && !exists {
+			record := meta.Metadata{Path: path, ReadingState: readingStateUnread}
+			if err := store.UpsertFrom(ctx, &record, meta.SourceImport); err != nil {
+				return "", err
+			}
+		}
+	}
+	if err := store.SetIdentity(ctx, path, id); err != nil {
+		return "", err
+	}
+	return moved, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// moveNoteFile renames the note of oldPath so it follows the document. An
+// existing note at the new location is left untouched.
+func moveNoteFile(notesDir, oldPath, newPath string) {
+	from, err := noteFilePathIn(notesDir, oldPath)
+	if err != nil {
+		return
+	}
+	to, err := noteFilePathIn(notesDir, newPath)
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(from); err != nil {
+		return
+	}
+	if _, err := os.Stat(to); err == nil {
+		return
+	}
+	_ = os.Rename(from, to)
+}
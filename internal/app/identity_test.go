@@ -0,0 +1,237 @@
+package app
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestReconcileFileIdentityReconnectsMovedFile(t *testing.T) {
+	dir := t.TempDir()
+	notesDir := filepath.Join(dir, "notes")
+	if err := os.MkdirAll(notesDir, 0o755); err != nil {
+		t.Fatalf("mkdir notes: %v", err)
+	}
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	oldPath := filepath.Join(dir, "paper.pdf")
+	writeDummyPDF(t, oldPath)
+	oldPath = canonicalPath(oldPath)
+	if err := store.Upsert(ctx, &meta.Metadata{Path: oldPath, Title: "Paper", Favorite: true}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	info, err := os.Stat(oldPath)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if moved, err := reconcileFileIdentity(ctx, store, oldPath, info, notesDir); err != nil || moved != "" {
+		t.Fatalf("fingerprint: moved=%q err=%v", moved, err)
+	}
+	note, _ := noteFilePathIn(notesDir, oldPath)
+	if err := os.WriteFile(note, []byte("note"), 0o644); err != nil {
+		t.Fatalf("write note: %v", err)
+	}
+
+	// Move the file behind gorae's back.
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatalf("mkdir sub: %v", err)
+	}
+	newPath := filepath.Join(dir, "sub", "renamed.pdf")
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	newPath = canonicalPath(newPath)
+	info, err = os.Stat(newPath)
+	if err != nil {
+		t.Fatalf("stat moved: %v", err)
+	}
+	moved, err := reconcileFileIdentity(ctx, store, newPath, info, notesDir)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if moved != oldPath {
+		t.Fatalf("expected reconnect from %s, got %q", oldPath, moved)
+	}
+	md, err := store.Get(ctx, newPath)
+	if err != nil || md == nil {
+		t.Fatalf("expected metadata at new path, got %v err=%v", md, err)
+	}
+	if md.Title != "Paper" || !md.Favorite {
+		t.Fatalf("metadata not carried over: %+v", md)
+	}
+	if old, _ := store.Get(ctx, oldPath); old != nil {
+		t.Fatalf("expected old row to be gone")
+	}
+	newNote, _ := noteFilePathIn(notesDir, newPath)
+	if _, err := os.Stat(newNote); err != nil {
+		t.Fatalf("expected note to follow the file: %v", err)
+	}
+}
+
+func TestFileIdentitiesOfSymlinkFolderUseTarget(t *testing.T) {
+	dir := canonicalPath(t.TempDir())
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	paper := filepath.Join(dir, "paper.pdf")
+	writeDummyPDF(t, paper)
+	favorites := filepath.Join(dir, "Favorites")
+	if err := os.MkdirAll(favorites, 0o755); err != nil {
+		t.Fatalf("mkdir favorites: %v", err)
+	}
+	if err := os.Symlink(paper, filepath.Join(favorites, "paper.pdf")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	m := &Model{cwd: favorites, meta: store, identitySync: new(sync.Mutex)}
+	reconcile := func() {
+		t.Helper()
+		entries, err := os.ReadDir(m.cwd)
+		if err != nil {
+			t.Fatalf("read dir: %v", err)
+		}
+		var docs []fs.DirEntry
+		for _, entry := range entries {
+			if filepath.Ext(entry.Name()) == ".pdf" {
+				docs = append(docs, entry)
+			}
+		}
+		m.queueFileIdentities(docs)
+		cmd := m.reconcileFileIdentitiesCmd()
+		if cmd == nil {
+			t.Fatalf("expected a fingerprinting command")
+		}
+		if msg := cmd().(identitiesReconciledMsg); msg.reconnected != 0 {
+			t.Fatalf("unexpected reconnect: %+v", msg)
+		}
+	}
+	reconcile()
+	info, err := os.Stat(paper)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	id, ok, err := store.Identity(ctx, paper)
+	if err != nil || !ok || !id.Matches(info.Size(), info.ModTime()) {
+		t.Fatalf("expected the identity of the target, got %+v ok=%v err=%v", id, ok, err)
+	}
+
+	// Visiting the folder again leaves the store alone.
+	before, _ := store.Generation(ctx)
+	reconcile()
+	m.cwd = dir
+	reconcile()
+	if after, _ := store.Generation(ctx); after != before {
+		t.Fatalf("expected no writes for unchanged files, generation %d -> %d", before, after)
+	}
+}
+
+func TestLoadEntriesReconnectsFileMovedInShell(t *testing.T) {
+	dir := canonicalPath(t.TempDir())
+	notesDir := filepath.Join(dir, "notes")
+	if err := os.MkdirAll(notesDir, 0o755); err != nil {
+		t.Fatalf("mkdir notes: %v", err)
+	}
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	lib := filepath.Join(dir, "lib")
+	sub := filepath.Join(lib, "sub")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatalf("mkdir lib: %v", err)
+	}
+	oldPath := filepath.Join(lib, "paper.pdf")
+	writeDummyPDF(t, oldPath)
+
+	m := &Model{cwd: lib, root: lib, notesDir: notesDir, meta: store, identitySync: new(sync.Mutex)}
+	visit := func(cwd string) identitiesReconciledMsg {
+		t.Helper()
+		m.cwd = cwd
+		m.loadEntries()
+		cmd := m.reconcileFileIdentitiesCmd()
+		if cmd == nil {
+			t.Fatalf("expected a fingerprinting command for %s", cwd)
+		}
+		return cmd().(identitiesReconciledMsg)
+	}
+	visit(lib)
+	if err := store.Upsert(ctx, &meta.Metadata{Path: oldPath, Title: "Paper", Favorite: true}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	note, _ := noteFilePathIn(notesDir, oldPath)
+	if err := os.WriteFile(note, []byte("note"), 0o644); err != nil {
+		t.Fatalf("write note: %v", err)
+	}
+
+	newPath := filepath.Join(sub, "renamed.pdf")
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if msg := visit(sub); msg.reconnected != 1 {
+		t.Fatalf("expected one reconnect, got %+v", msg)
+	}
+	md, err := store.Get(ctx, newPath)
+	if err != nil || md == nil || md.Title != "Paper" || !md.Favorite {
+		t.Fatalf("metadata not carried over: %+v err=%v", md, err)
+	}
+	if old, _ := store.Get(ctx, oldPath); old != nil {
+		t.Fatalf("expected old row to be gone")
+	}
+	newNote, _ := noteFilePathIn(notesDir, newPath)
+	if _, err := os.Stat(newNote); err != nil {
+		t.Fatalf("expected note to follow the file: %v", err)
+	}
+}
+
+func TestReconcileKeepsFilledRowAtNewPath(t *testing.T) {
+	dir := canonicalPath(t.TempDir())
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	oldPath := filepath.Join(dir, "paper.pdf")
+	writeDummyPDF(t, oldPath)
+	info, err := os.Stat(oldPath)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if err := store.Upsert(ctx, &meta.Metadata{Path: oldPath, Title: "Old"}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if _, err := reconcileFileIdentity(ctx, store, oldPath, info, ""); err != nil {
+		t.Fatalf("fingerprint: %v", err)
+	}
+	newPath := filepath.Join(dir, "copy.pdf")
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if err := store.Upsert(ctx, &meta.Metadata{Path: newPath, Title: "New"}); err != nil {
+		t.Fatalf("upsert new: %v", err)
+	}
+	if moved, err := reconcileFileIdentity(ctx, store, newPath, info, ""); err != nil || moved != "" {
+		t.Fatalf("expected no reconnect over a filled row, moved=%q err=%v", moved, err)
+	}
+	if md, _ := store.Get(ctx, newPath); md == nil || md.Title != "New" {
+		t.Fatalf("filled row replaced: %+v", md)
+	}
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"gorae/internal/meta"
+)
+
+// Close closes the metadata store of the library currently open.
+func (m Model) Close() error {
+	if m.meta == nil {
+		return nil
+	}
+	return m.meta.Close()
+}
+
+func (m *Model) handleLibraryCommand(args []string) tea.Cmd {
+	if m.cfg == nil {
+		m.setStatus("Config not available")
+		return nil
+	}
+	if len(args) == 0 || strings.EqualFold(args[0], "list") {
+		m.showLibraries()
+		return nil
+	}
+	switch strings.ToLower(args[0]) {
+	case "switch", "use", "open":
+		if len(args) != 2 {
+			m.setStatus("Usage: :library switch <name>")
+			return nil
+		}
+		store := m.meta
+		if err := m.switchLibrary(args[1]); err != nil {
+			m.setStatus("Library switch failed: " + err.Error())
+			return nil
+		}
+		if m.meta != store {
+			return watchStoreCmd(m.meta)
+		}
+		return nil
+	default:
+		m.setStatus(fmt.Sprintf("Unknown library command: %s", args[0]))
+		return nil
+	}
+}
+
+func (m *Model) showLibraries() {
+	active := m.cfg.ActiveLibrary()
+	lines := []string{"Libraries:"}
+	for _, name := range m.cfg.LibraryNames() {
+		marker := "  "
+		if name == active {
+			marker = "* "
+		}
+		watch := ""
+		if cfg, err := m.cfg.ForLibrary(name); err == nil {
+			watch = cfg.WatchDir
+		}
+		lines = append(lines, fmt.Sprintf("%s%-16s %s", marker, name, watch))
+	}
+	lines = append(lines, "", "Use :library switch <name> to change library.")
+	m.setCommandOutput(lines)
+	m.setPersistentStatus(fmt.Sprintf("Library: %s (use :clear to hide)", active))
+}
+
+// switchLibrary reopens the UI on another library profile: its store, root,
+// notes and helper folders. Settings shared by all libraries carry over, as
+// do the window size and command history.
+func (m *Model) switchLibrary(name string) error {
+	cfg, err := m.cfg.ForLibrary(name)
+	if err != nil {
+		return err
+	}
+	if cfg.ActiveLibrary() == m.cfg.ActiveLibrary() && cfg.WatchDir == m.cfg.WatchDir {
+		m.setStatus(fmt.Sprintf("Already in library %s", cfg.ActiveLibrary()))
+		return nil
+	}
+	store, err := meta.Open(cfg.DBPath())
+	if err != nil {
+		return err
+	}
+	// Reading sessions still open in a viewer keep the old store; their
+	// duration is lost when it closes, which beats leaking it.
+	if err := m.Close(); err != nil {
+		_ = store.Close()
+		return err
+	}
+
+	next := NewModel(cfg, store)
+	next.width = m.width
+	next.windowHeight = m.windowHeight
+	next.viewportHeight = m.viewportHeight
+	next.commandHistory = m.commandHistory
+	next.commandHistoryIndex = len(m.commandHistory)
+	*m = next
+	if !m.sticky {
+		m.setStatus(fmt.Sprintf("Switched to library %s (%s)", cfg.ActiveLibrary(), cfg.WatchDir))
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorae/internal/config"
+	"gorae/internal/meta"
+)
+
+func TestSwitchLibraryReopensModel(t *testing.T) {
+	base := canonicalPath(t.TempDir())
+	work := filepath.Join(base, "work")
+	reading := filepath.Join(base, "reading")
+	metaDir := filepath.Join(base, "meta")
+	for _, dir := range []string{work, reading, metaDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+	writeDummyPDF(t, filepath.Join(reading, "paper.pdf"))
+
+	cfg := &config.Config{
+		WatchDir:  work,
+		MetaDir:   metaDir,
+		NotesDir:  filepath.Join(metaDir, "notes"),
+		Libraries: map[string]config.Library{"reading": {WatchDir: reading}},
+	}
+	store, err := meta.Open(cfg.DBPath())
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	m := NewModel(cfg, store)
+	m.width, m.windowHeight = 120, 40
+
+	if err := m.switchLibrary("missing"); err == nil {
+		t.Fatalf("expected an unknown library to fail")
+	}
+	if err := m.switchLibrary("Reading"); err != nil {
+		t.Fatalf("switch: %v", err)
+	}
+	t.Cleanup(func() { _ = m.Close() })
+
+	if m.root != reading || m.cfg.ActiveLibrary() != "reading" || m.width != 120 {
+		t.Fatalf("unexpected model after switch: root=%s library=%s width=%d", m.root, m.cfg.ActiveLibrary(), m.width)
+	}
+	wantMeta := filepath.Join(metaDir, "libraries", "reading")
+	if m.cfg.MetaDir != wantMeta || m.notesDir != filepath.Join(wantMeta, "notes") {
+		t.Fatalf("unexpected library dirs: meta=%s notes=%s", m.cfg.MetaDir, m.notesDir)
+	}
+	if _, err := store.Get(context.Background(), work); err == nil {
+		t.Fatalf("expected the old store to be closed")
+	}
+	if len(m.entries) == 0 {
+		t.Fatalf("expected the reading library to be listed")
+	}
+
+	if err := m.switchLibrary(config.DefaultLibrary); err != nil {
+		t.Fatalf("switch back: %v", err)
+	}
+	if m.root != work || m.cfg.ActiveLibrary() != config.DefaultLibrary {
+		t.Fatalf("expected to be back in the default library, got %s", m.root)
+	}
+}
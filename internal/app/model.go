@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	textinput "github.com/charmbracelet/bubbles/textinput"
@@ -24,6 +25,10 @@ const (
 	statusMessageTTL = 4 * time.Second
 	favoritesDirName = "Favorites"
 	toReadDirName    = "To Read"
+	// collectionsDirName holds one symlink folder per named collection.
+	collectionsDirName = "Collections"
+	// savedSearchesDirName holds one smart folder per saved search.
+	savedSearchesDirName = "Saved Searches"
 )
 
 type sortMode int
@@ -32,6 +37,8 @@ const (
 	sortByName sortMode = iota
 	sortByTitle
 	sortByYear
+	sortByRating
+	sortByPriority
 )
 
 type uiState int
@@ -60,6 +67,8 @@ const (
 	quickFilterUnread
 	quickFilterReading
 	quickFilterRead
+	quickFilterTopRated
+	quickFilterPriority
 )
 
 func (q quickFilterMode) label() string {
@@ -76,6 +85,10 @@ func (q quickFilterMode) label() string {
 		return "Reading"
 	case quickFilterRead:
 		return "Read"
+	case quickFilterTopRated:
+		return "Top rated"
+	case quickFilterPriority:
+		return "High priority"
 	default:
 		return ""
 	}
@@ -95,6 +108,10 @@ func (q quickFilterMode) labelLower() string {
 		return "reading"
 	case quickFilterRead:
 		return "read"
+	case quickFilterTopRated:
+		return "top rated"
+	case quickFilterPriority:
+		return "high priority"
 	default:
 		return ""
 	}
@@ -144,6 +161,8 @@ type Model struct {
 	favoritesDirCanonical      string
 	toReadDir                  string
 	toReadDirCanonical         string
+	collectionsDir             string
+	savedSearchesDir           string
 
 	viewportStart  int
 	viewportHeight int
@@ -161,6 +180,9 @@ type Model struct {
 	metaEditingPath string        // path of file being edited
 	metaFieldIndex  int           // 0:title,1:author,2:year,...
 	metaDraft       meta.Metadata // draft being edited
+	metaReading     meta.ReadingSummary
+	metaCitations   citationPanels
+	metaAttachments []meta.Attachment
 
 	previewText []string
 	previewPath string
@@ -180,6 +202,36 @@ type Model struct {
 	searchSummary      string
 	lastSearchQuery    string
 	lastSearchMode     searchMode
+	searchOrder        resultOrder
+	duplicatesView     bool
+	pendingMerge       *duplicateMerge
+
+	// searchCancel stops the running search job; nil when none runs.
+	// searchStreaming is set once its partial results fill the results view.
+	searchCancel    context.CancelFunc
+	searchJob       int
+	runningSearch   searchRequest
+	searchStreaming bool
+	searchScanned   int
+	searchTotal     int
+
+	// savedSearches are listed in the tree panel; savedSearchSync keeps two
+	// smart folder refreshes from running at once.
+	savedSearches   []meta.SavedSearch
+	savedSearchSync *sync.Mutex
+	// savedSearchesStale is set when the library changed since the smart
+	// folders were last refreshed at savedSearchesSyncedAt.
+	savedSearchesStale    bool
+	savedSearchesSyncedAt time.Time
+
+	// pendingIdentities are documents listed since the last fingerprinting
+	// pass; identitySync keeps two passes from running at once.
+	pendingIdentities []string
+	identitySync      *sync.Mutex
+
+	// storeGeneration is the store's change counter when the caches were
+	// last known to be fresh.
+	storeGeneration int64
 
 	pendingArxivFiles  []string
 	pendingArxivActive string
@@ -311,6 +363,8 @@ func (m *Model) searchSkipDirs() []string {
 	add(m.recentlyOpenedDir)
 	add(m.favoritesDir)
 	add(m.toReadDir)
+	add(m.collectionsDir)
+	add(m.savedSearchesDir)
 	return dirs
 }
 
@@ -386,7 +440,11 @@ func (m Model) panelWidths() (int, int, int) {
 }
 
 func (m *Model) noteFilePath(path string) (string, error) {
-	dir := strings.TrimSpace(m.notesDir)
+	return noteFilePathIn(m.notesDir, path)
+}
+
+func noteFilePathIn(dir, path string) (string, error) {
+	dir = strings.TrimSpace(dir)
 	if dir == "" {
 		return "", fmt.Errorf("notes directory not configured")
 	}
@@ -476,6 +534,8 @@ func NewModel(cfg *config.Config, store *meta.Store) Model {
 	}
 	favoritesDir := canonicalPath(filepath.Join(root, favoritesDirName))
 	toReadDir := canonicalPath(filepath.Join(root, toReadDirName))
+	collectionsDir := canonicalPath(filepath.Join(root, collectionsDirName))
+	savedSearchesDir := canonicalPath(filepath.Join(root, savedSearchesDirName))
 
 	m := Model{
 		cfg:                   cfg,
@@ -497,46 +557,33 @@ func NewModel(cfg *config.Config, store *meta.Store) Model {
 		favoritesDirCanonical: favoritesDir,
 		toReadDir:             toReadDir,
 		toReadDirCanonical:    toReadDir,
-		notesDir:              strings.TrimSpace(cfg.NotesDir),
+		collectionsDir:        collectionsDir,
+		savedSearchesDir:      savedSearchesDir,
+		savedSearchSync:       new(sync.Mutex),
+		identitySync:          new(sync.Mutex),
 	}
 
 	m.applyTheme(th)
 	if m.recentlyAddedSyncInt <= 0 {
 		m.recentlyAddedSyncInt = defaultRecentlyAddedSyncInterval
 	}
-	if m.recentlyAddedDir != "" && !filepath.IsAbs(m.recentlyAddedDir) {
-		m.recentlyAddedDir = filepath.Join(root, m.recentlyAddedDir)
-	}
-	if m.recentlyAddedDir != "" {
-		m.recentlyAddedDir = canonicalPath(m.recentlyAddedDir)
-		m.recentlyAddedDirCanonical = m.recentlyAddedDir
-	}
-	if m.recentlyOpenedDir != "" && !filepath.IsAbs(m.recentlyOpenedDir) {
-		m.recentlyOpenedDir = filepath.Join(root, m.recentlyOpenedDir)
-	}
-	if m.recentlyOpenedDir != "" {
-		m.recentlyOpenedDir = canonicalPath(m.recentlyOpenedDir)
-		m.recentlyOpenedDirCanonical = m.recentlyOpenedDir
-	}
+	m.recentlyAddedDir = resolveHelperDir(root, m.recentlyAddedDir)
+	m.recentlyAddedDirCanonical = m.recentlyAddedDir
+	m.recentlyOpenedDir = resolveHelperDir(root, m.recentlyOpenedDir)
+	m.recentlyOpenedDirCanonical = m.recentlyOpenedDir
 	if err := m.maybeSyncRecentlyAddedDir(true); err != nil {
 		m.setStatus("Recently added sync failed: " + err.Error())
 	}
-	if m.notesDir == "" && strings.TrimSpace(cfg.MetaDir) != "" {
-		m.notesDir = filepath.Join(cfg.MetaDir, "notes")
-	}
-	if m.notesDir != "" && !filepath.IsAbs(m.notesDir) {
-		base := strings.TrimSpace(cfg.MetaDir)
-		if base != "" {
-			m.notesDir = filepath.Join(base, m.notesDir)
-		} else if abs, err := filepath.Abs(m.notesDir); err == nil {
-			m.notesDir = abs
-		}
+	m.notesDir = resolveNotesDir(cfg)
+	if m.meta != nil {
+		m.storeGeneration, _ = m.meta.Generation(context.Background())
 	}
 	m.loadEntries()
 	m.updateTextPreview()
 	if err := m.syncCollectionDirectories(); err != nil {
 		m.setStatus("Favorite/To-read sync failed: " + err.Error())
 	}
+	m.loadSavedSearches()
 	if m.recentlyOpenedDir != "" && m.meta != nil && m.recentlyOpenedLimit > 0 {
 		if err := rebuildRecentlyOpenedDirectory(m.recentlyOpenedDir, m.recentlyOpenedLimit, m.meta); err != nil {
 			m.setStatus("Recently read sync failed: " + err.Error())
@@ -559,6 +606,36 @@ func NewModel(cfg *config.Config, store *meta.Store) Model {
 	return m
 }
 
+// resolveHelperDir makes a configured helper directory absolute under root.
+func resolveHelperDir(root, dir string) string {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return ""
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return canonicalPath(dir)
+}
+
+// resolveNotesDir returns the notes directory of cfg, defaulting to the
+// notes folder of meta_dir and resolving relative paths against meta_dir.
+func resolveNotesDir(cfg *config.Config) string {
+	notesDir := strings.TrimSpace(cfg.NotesDir)
+	if notesDir == "" && strings.TrimSpace(cfg.MetaDir) != "" {
+		notesDir = filepath.Join(cfg.MetaDir, "notes")
+	}
+	if notesDir != "" && !filepath.IsAbs(notesDir) {
+		base := strings.TrimSpace(cfg.MetaDir)
+		if base != "" {
+			notesDir = filepath.Join(base, notesDir)
+		} else if abs, err := filepath.Abs(notesDir); err == nil {
+			notesDir = abs
+		}
+	}
+	return notesDir
+}
+
 func (m *Model) applyTheme(th theme.Theme) {
 	if m == nil {
 		return
@@ -577,6 +654,8 @@ func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		textinput.Blink,
 		scheduleAutoMetadataScan(autoMetadataInitialScanDelay),
+		watchStoreCmd(m.meta),
+		m.syncSavedSearchesCmd(),
 	)
 }
 
@@ -646,6 +725,10 @@ func (m Model) specialDirPriority(path string) int {
 		return 2
 	case m.toReadDirCanonical:
 		return 3
+	case m.collectionsDir:
+		return 4
+	case m.savedSearchesDir:
+		return 5
 	default:
 		return 100
 	}
@@ -679,6 +762,10 @@ func sortModeLabel(mode sortMode) string {
 		return "title"
 	case sortByYear:
 		return "year"
+	case sortByRating:
+		return "rating"
+	case sortByPriority:
+		return "priority"
 	default:
 		return "name"
 	}
@@ -836,9 +923,13 @@ func (m *Model) startNextArxivPrompt() string {
 }
 
 func (m *Model) enterSearchResults(msg searchResultMsg) {
+	m.cancelSearch()
 	m.clearSearchResults()
 	m.state = stateSearchResults
 	m.searchResults = append([]searchMatch{}, msg.matches...)
+	for i := range m.searchResults {
+		m.searchResults[i].rank = i
+	}
 	m.searchWarnings = append([]string{}, msg.warnings...)
 	m.searchSummary = msg.summary
 	m.lastSearchQuery = msg.req.query
@@ -849,6 +940,7 @@ func (m *Model) enterSearchResults(msg searchResultMsg) {
 }
 
 func (m *Model) exitSearchResults() {
+	m.cancelSearch()
 	m.state = stateNormal
 	m.clearSearchResults()
 }
@@ -859,9 +951,12 @@ func (m *Model) clearSearchResults() {
 	m.searchSummary = ""
 	m.lastSearchQuery = ""
 	m.lastSearchMode = searchModeContent
+	m.searchOrder = resultOrderRelevance
 	m.searchResultCursor = 0
 	m.searchResultOffset = 0
 	m.quickFilter = quickFilterNone
+	m.duplicatesView = false
+	m.pendingMerge = nil
 }
 
 func (m *Model) currentSearchMatch() *searchMatch {
@@ -1274,7 +1369,7 @@ func (m *Model) directoryEntryLine(ctx context.Context, dir string, entry os.Dir
 		if icon == "" {
 			icon = "DIR"
 		}
-		return formatEntryColumns(icon, "", "", "-", name+"/")
+		return formatEntryColumns(icon, "", "", "", "", "-", name+"/")
 	}
 	state := m.readingStateIcon("")
 	year := "-"
@@ -1284,6 +1379,8 @@ func (m *Model) directoryEntryLine(ctx context.Context, dir string, entry os.Dir
 	}
 	favIcon := ""
 	toReadIcon := ""
+	rating := ""
+	priority := ""
 	if ctx != nil && m.meta != nil {
 		canonical := canonicalPath(full)
 		md, err := m.meta.Get(ctx, canonical)
@@ -1301,7 +1398,9 @@ func (m *Model) directoryEntryLine(ctx context.Context, dir string, entry os.Dir
 			if md.ToRead {
 				toReadIcon = m.toReadIcon()
 			}
+			rating = ratingBadge(md.Rating)
+			priority = priorityBadge(md.Priority)
 		}
 	}
-	return formatEntryColumns(state, favIcon, toReadIcon, year, title)
+	return formatEntryColumns(state, favIcon, toReadIcon, rating, priority, year, title)
 }
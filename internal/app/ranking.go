@@ -0,0 +1,230 @@
+package app
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// resultOrder is how the results view orders its rows; o cycles through them.
+type resultOrder int
+
+const (
+	resultOrderRelevance resultOrder = iota
+	resultOrderYear
+	resultOrderTitle
+)
+
+func (o resultOrder) label() string {
+	switch o {
+	case resultOrderYear:
+		return "year"
+	case resultOrderTitle:
+		return "title"
+	}
+	return "relevance"
+}
+
+func (o resultOrder) next() resultOrder {
+	return (o + 1) % 3
+}
+
+// BM25 parameters for content scores.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// metadataFieldWeights rank a hit by the field it lands in: a title hit is
+// worth three abstract hits.
+var metadataFieldWeights = []struct {
+	field  string
+	weight float64
+}{
+	{"title", 3},
+	{"author", 2},
+	{"tag", 1.5},
+	{"abstract", 1},
+}
+
+// rankMatches scores every match and sorts them best first. Content hits
+// are scored with BM25 over the corpus files that were searched; query words
+// found in the metadata add field-weighted points on top.
+func rankMatches(req searchRequest, matches []searchMatch, corpus int) {
+	if len(matches) == 0 {
+		return
+	}
+	matchers := rankingMatchers(req)
+
+	df := make(map[string]int)
+	totalLen, docs := 0, 0
+	for _, match := range matches {
+		for term, tf := range match.termFreq {
+			if tf > 0 {
+				df[term]++
+			}
+		}
+		if match.docLen > 0 {
+			totalLen += match.docLen
+			docs++
+		}
+	}
+	if corpus < len(matches) {
+		corpus = len(matches)
+	}
+	// Unmatched files are never read, so the average length is taken over
+	// the matches.
+	avgLen := 1.0
+	if docs > 0 {
+		avgLen = float64(totalLen) / float64(docs)
+	}
+
+	for i := range matches {
+		match := &matches[i]
+		score := 0.0
+		for term, tf := range match.termFreq {
+			if tf == 0 {
+				continue
+			}
+			n := float64(df[term])
+			idf := math.Log(1 + (float64(corpus)-n+0.5)/(n+0.5))
+			norm := 1 - bm25B + bm25B*float64(match.docLen)/avgLen
+			score += idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+		}
+		score += metadataScore(metadataFieldsForRanking(req, match), matchers)
+		match.Score = score
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return lessByTitle(matches[i], matches[j])
+	})
+}
+
+// rankingMatchers match the words whose presence in the metadata counts
+// towards the score: the query itself, or the positive terms of a boolean
+// query.
+func rankingMatchers(req searchRequest) []textMatcher {
+	if req.expr != nil {
+		var matchers []textMatcher
+		for _, t := range positiveQueryTerms(req.expr) {
+			if t.isText() {
+				matchers = append(matchers, textMatcher{query: t.value, caseSensitive: req.caseSensitive, fuzzy: req.fuzzy()})
+			}
+		}
+		return matchers
+	}
+	if req.mode == searchModeYear || strings.TrimSpace(req.query) == "" {
+		return nil
+	}
+	tm := req.matcher()
+	tm.query = strings.TrimSpace(tm.query)
+	return []textMatcher{tm}
+}
+
+// metadataFieldsForRanking returns the stored title, author, tag and
+// abstract of match, falling back to what the match itself carries.
+func metadataFieldsForRanking(req searchRequest, match *searchMatch) map[string]string {
+	fields := map[string]string{
+		"title":  match.Meta.Title,
+		"author": match.Meta.Author,
+		"tag":    match.Meta.Tag,
+	}
+	if fields["title"] == "" {
+		fields["title"] = match.Title
+	}
+	if req.metaStore == nil {
+		return fields
+	}
+	stored, err := req.metaStore.Get(context.Background(), canonicalPath(match.Path))
+	if err != nil || stored == nil {
+		return fields
+	}
+	for name, value := range map[string]string{
+		"title": stored.Title, "author": stored.Author, "tag": stored.Tag, "abstract": stored.Abstract,
+	} {
+		if strings.TrimSpace(value) != "" {
+			fields[name] = value
+		}
+	}
+	return fields
+}
+
+// metadataScore adds up the weighted hits of matchers in fields. A fuzzy
+// matcher's title or author that only matches with typos earns half the
+// weight of an exact hit.
+func metadataScore(fields map[string]string, matchers []textMatcher) float64 {
+	score := 0.0
+	for _, tm := range matchers {
+		for _, fw := range metadataFieldWeights {
+			if n := len(tm.exact().find(fields[fw.field])); n > 0 {
+				score += fw.weight * (1 + math.Log(float64(n)))
+				continue
+			}
+			if tm.fuzzy && (fw.field == "title" || fw.field == "author") && tm.match(fields[fw.field]) {
+				score += fw.weight / 2
+			}
+		}
+	}
+	return score
+}
+
+// sortSearchResults puts the results view in order o. Relevance restores
+// the order the results arrived in.
+func sortSearchResults(matches []searchMatch, o resultOrder) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch o {
+		case resultOrderYear:
+			ya, errA := strconv.Atoi(strings.TrimSpace(a.Year))
+			yb, errB := strconv.Atoi(strings.TrimSpace(b.Year))
+			switch {
+			case errA != nil && errB != nil:
+			case errA != nil:
+				return false
+			case errB != nil:
+				return true
+			case ya != yb:
+				return ya > yb
+			}
+			return lessByTitle(a, b)
+		case resultOrderTitle:
+			return lessByTitle(a, b)
+		}
+		return a.rank < b.rank
+	})
+}
+
+func lessByTitle(a, b searchMatch) bool {
+	ta, tb := strings.ToLower(a.Title), strings.ToLower(b.Title)
+	if ta != tb {
+		return ta < tb
+	}
+	return a.Path < b.Path
+}
+
+// cycleSearchOrder switches the results view to the next ordering, keeping
+// the cursor on the same result.
+func (m *Model) cycleSearchOrder() {
+	if m.duplicatesView {
+		m.setStatus("Duplicate groups keep their order")
+		return
+	}
+	var current string
+	if match := m.currentSearchMatch(); match != nil {
+		current = match.Path
+	}
+	m.searchOrder = m.searchOrder.next()
+	sortSearchResults(m.searchResults, m.searchOrder)
+	for i, match := range m.searchResults {
+		if match.Path == current {
+			m.searchResultCursor = i
+			break
+		}
+	}
+	m.ensureSearchResultVisible()
+	m.setStatus("Results ordered by " + m.searchOrder.label())
+}
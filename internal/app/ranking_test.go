@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestRankMatchesPrefersTitleAndDenseContent(t *testing.T) {
+	dir := canonicalPath(t.TempDir())
+	libDir := filepath.Join(dir, "library")
+	if err := os.MkdirAll(libDir, 0o755); err != nil {
+		t.Fatalf("mkdir library: %v", err)
+	}
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	papers := []struct {
+		name, body string
+		md         meta.Metadata
+	}{
+		{"abstract.epub", "Attention helps.", meta.Metadata{Title: "Memory Networks", Abstract: "We study attention."}},
+		{"title.epub", "Attention helps.", meta.Metadata{Title: "Attention Is All You Need"}},
+		{"dense.epub", "Attention, attention and more attention.", meta.Metadata{Title: "Other"}},
+		{"sparse.epub", "Attention once among many other words in a long text.", meta.Metadata{Title: "Another"}},
+	}
+	for _, p := range papers {
+		path := filepath.Join(libDir, p.name)
+		writeTestEPUB(t, path, p.body)
+		md := p.md
+		md.Path = path
+		if err := store.Upsert(ctx, &md); err != nil {
+			t.Fatalf("upsert %s: %v", p.name, err)
+		}
+	}
+
+	agg, _, err := performSearch(searchRequest{root: libDir, mode: searchModeContent, query: "attention", metaStore: store})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	var order []string
+	for _, match := range agg.matches {
+		if match.Score <= 0 {
+			t.Fatalf("expected a positive score for %s", match.Path)
+		}
+		order = append(order, filepath.Base(match.Path))
+	}
+	want := []string{"title.epub", "abstract.epub", "dense.epub", "sparse.epub"}
+	if len(order) != len(want) {
+		t.Fatalf("unexpected matches %v", order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestSortSearchResults(t *testing.T) {
+	matches := []searchMatch{
+		{Path: "/b", Title: "Beta", Year: "2019", rank: 0},
+		{Path: "/a", Title: "alpha", Year: "", rank: 1},
+		{Path: "/c", Title: "Gamma", Year: "2023", rank: 2},
+	}
+	check := func(o resultOrder, want ...string) {
+		t.Helper()
+		sortSearchResults(matches, o)
+		for i, path := range want {
+			if matches[i].Path != path {
+				t.Fatalf("%s order: got %v at %d, want %v", o.label(), matches[i].Path, i, want)
+			}
+		}
+	}
+	check(resultOrderYear, "/c", "/b", "/a")
+	check(resultOrderTitle, "/a", "/b", "/c")
+	check(resultOrderRelevance, "/b", "/a", "/c")
+}
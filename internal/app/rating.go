@@ -0,0 +1,175 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorae/internal/meta"
+)
+
+// topRatedMin is the rating the "g s" quick filter starts from.
+const topRatedMin = 4
+
+// ratingBadge is the list column for a star rating: the number of stars, or
+// blank when unrated.
+func ratingBadge(rating int) string {
+	if rating <= 0 {
+		return ""
+	}
+	return strconv.Itoa(min(rating, meta.MaxRating))
+}
+
+// priorityBadge is the list column for a priority. Normal papers stay blank
+// so that only the exceptions stand out.
+func priorityBadge(priority string) string {
+	switch priority, _ := meta.NormalizePriority(priority); priority {
+	case meta.PriorityLow:
+		return "↓"
+	case meta.PriorityHigh:
+		return "!"
+	case meta.PriorityUrgent:
+		return "‼"
+	}
+	return ""
+}
+
+func formatRating(rating int) string {
+	if rating <= 0 {
+		return "(unrated)"
+	}
+	rating = min(rating, meta.MaxRating)
+	return strings.Repeat("★", rating) + strings.Repeat("☆", meta.MaxRating-rating)
+}
+
+func priorityLabel(priority string) string {
+	priority, _ = meta.NormalizePriority(priority)
+	return strings.ToUpper(priority[:1]) + priority[1:]
+}
+
+// nextPriority cycles low → normal → high → urgent → low.
+func nextPriority(priority string) string {
+	rank := meta.PriorityRank(priority)
+	return meta.Priorities[(rank+1)%len(meta.Priorities)]
+}
+
+// setRating gives the selection (or the current file) rating stars; 0 clears
+// the rating.
+func (m *Model) setRating(rating int) {
+	m.updateMetadataRecords(func(md *meta.Metadata) {
+		md.Rating = rating
+	})
+	if rating == 0 {
+		m.setStatus("Rating cleared")
+	} else {
+		m.setStatus("Rating: " + formatRating(rating))
+	}
+}
+
+// cyclePriority moves the current file to the next priority and gives the
+// rest of the selection the same one.
+func (m *Model) cyclePriority() {
+	var priority string
+	m.updateMetadataRecords(func(md *meta.Metadata) {
+		if priority == "" {
+			priority = nextPriority(md.Priority)
+		}
+		md.Priority = priority
+	})
+	if priority != "" {
+		m.setStatus("Priority: " + priorityLabel(priority))
+	}
+}
+
+// updateMetadataRecords applies update to the selection or the current file
+// and refreshes the listing.
+func (m *Model) updateMetadataRecords(update func(md *meta.Metadata)) {
+	if m.meta == nil {
+		m.setStatus("Metadata store not available")
+		return
+	}
+	paths := m.canonicalFilePaths(m.selectionOrCurrent())
+	if len(paths) == 0 {
+		m.setStatus("No files selected")
+		return
+	}
+	ctx := context.Background()
+	refreshPreview := false
+	for _, path := range paths {
+		md, err := m.loadMetadataRecord(ctx, path)
+		if err != nil {
+			m.setStatus("Failed to load metadata: " + err.Error())
+			return
+		}
+		update(&md)
+		if err := m.meta.Upsert(ctx, &md); err != nil {
+			m.setStatus("Failed to save metadata: " + err.Error())
+			return
+		}
+		m.refreshMetadataCache(path, md)
+		if path == canonicalPath(m.currentEntryPath()) {
+			refreshPreview = true
+		}
+	}
+	if refreshPreview {
+		m.updateTextPreview()
+	}
+	m.resortAndPreserveSelection()
+}
+
+// parseRatingFilter parses the value of --rating: a minimum number of stars,
+// optionally written as "4+" or ">=4".
+func parseRatingFilter(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(value, ">=")
+	value = strings.TrimSuffix(value, "+")
+	rating, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || rating < 1 || rating > meta.MaxRating {
+		return 0, fmt.Errorf("Invalid rating %q (use 1-%d)", value, meta.MaxRating)
+	}
+	return rating, nil
+}
+
+// parsePriorityFilter parses the value of --priority: one or more priorities
+// separated by commas.
+func parsePriorityFilter(value string) ([]string, error) {
+	var priorities []string
+	for _, part := range strings.Split(value, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		priority, ok := meta.NormalizePriority(part)
+		if !ok {
+			return nil, fmt.Errorf("Unknown priority %q (use %s)", strings.TrimSpace(part), strings.Join(meta.Priorities, ", "))
+		}
+		priorities = append(priorities, priority)
+	}
+	if len(priorities) == 0 {
+		return nil, fmt.Errorf("Missing value for --priority")
+	}
+	return priorities, nil
+}
+
+// filterByRatingPriority keeps the files that satisfy the --rating,
+// --priority and --id filters of req.
+func filterByRatingPriority(req searchRequest, files []string) ([]string, error) {
+	if req.ratingMin == 0 && len(req.priorities) == 0 && len(req.identifiers) == 0 {
+		return files, nil
+	}
+	list, err := req.metaStore.Query(context.Background(), req.metadataFilter())
+	if err != nil {
+		return nil, fmt.Errorf("query metadata: %w", err)
+	}
+	allowed := make(map[string]bool, len(list))
+	for _, md := range list {
+		allowed[md.Path] = true
+	}
+	kept := make([]string, 0, len(files))
+	for _, path := range files {
+		if allowed[canonicalPath(path)] {
+			kept = append(kept, path)
+		}
+	}
+	return kept, nil
+}
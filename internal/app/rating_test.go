@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestParseRatingAndPriorityFilters(t *testing.T) {
+	for _, raw := range []string{"4", "4+", ">=4", " 4 "} {
+		if got, err := parseRatingFilter(raw); err != nil || got != 4 {
+			t.Fatalf("parseRatingFilter(%q) = %d, %v; want 4", raw, got, err)
+		}
+	}
+	for _, raw := range []string{"", "0", "6", "four"} {
+		if _, err := parseRatingFilter(raw); err == nil {
+			t.Fatalf("expected error for rating %q", raw)
+		}
+	}
+	got, err := parsePriorityFilter("High, urgent")
+	if err != nil || len(got) != 2 || got[0] != meta.PriorityHigh || got[1] != meta.PriorityUrgent {
+		t.Fatalf("parsePriorityFilter = %v, %v", got, err)
+	}
+	if _, err := parsePriorityFilter("someday"); err == nil {
+		t.Fatalf("expected error for unknown priority")
+	}
+}
+
+func TestNextPriorityCycles(t *testing.T) {
+	want := map[string]string{"": "high", "low": "normal", "normal": "high", "high": "urgent", "urgent": "low"}
+	for from, to := range want {
+		if got := nextPriority(from); got != to {
+			t.Fatalf("nextPriority(%q) = %q, want %q", from, got, to)
+		}
+	}
+}
+
+func TestSearchFiltersByRatingAndPriority(t *testing.T) {
+	root := canonicalPath(t.TempDir())
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	papers := []meta.Metadata{
+		{Path: filepath.Join(root, "great.pdf"), Title: "Great", Rating: 5, Priority: meta.PriorityHigh},
+		{Path: filepath.Join(root, "good.pdf"), Title: "Good", Rating: 4},
+		{Path: filepath.Join(root, "meh.pdf"), Title: "Meh", Rating: 2, Priority: meta.PriorityUrgent},
+	}
+	for i := range papers {
+		writeDummyPDF(t, papers[i].Path)
+		if err := store.Upsert(ctx, &papers[i]); err != nil {
+			t.Fatalf("upsert %s: %v", papers[i].Path, err)
+		}
+	}
+
+	cases := []struct {
+		name string
+		req  searchRequest
+		want []string
+	}{
+		{"rating only", searchRequest{ratingMin: 4}, []string{"good.pdf", "great.pdf"}},
+		{"priority only", searchRequest{priorities: []string{meta.PriorityUrgent}}, []string{"meh.pdf"}},
+		{"with title query", searchRequest{mode: searchModeTitle, query: "g", ratingMin: 4, priorities: []string{meta.PriorityHigh}}, []string{"great.pdf"}},
+	}
+	for _, tc := range cases {
+		tc.req.root = root
+		tc.req.metaStore = store
+		agg, _, err := performSearch(tc.req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got := make(map[string]bool, len(agg.matches))
+		for _, match := range agg.matches {
+			got[filepath.Base(match.Path)] = true
+		}
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+		for _, name := range tc.want {
+			if !got[name] {
+				t.Fatalf("%s: got %v, want %v", tc.name, got, tc.want)
+			}
+		}
+	}
+}
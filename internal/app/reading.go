@@ -0,0 +1,180 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"gorae/internal/meta"
+)
+
+// readingStatsLimit caps each section of :stats reading.
+const readingStatsLimit = 15
+
+// trackReadingSession records a reading session for path that lasts as long
+// as the viewer process. Viewers that hand the file to an already running
+// instance and exit right away yield sessions of a few seconds.
+func (m *Model) trackReadingSession(path string, cmd *exec.Cmd) {
+	if m.meta == nil {
+		return
+	}
+	canonical := canonicalPath(path)
+	if canonical == "" {
+		return
+	}
+	start := time.Now()
+	id, err := m.meta.StartSession(context.Background(), canonical, start)
+	if err != nil {
+		m.setStatus("Reading session failed: " + err.Error())
+		return
+	}
+	store := m.meta
+	go func() {
+		_ = cmd.Wait()
+		_ = store.FinishSession(context.Background(), id, time.Since(start))
+	}()
+}
+
+// handleProgressCommand implements :progress <page>, <page>/<pages> and
+// <percent>%. The new value replaces both the stored page and percentage.
+func (m *Model) handleProgressCommand(args []string) tea.Cmd {
+	if m.meta == nil {
+		m.setStatus("Metadata store not available")
+		return nil
+	}
+	target := canonicalPath(m.currentEntryPath())
+	if target == "" {
+		m.setStatus("No file selected")
+		return nil
+	}
+	if len(args) != 1 {
+		m.setStatus("Usage: :progress <page> | <page>/<pages> | <percent>%")
+		return nil
+	}
+	page, percent, err := parseProgress(args[0])
+	if err != nil {
+		m.setStatus(err.Error())
+		return nil
+	}
+	if err := m.meta.SetProgress(context.Background(), target, page, percent); err != nil {
+		m.setStatus("Failed to save progress: " + err.Error())
+		return nil
+	}
+	m.reloadCurrentMetadata()
+	m.setStatus(fmt.Sprintf("Progress of %s: %s", filepath.Base(target), formatProgress(meta.Metadata{ProgressPage: page, ProgressPercent: percent})))
+	return nil
+}
+
+func parseProgress(raw string) (page, percent int, err error) {
+	raw = strings.TrimSpace(raw)
+	invalid := fmt.Errorf("Invalid progress %q (use 42, 42/300 or 30%%)", raw)
+	if value, ok := strings.CutSuffix(raw, "%"); ok {
+		percent, err = strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || percent < 0 || percent > 100 {
+			return 0, 0, invalid
+		}
+		return 0, percent, nil
+	}
+	pageText, pagesText, hasTotal := strings.Cut(raw, "/")
+	page, err = strconv.Atoi(strings.TrimSpace(pageText))
+	if err != nil || page < 0 {
+		return 0, 0, invalid
+	}
+	if hasTotal {
+		pages, err := strconv.Atoi(strings.TrimSpace(pagesText))
+		if err != nil || pages <= 0 || page > pages {
+			return 0, 0, invalid
+		}
+		percent = page * 100 / pages
+	}
+	return page, percent, nil
+}
+
+func formatProgress(md meta.Metadata) string {
+	switch {
+	case md.ProgressPage > 0 && md.ProgressPercent > 0:
+		return fmt.Sprintf("p. %d (%d%%)", md.ProgressPage, md.ProgressPercent)
+	case md.ProgressPage > 0:
+		return fmt.Sprintf("p. %d", md.ProgressPage)
+	case md.ProgressPercent > 0:
+		return fmt.Sprintf("%d%%", md.ProgressPercent)
+	}
+	return "(not set)"
+}
+
+// formatReadingDuration renders d as "1h05m", "12m" or "40s".
+func formatReadingDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%ds", int(d.Seconds()))
+}
+
+func (m *Model) handleStatsCommand(args []string) tea.Cmd {
+	if m.meta == nil {
+		m.setStatus("Metadata store not available")
+		return nil
+	}
+	if len(args) == 0 || !strings.EqualFold(args[0], "reading") {
+		m.setStatus("Usage: :stats reading [days]")
+		return nil
+	}
+	var since time.Time
+	if len(args) > 1 {
+		days, err := strconv.Atoi(args[1])
+		if err != nil || days <= 0 {
+			m.setStatus(fmt.Sprintf("Invalid number of days: %s", args[1]))
+			return nil
+		}
+		since = time.Now().AddDate(0, 0, -days)
+	}
+	stats, err := m.meta.ReadingStats(context.Background(), since, readingStatsLimit)
+	if err != nil {
+		m.setStatus("Failed to load reading stats: " + err.Error())
+		return nil
+	}
+	if len(stats.Papers) == 0 {
+		m.setStatus("No reading sessions recorded yet")
+		return nil
+	}
+	m.setCommandOutput(formatReadingStats(stats, since))
+	m.setStatus("Reading stats")
+	return nil
+}
+
+func formatReadingStats(stats meta.ReadingStats, since time.Time) []string {
+	title := "Reading time (all sessions)"
+	if !since.IsZero() {
+		title = fmt.Sprintf("Reading time since %s", since.Format("2006-01-02"))
+	}
+	lines := []string{title, ""}
+	section := func(name string, totals []meta.ReadingTotal, label func(meta.ReadingTotal) string) {
+		lines = append(lines, name+":")
+		if len(totals) == 0 {
+			lines = append(lines, "  (none)")
+		}
+		for _, t := range totals {
+			lines = append(lines, fmt.Sprintf("  %8s  %3d session(s)  %s", formatReadingDuration(t.Total), t.Sessions, label(t)))
+		}
+		lines = append(lines, "")
+	}
+	section("Papers", stats.Papers, func(t meta.ReadingTotal) string {
+		if t.Label != "" {
+			return t.Label
+		}
+		return filepath.Base(t.Key)
+	})
+	section("Tags", stats.Tags, func(t meta.ReadingTotal) string { return t.Label })
+	section("Weeks", stats.Weeks, func(t meta.ReadingTotal) string { return t.Key })
+	return lines
+}
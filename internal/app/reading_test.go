@@ -0,0 +1,41 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseProgress(t *testing.T) {
+	cases := []struct {
+		raw           string
+		page, percent int
+	}{
+		{"42", 42, 0},
+		{"42/300", 42, 14},
+		{"30%", 0, 30},
+		{" 100 % ", 0, 100},
+	}
+	for _, tc := range cases {
+		page, percent, err := parseProgress(tc.raw)
+		if err != nil || page != tc.page || percent != tc.percent {
+			t.Fatalf("parseProgress(%q) = %d, %d, %v; want %d, %d", tc.raw, page, percent, err, tc.page, tc.percent)
+		}
+	}
+	for _, raw := range []string{"", "abc", "-1", "301/300", "120%", "4/0"} {
+		if _, _, err := parseProgress(raw); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestFormatReadingDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		40 * time.Second:                "40s",
+		12*time.Minute + 10*time.Second: "12m",
+		65 * time.Minute:                "1h05m",
+	} {
+		if got := formatReadingDuration(d); got != want {
+			t.Fatalf("formatReadingDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -250,8 +250,9 @@ func ensureMetadataForRecentlyAdded(store *meta.Store, path string) {
 		return
 	}
 
-	data, err := detectMetadataForFile(path)
+	data, known, err := detectMetadataForFile(path)
 	if err != nil || data == nil {
+		_ = saveFileIdentifiers(ctx, store, canonical, known)
 		return
 	}
 
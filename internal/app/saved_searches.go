@@ -0,0 +1,225 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"gorae/internal/meta"
+)
+
+// savedSearchesSyncedMsg reports that the smart folders were refreshed.
+type savedSearchesSyncedMsg struct {
+	err error
+}
+
+// handleSavedSearchCommand implements :search save <name> <query>,
+// :search saved, :search run <name> and :search delete <name>.
+func (m *Model) handleSavedSearchCommand(args []string) tea.Cmd {
+	if m.meta == nil {
+		m.setStatus("Metadata store not available")
+		return nil
+	}
+	ctx := context.Background()
+	sub := strings.ToLower(args[0])
+	if sub == "saved" {
+		return m.listSavedSearches()
+	}
+	if len(args) < 2 {
+		m.setStatus(fmt.Sprintf("Usage: :search %s <name>", sub))
+		return nil
+	}
+	name := args[1]
+	switch sub {
+	case "save":
+		if len(args) < 3 {
+			m.setStatus("Usage: :search save <name> <query>")
+			return nil
+		}
+		tokens := args[2:]
+		if _, err := m.buildSearchRequestIn(m.root, tokens); err != nil {
+			m.setStatus(err.Error())
+			return nil
+		}
+		if err := m.meta.SaveSearch(ctx, name, joinCommandLine(tokens)); err != nil {
+			m.setStatus("Failed to save search: " + err.Error())
+			return nil
+		}
+		m.loadSavedSearches()
+		m.setStatus(fmt.Sprintf("Saved search %q (folder %s)", strings.TrimSpace(name), filepath.Join(filepath.Base(m.savedSearchesDir), strings.TrimSpace(name))))
+		return m.syncSavedSearchesCmd()
+	case "run":
+		saved, err := m.meta.GetSavedSearch(ctx, name)
+		if err != nil {
+			m.setStatus("Failed to load saved search: " + err.Error())
+			return nil
+		}
+		return m.runSavedSearch(saved)
+	case "delete":
+		if err := m.meta.DeleteSavedSearch(ctx, name); err != nil {
+			m.setStatus("Failed to delete saved search: " + err.Error())
+			return nil
+		}
+		m.loadSavedSearches()
+		m.setStatus(fmt.Sprintf("Deleted saved search %q", name))
+		return m.syncSavedSearchesCmd()
+	}
+	return nil
+}
+
+func (m *Model) listSavedSearches() tea.Cmd {
+	if len(m.savedSearches) == 0 {
+		m.setStatus("No saved searches yet (save one with :search save <name> <query>)")
+		return nil
+	}
+	lines := make([]string, 0, len(m.savedSearches)+1)
+	lines = append(lines, fmt.Sprintf("Saved searches (%d):", len(m.savedSearches)))
+	for i, saved := range m.savedSearches {
+		key := "  "
+		if i < 9 {
+			key = fmt.Sprintf("g%d", i+1)
+		}
+		lines = append(lines, fmt.Sprintf("  %s %-30s %s", key, saved.Name, saved.Query))
+	}
+	m.setCommandOutput(lines)
+	m.setStatus(fmt.Sprintf("Listed %d saved search(es)", len(m.savedSearches)))
+	return nil
+}
+
+// loadSavedSearches refreshes the saved searches shown in the tree panel.
+func (m *Model) loadSavedSearches() {
+	if m.meta == nil {
+		m.savedSearches = nil
+		return
+	}
+	list, err := m.meta.ListSavedSearches(context.Background())
+	if err != nil {
+		m.setStatus("Failed to load saved searches: " + err.Error())
+		return
+	}
+	m.savedSearches = list
+}
+
+// runSavedSearchAt runs the i-th saved search of the tree panel (g1 is 0).
+func (m *Model) runSavedSearchAt(i int) tea.Cmd {
+	if i < 0 || i >= len(m.savedSearches) {
+		m.setStatus(fmt.Sprintf("No saved search %d", i+1))
+		return nil
+	}
+	return m.runSavedSearch(m.savedSearches[i])
+}
+
+func (m *Model) runSavedSearch(saved meta.SavedSearch) tea.Cmd {
+	req, err := m.savedSearchRequest(saved)
+	if err != nil {
+		m.setStatus(fmt.Sprintf("Saved search %q: %v", saved.Name, err))
+		return nil
+	}
+	return m.runSearch(req)
+}
+
+// savedSearchRequest parses a saved query. Saved searches cover the whole
+// library, wherever the browser happens to be.
+func (m *Model) savedSearchRequest(saved meta.SavedSearch) (searchRequest, error) {
+	tokens, err := splitCommandLine(saved.Query)
+	if err != nil {
+		return searchRequest{}, err
+	}
+	return m.buildSearchRequestIn(m.root, tokens)
+}
+
+// syncSavedSearchesCmd runs every saved search in the background and
+// mirrors its results into a smart folder under Saved Searches/.
+func (m *Model) syncSavedSearchesCmd() tea.Cmd {
+	return m.savedSearchSyncCmd(false)
+}
+
+// resyncSavedSearchesCmd refreshes the smart folders after the library
+// changed. Searches of the document text alone cannot have changed and are
+// left alone.
+func (m *Model) resyncSavedSearchesCmd() tea.Cmd {
+	return m.savedSearchSyncCmd(true)
+}
+
+func (m *Model) savedSearchSyncCmd(storeOnly bool) tea.Cmd {
+	if m.meta == nil || m.savedSearchesDir == "" {
+		return nil
+	}
+	m.savedSearchesSyncedAt = time.Now()
+	requests := make(map[string]searchRequest, len(m.savedSearches))
+	keep := make(map[string]bool, len(m.savedSearches))
+	var broken []string
+	for _, saved := range m.savedSearches {
+		req, err := m.savedSearchRequest(saved)
+		if err != nil {
+			broken = append(broken, saved.Name)
+			continue
+		}
+		keep[saved.Name] = true
+		if storeOnly && !req.usesStore() {
+			continue
+		}
+		requests[saved.Name] = req
+	}
+	dir, mu := m.savedSearchesDir, m.savedSearchSync
+	return func() tea.Msg {
+		if mu != nil {
+			mu.Lock()
+			defer mu.Unlock()
+		}
+		err := syncSavedSearchDirectories(context.Background(), dir, requests, keep)
+		if err == nil && len(broken) > 0 {
+			err = fmt.Errorf("cannot parse %s", strings.Join(broken, ", "))
+		}
+		return savedSearchesSyncedMsg{err: err}
+	}
+}
+
+// syncSavedSearchDirectories mirrors the results of each search into its own
+// folder under dir and removes the folders of searches not in keep.
+func syncSavedSearchDirectories(ctx context.Context, dir string, requests map[string]searchRequest, keep map[string]bool) error {
+	if dir == "" {
+		return nil
+	}
+	if len(requests) == 0 {
+		if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+	}
+	for name, req := range requests {
+		fetch := func(ctx context.Context) ([]meta.Metadata, error) {
+			agg, _, err := performSearchContext(ctx, req, nil)
+			if err != nil {
+				return nil, err
+			}
+			records := make([]meta.Metadata, 0, len(agg.matches))
+			for _, match := range agg.matches {
+				records = append(records, meta.Metadata{Path: match.Path, Title: match.Title, Year: match.Year})
+			}
+			return records, nil
+		}
+		if err := syncMetadataLinkDirectory(ctx, filepath.Join(dir, name), fetch); err != nil {
+			return fmt.Errorf("saved search %q: %w", name, err)
+		}
+	}
+	return pruneLinkDirectories(dir, keep)
+}
+
+// handleSavedSearchesSynced reports a failed refresh and reloads the file
+// list when it shows a smart folder.
+func (m *Model) handleSavedSearchesSynced(msg savedSearchesSyncedMsg) {
+	if msg.err != nil {
+		m.setStatus("Saved search sync failed: " + msg.err.Error())
+		return
+	}
+	cwd := canonicalPath(m.cwd)
+	if m.savedSearchesDir != "" && (cwd == m.savedSearchesDir || strings.HasPrefix(cwd, m.savedSearchesDir+string(filepath.Separator))) {
+		m.loadEntries()
+	}
+}
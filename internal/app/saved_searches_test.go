@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorae/internal/meta"
+)
+
+func TestSavedSearchSmartFolders(t *testing.T) {
+	root := canonicalPath(t.TempDir())
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	nlp := filepath.Join(root, "nlp.epub")
+	vision := filepath.Join(root, "vision.epub")
+	writeTestEPUB(t, nlp, "Language.")
+	writeTestEPUB(t, vision, "Images.")
+	for path, tag := range map[string]string{nlp: "nlp", vision: "vision"} {
+		if err := store.Upsert(ctx, &meta.Metadata{Path: path, Title: filepath.Base(path), Tag: tag}); err != nil {
+			t.Fatalf("upsert %s: %v", path, err)
+		}
+	}
+
+	smart := filepath.Join(root, savedSearchesDirName)
+	m := &Model{root: root, cwd: filepath.Join(root, "elsewhere"), meta: store, savedSearchesDir: smart}
+	cmd := m.handleSavedSearchCommand([]string{"save", "my nlp", "--tag", "nlp"})
+	if cmd == nil {
+		t.Fatalf("expected a sync command, status %q", m.status)
+	}
+	if msg := cmd().(savedSearchesSyncedMsg); msg.err != nil {
+		t.Fatalf("sync: %v", msg.err)
+	}
+	folder := filepath.Join(smart, "my nlp")
+	if targets := listSymlinkTargets(t, folder); len(targets) != 1 || targets[0] != nlp {
+		t.Fatalf("unexpected smart folder links: %v", targets)
+	}
+	if len(m.savedSearches) != 1 || m.savedSearches[0].Query != "--tag nlp" {
+		t.Fatalf("unexpected saved searches %+v", m.savedSearches)
+	}
+
+	// The folder follows the library.
+	if err := store.Upsert(ctx, &meta.Metadata{Path: vision, Title: "vision.epub", Tag: "nlp, vision"}); err != nil {
+		t.Fatalf("retag: %v", err)
+	}
+	if msg := m.syncSavedSearchesCmd()().(savedSearchesSyncedMsg); msg.err != nil {
+		t.Fatalf("resync: %v", msg.err)
+	}
+	if targets := listSymlinkTargets(t, folder); len(targets) != 2 {
+		t.Fatalf("expected both papers after retagging, got %v", targets)
+	}
+
+	if handled, cmd := m.handleQuickFilterPrefix("1"); !handled || cmd == nil {
+		t.Fatalf("expected g1 to run the saved search")
+	}
+	m.cancelSearch()
+	if m.runningSearch.root != root || m.runningSearch.mode != searchModeTag {
+		t.Fatalf("expected the saved search to run over the library, got %+v", m.runningSearch)
+	}
+
+	cmd = m.handleSavedSearchCommand([]string{"delete", "my nlp"})
+	if msg := cmd().(savedSearchesSyncedMsg); msg.err != nil {
+		t.Fatalf("sync after delete: %v", msg.err)
+	}
+	if _, err := os.Stat(folder); !os.IsNotExist(err) {
+		t.Fatalf("expected the smart folder to be removed, got %v", err)
+	}
+
+	if cmd := m.handleSavedSearchCommand([]string{"save", "bad", "-r", "BERT("}); cmd != nil || len(m.savedSearches) != 0 {
+		t.Fatalf("expected an invalid query not to be saved")
+	}
+}
+
+func TestSavedSearchResyncAfterLibraryChange(t *testing.T) {
+	root := canonicalPath(t.TempDir())
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	paper := filepath.Join(root, "paper.epub")
+	writeTestEPUB(t, paper, "Transformers everywhere.")
+	smart := filepath.Join(root, savedSearchesDirName)
+	m := &Model{root: root, cwd: root, meta: store, savedSearchesDir: smart}
+	for _, line := range [][]string{{"save", "text", "transformers"}, {"save", "tagged", "--tag", "nlp"}} {
+		if cmd := m.handleSavedSearchCommand(line); cmd == nil {
+			t.Fatalf("save %q: %s", line, m.status)
+		}
+	}
+	if msg := m.syncSavedSearchesCmd()().(savedSearchesSyncedMsg); msg.err != nil {
+		t.Fatalf("sync: %v", msg.err)
+	}
+
+	// A change marks the folders stale; they are refreshed once the
+	// generation holds still and the resync interval has passed.
+	if err := store.Upsert(ctx, &meta.Metadata{Path: paper, Title: "Paper", Tag: "nlp"}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	gen, _ := store.Generation(ctx)
+	m.handleStoreGeneration(storeGenerationMsg{store: store, generation: gen})
+	if !m.savedSearchesStale {
+		t.Fatalf("expected the smart folders to be marked stale")
+	}
+	m.handleStoreGeneration(storeGenerationMsg{store: store, generation: gen})
+	if !m.savedSearchesStale {
+		t.Fatalf("expected no resync within the interval")
+	}
+	m.savedSearchesSyncedAt = time.Now().Add(-savedSearchResyncInterval)
+	m.handleStoreGeneration(storeGenerationMsg{store: store, generation: gen})
+	if m.savedSearchesStale {
+		t.Fatalf("expected a resync once the interval passed")
+	}
+
+	// The resync skips the text-only search but keeps its folder.
+	if msg := m.resyncSavedSearchesCmd()().(savedSearchesSyncedMsg); msg.err != nil {
+		t.Fatalf("resync: %v", msg.err)
+	}
+	if targets := listSymlinkTargets(t, filepath.Join(smart, "text")); len(targets) != 1 {
+		t.Fatalf("expected the text search folder to survive a resync, got %v", targets)
+	}
+	if targets := listSymlinkTargets(t, filepath.Join(smart, "tagged")); len(targets) != 1 || targets[0] != paper {
+		t.Fatalf("expected the tag search to pick up the retagged paper, got %v", targets)
+	}
+}
+
+func TestJoinCommandLine(t *testing.T) {
+	tokens := []string{"-r", `\bGPT-?[0-9]\b`, "title:deep learning", `it's "quoted"`, ""}
+	got, err := splitCommandLine(joinCommandLine(tokens))
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	// splitCommandLine drops empty tokens.
+	if want := tokens[:4]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip = %q, want %q", got, want)
+	}
+}
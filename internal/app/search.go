@@ -14,8 +14,6 @@ import (
 	"strings"
 	"sync"
 
-	tea "github.com/charmbracelet/bubbletea"
-
 	"gorae/internal/meta"
 )
 
@@ -37,9 +35,75 @@ type searchRequest struct {
 	wrapWidth     int
 	metaStore     *meta.Store
 	skipDirs      []string
+	fieldFilters  []fieldFilter
+	ratingMin     int
+	priorities    []string
+	identifiers   []meta.Identifier
+	// expr is set for queries written in the boolean query language; mode is
+	// then searchModeQuery.
+	expr queryNode
+	// exact turns off typo-tolerant matching of titles and authors.
+	exact bool
+	// pattern is the compiled query of a regex search (-r).
+	pattern *regexp.Regexp
+}
+
+// fuzzy reports whether title and author terms match fuzzily: the default,
+// unless the search is exact or case-sensitive.
+func (req searchRequest) fuzzy() bool {
+	if req.exact || req.caseSensitive || req.pattern != nil {
+		return false
+	}
+	switch req.mode {
+	case searchModeTitle, searchModeAuthor, searchModeQuery:
+		return true
+	}
+	return false
+}
+
+// matcher matches the query against the field req.mode searches.
+func (req searchRequest) matcher() textMatcher {
+	return textMatcher{query: req.query, caseSensitive: req.caseSensitive, fuzzy: req.fuzzy(), re: req.pattern}
+}
+
+// hasFilters reports whether the request filters on metadata, in which case
+// the query may be empty.
+func (req searchRequest) hasFilters() bool {
+	return len(req.fieldFilters) > 0 || req.ratingMin > 0 || len(req.priorities) > 0 || len(req.identifiers) > 0
+}
+
+// usesStore reports whether the results of req depend on the metadata
+// store, rather than on the document text alone.
+func (req searchRequest) usesStore() bool {
+	return req.expr != nil || req.mode != searchModeContent || req.hasFilters()
+}
+
+// metadataFilter is the part of the request the metadata store can answer.
+func (req searchRequest) metadataFilter() meta.Filter {
+	return meta.Filter{PathPrefix: req.root, RatingMin: req.ratingMin, Priorities: req.priorities, Identifiers: req.identifiers}
+}
+
+func (req searchRequest) filterLabels() []string {
+	labels := make([]string, 0, len(req.fieldFilters)+2)
+	if req.ratingMin > 0 {
+		labels = append(labels, fmt.Sprintf("rating>=%d", req.ratingMin))
+	}
+	if len(req.priorities) > 0 {
+		labels = append(labels, "priority="+strings.Join(req.priorities, "|"))
+	}
+	for _, id := range req.identifiers {
+		labels = append(labels, "id="+id.String())
+	}
+	for _, f := range req.fieldFilters {
+		labels = append(labels, f.String())
+	}
+	return labels
 }
 
 type searchResultMsg struct {
+	// job is the search job that produced the results; zero for views that
+	// fill the results list directly.
+	job          int
 	req          searchRequest
 	matches      []searchMatch
 	warnings     []string
@@ -57,6 +121,17 @@ type searchMatch struct {
 	Meta       pdfMeta
 	Title      string
 	Year       string
+	// Group numbers the duplicate group a :duplicates result belongs to.
+	Group int
+	// Excerpts holds the content snippets of a query match; its metadata
+	// lines are in Snippets.
+	Excerpts []string
+	// Score is the relevance a search gave the match; higher is better.
+	Score float64
+
+	termFreq map[string]int // content hits per query word, for BM25
+	docLen   int            // words in the document text
+	rank     int            // position in relevance order
 }
 
 type searchAggregate struct {
@@ -64,6 +139,8 @@ type searchAggregate struct {
 	warnings     []string
 	filesMatched int
 	totalMatches int
+	// filesScanned counts the files the query was checked against.
+	filesScanned int
 }
 
 const maxSnippetsPerFile = 6
@@ -83,23 +160,28 @@ func (m searchMode) displayName() string {
 	return strings.ToUpper(label[:1]) + label[1:]
 }
 
-func newSearchCmd(req searchRequest) tea.Cmd {
-	return func() tea.Msg {
-		agg, summary, err := performSearch(req)
-		return searchResultMsg{
-			req:          req,
-			matches:      agg.matches,
-			warnings:     agg.warnings,
-			filesMatched: agg.filesMatched,
-			totalMatches: agg.totalMatches,
-			summary:      summary,
-			err:          err,
-		}
+// performSearch runs req and returns its matches best first.
+func performSearch(req searchRequest) (searchAggregate, string, error) {
+	return performSearchContext(context.Background(), req, nil)
+}
+
+// performSearchContext is performSearch that stops when ctx is cancelled and
+// passes progress and unranked matches to report while files are checked.
+func performSearchContext(ctx context.Context, req searchRequest, report func(searchProgress)) (searchAggregate, string, error) {
+	scan := newSearchScan(ctx, report)
+	agg, summary, err := collectSearchMatches(scan, req)
+	if err == nil && scan.stopped() {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return agg, summary, err
 	}
+	rankMatches(req, agg.matches, agg.filesScanned)
+	return agg, summary, nil
 }
 
-func performSearch(req searchRequest) (searchAggregate, string, error) {
-	if strings.TrimSpace(req.query) == "" {
+func collectSearchMatches(scan *searchScan, req searchRequest) (searchAggregate, string, error) {
+	if strings.TrimSpace(req.query) == "" && !req.hasFilters() {
 		return searchAggregate{}, "", fmt.Errorf("empty query")
 	}
 
@@ -116,6 +198,15 @@ func performSearch(req searchRequest) (searchAggregate, string, error) {
 		wrapWidth = 80
 	}
 
+	if req.metaStore != nil {
+		switch {
+		case strings.TrimSpace(req.query) == "":
+			return searchStoredFields(req)
+		case req.expr == nil && req.mode == searchModeTag:
+			return searchStoredTags(req)
+		}
+	}
+
 	files, walkWarnings, err := collectDocumentFiles(req.root, req.skipDirs)
 	if err != nil {
 		return searchAggregate{}, "", err
@@ -134,10 +225,33 @@ func performSearch(req searchRequest) (searchAggregate, string, error) {
 		return agg, summary, nil
 	}
 
-	workerCount := runtime.NumCPU()
-	if workerCount < 2 {
-		workerCount = 2
+	if req.hasFilters() {
+		if req.metaStore == nil {
+			return searchAggregate{}, "", fmt.Errorf("field filters need the metadata store")
+		}
+		if files, err = filterByRatingPriority(req, files); err != nil {
+			return searchAggregate{}, "", err
+		}
+		if files, err = filterByFields(req.metaStore, files, req.fieldFilters); err != nil {
+			return searchAggregate{}, "", err
+		}
+	}
+
+	agg.filesScanned = len(files)
+
+	if req.expr != nil {
+		if files, err = narrowQueryCandidates(scan, req, files, &agg); err != nil {
+			return searchAggregate{}, "", err
+		}
+	} else if req.mode == searchModeContent && req.metaStore != nil {
+		searchIndexedContent(scan, req, files, &agg)
+		scan.flush()
+		return agg, formatSearchSummary(req, agg), nil
 	}
+
+	scan.begin(len(files), false)
+
+	workerCount := searchWorkerCount()
 	jobs := make(chan string, workerCount*2)
 	var wg sync.WaitGroup
 	var aggMu sync.Mutex
@@ -147,35 +261,61 @@ func performSearch(req searchRequest) (searchAggregate, string, error) {
 		go func() {
 			defer wg.Done()
 			for path := range jobs {
+				if scan.stopped() {
+					continue
+				}
 				match, matched, err := evaluatePath(path, req)
 				if err != nil {
 					aggMu.Lock()
 					agg.warnings = append(agg.warnings, fmt.Sprintf("[WARN] %s: %v", path, err))
 					aggMu.Unlock()
+					scan.checked(nil)
 					continue
 				}
-				if matched {
-					aggMu.Lock()
-					agg.matches = append(agg.matches, match)
-					agg.filesMatched++
-					agg.totalMatches += match.MatchCount
-					aggMu.Unlock()
+				if !matched {
+					scan.checked(nil)
+					continue
 				}
+				aggMu.Lock()
+				agg.matches = append(agg.matches, match)
+				agg.filesMatched++
+				agg.totalMatches += match.MatchCount
+				aggMu.Unlock()
+				scan.checked(&match)
 			}
 		}()
 	}
 
 	for _, path := range files {
+		if scan.stopped() {
+			break
+		}
 		jobs <- path
 	}
 	close(jobs)
 	wg.Wait()
+	scan.flush()
 
 	summary := formatSearchSummary(req, agg)
 	return agg, summary, nil
 }
 
+func searchWorkerCount() int {
+	workerCount := runtime.NumCPU()
+	if workerCount < 2 {
+		workerCount = 2
+	}
+	return workerCount
+}
+
 func formatSearchSummary(req searchRequest, agg searchAggregate) string {
+	if strings.TrimSpace(req.query) == "" {
+		summary := fmt.Sprintf("Field search %s: %d file(s) matched", strings.Join(req.filterLabels(), ", "), agg.filesMatched)
+		if len(agg.warnings) > 0 {
+			summary += fmt.Sprintf(" [%d warning(s)]", len(agg.warnings))
+		}
+		return summary
+	}
 	if agg.filesMatched == 0 {
 		summary := fmt.Sprintf("%s search: no matches for %q", req.mode.displayName(), req.query)
 		if len(agg.warnings) > 0 {
@@ -184,7 +324,7 @@ func formatSearchSummary(req searchRequest, agg searchAggregate) string {
 		return summary
 	}
 	summary := fmt.Sprintf("%s search: %d file(s) matched", req.mode.displayName(), agg.filesMatched)
-	if req.mode == searchModeContent {
+	if req.mode == searchModeContent || (req.expr != nil && queryUsesField(req.expr, searchModeContent)) {
 		summary = fmt.Sprintf("%s search: %d file(s), %d match(es)", req.mode.displayName(), agg.filesMatched, agg.totalMatches)
 	}
 	if len(agg.warnings) > 0 {
@@ -205,6 +345,39 @@ func collectDocumentFiles(root string, skipDirs []string) ([]string, []string, e
 		rootPath = "."
 	}
 
+	shouldSkip := skipDirMatcher(rootPath, skipDirs)
+
+	err := filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			warnings = append(warnings, fmt.Sprintf("[WARN] %s: %v", path, walkErr))
+			return nil
+		}
+		name := d.Name()
+		if shouldSkip(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path != rootPath && strings.HasPrefix(name, ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(name, ".") {
+			return nil
+		}
+		if ext := strings.ToLower(filepath.Ext(name)); ext == ".pdf" || ext == ".epub" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, warnings, err
+}
+
+// skipDirMatcher reports whether a path lies in one of skipDirs below root.
+func skipDirMatcher(rootPath string, skipDirs []string) func(string) bool {
 	type skipEntry struct {
 		path   string
 		prefix string
@@ -229,7 +402,7 @@ func collectDocumentFiles(root string, skipDirs []string) ([]string, []string, e
 		})
 	}
 
-	shouldSkip := func(path string) bool {
+	return func(path string) bool {
 		if len(skipEntries) == 0 {
 			return false
 		}
@@ -241,34 +414,43 @@ func collectDocumentFiles(root string, skipDirs []string) ([]string, []string, e
 		}
 		return false
 	}
+}
 
-	err := filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, walkErr error) error {
-		if walkErr != nil {
-			warnings = append(warnings, fmt.Sprintf("[WARN] %s: %v", path, walkErr))
-			return nil
+// storedDocumentFiles returns the paths of list that collectDocumentFiles
+// would have found under root: existing PDF/EPUB files outside hidden and
+// skipped directories.
+func storedDocumentFiles(root string, skipDirs []string, list []meta.Metadata) []string {
+	rootPath := canonicalPath(root)
+	if rootPath == "" {
+		rootPath = filepath.Clean(root)
+	}
+	shouldSkip := skipDirMatcher(rootPath, skipDirs)
+	files := make([]string, 0, len(list))
+	for _, md := range list {
+		ext := strings.ToLower(filepath.Ext(md.Path))
+		if ext != ".pdf" && ext != ".epub" {
+			continue
 		}
-		name := d.Name()
-		if shouldSkip(path) {
-			if d.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
+		rel, err := filepath.Rel(rootPath, md.Path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, fmt.Sprintf("..%c", os.PathSeparator)) {
+			continue
 		}
-		if d.IsDir() {
-			if path != rootPath && strings.HasPrefix(name, ".") {
-				return filepath.SkipDir
+		hidden := false
+		for _, part := range strings.Split(rel, string(os.PathSeparator)) {
+			if strings.HasPrefix(part, ".") {
+				hidden = true
+				break
 			}
-			return nil
 		}
-		if strings.HasPrefix(name, ".") {
-			return nil
+		if hidden || shouldSkip(md.Path) {
+			continue
 		}
-		if ext := strings.ToLower(filepath.Ext(name)); ext == ".pdf" || ext == ".epub" {
-			files = append(files, path)
+		if info, err := os.Stat(md.Path); err != nil || !info.Mode().IsRegular() {
+			continue
 		}
-		return nil
-	})
-	return files, warnings, err
+		files = append(files, md.Path)
+	}
+	return files
 }
 
 func collectPDFFiles(root string, skipDirs []string) ([]string, []string, error) {
@@ -288,60 +470,43 @@ func collectPDFFiles(root string, skipDirs []string) ([]string, []string, error)
 func evaluatePath(path string, req searchRequest) (searchMatch, bool, error) {
 	ext := strings.ToLower(filepath.Ext(path))
 	switch req.mode {
+	case searchModeQuery:
+		return evaluateQuery(path, req)
 	case searchModeContent:
 		if ext == ".epub" {
-			return searchEPUBContent(path, req.query, req.caseSensitive, req.wrapWidth, req.metaStore)
+			return searchEPUBContent(path, req.matcher(), req.wrapWidth, req.metaStore)
 		}
-		return searchPDFContent(path, req.query, req.caseSensitive, req.wrapWidth, req.metaStore)
+		return searchPDFContent(path, req.matcher(), req.wrapWidth, req.metaStore)
 	default:
 		if ext == ".epub" {
-			return searchEPUBMetadata(path, req.mode, req.query, req.caseSensitive, req.metaStore)
+			return searchEPUBMetadata(path, req.mode, req.matcher(), req.metaStore)
 		}
-		return searchPDFMetadata(path, req.mode, req.query, req.caseSensitive, req.metaStore)
+		return searchPDFMetadata(path, req.mode, req.matcher(), req.metaStore)
 	}
 }
 
-func searchPDFContent(path, query string, caseSensitive bool, wrapWidth int, store *meta.Store) (searchMatch, bool, error) {
+func searchPDFContent(path string, tm textMatcher, wrapWidth int, store *meta.Store) (searchMatch, bool, error) {
 	text, err := readPDFText(path)
 	if err != nil {
 		return searchMatch{}, false, err
 	}
-	positions := findAllMatches(text, query, caseSensitive)
-	if len(positions) == 0 {
-		return searchMatch{}, false, nil
-	}
-	maxSnippets := maxSnippetsPerFile
-	if maxSnippets > len(positions) {
-		maxSnippets = len(positions)
-	}
-	snippets := make([]string, 0, maxSnippets)
-	for i := 0; i < maxSnippets; i++ {
-		pos := positions[i]
-		snippet := makeSnippet(text, pos, len(query), query, caseSensitive, wrapWidth)
-		snippets = append(snippets, snippet)
-	}
-	if len(positions) > maxSnippetsPerFile {
-		extra := len(positions) - maxSnippetsPerFile
-		snippets = append(snippets, fmt.Sprintf("(+%d more match(es) in this file)", extra))
-	}
-	match := searchMatch{
-		Path:       path,
-		Mode:       searchModeContent,
-		MatchCount: len(positions),
-		Snippets:   snippets,
-	}
-	populateMatchDisplay(&match, store)
-	return match, true, nil
+	match, ok := buildContentMatch(path, text, tm, wrapWidth, store)
+	return match, ok, nil
 }
 
-func searchEPUBContent(path, query string, caseSensitive bool, wrapWidth int, store *meta.Store) (searchMatch, bool, error) {
+func searchEPUBContent(path string, tm textMatcher, wrapWidth int, store *meta.Store) (searchMatch, bool, error) {
 	text, err := readEPUBText(path)
 	if err != nil {
 		return searchMatch{}, false, err
 	}
-	positions := findAllMatches(text, query, caseSensitive)
+	match, ok := buildContentMatch(path, text, tm, wrapWidth, store)
+	return match, ok, nil
+}
+
+func buildContentMatch(path, text string, tm textMatcher, wrapWidth int, store *meta.Store) (searchMatch, bool) {
+	positions := tm.find(text)
 	if len(positions) == 0 {
-		return searchMatch{}, false, nil
+		return searchMatch{}, false
 	}
 	maxSnippets := maxSnippetsPerFile
 	if maxSnippets > len(positions) {
@@ -349,8 +514,7 @@ func searchEPUBContent(path, query string, caseSensitive bool, wrapWidth int, st
 	}
 	snippets := make([]string, 0, maxSnippets)
 	for i := 0; i < maxSnippets; i++ {
-		pos := positions[i]
-		snippet := makeSnippet(text, pos, len(query), query, caseSensitive, wrapWidth)
+		snippet := makeSnippet(text, positions[i], tm, wrapWidth)
 		snippets = append(snippets, snippet)
 	}
 	if len(positions) > maxSnippetsPerFile {
@@ -362,12 +526,14 @@ func searchEPUBContent(path, query string, caseSensitive bool, wrapWidth int, st
 		Mode:       searchModeContent,
 		MatchCount: len(positions),
 		Snippets:   snippets,
+		termFreq:   map[string]int{tm.query: len(positions)},
+		docLen:     len(strings.Fields(text)),
 	}
 	populateMatchDisplay(&match, store)
-	return match, true, nil
+	return match, true
 }
 
-func searchPDFMetadata(path string, mode searchMode, query string, caseSensitive bool, store *meta.Store) (searchMatch, bool, error) {
+func searchPDFMetadata(path string, mode searchMode, tm textMatcher, store *meta.Store) (searchMatch, bool, error) {
 	var stored *meta.Metadata
 	canonical := canonicalPath(path)
 	if store != nil {
@@ -382,30 +548,22 @@ func searchPDFMetadata(path string, mode searchMode, query string, caseSensitive
 	}
 
 	metaInfo := pdfMeta{}
-	needPDFInfo := mode == searchModeYear
 	if stored != nil {
 		metaInfo.Title = strings.TrimSpace(stored.Title)
 		metaInfo.Author = strings.TrimSpace(stored.Author)
 		metaInfo.Tag = strings.TrimSpace(stored.Tag)
+		metaInfo.Year = strings.TrimSpace(stored.Year)
 	}
+	// A year search (-r -y) matches the stored year and only falls back to
+	// the PDF's dates for papers without one.
+	needPDFInfo := mode == searchModeYear && metaInfo.Year == ""
 
 	if mode == searchModeTag {
 		// Tag searches rely solely on stored metadata; they do not fall back to PDF info.
-		if !matchTags(metaInfo.Tag, query, caseSensitive) {
+		if !tm.matchTags(metaInfo.Tag) {
 			return searchMatch{}, false, nil
 		}
-		lines := []string{
-			fmt.Sprintf("Title : %s", highlightField(metaInfo.Title, query, caseSensitive)),
-			fmt.Sprintf("Author: %s", highlightField(metaInfo.Author, query, caseSensitive)),
-			fmt.Sprintf("Tags  : %s", highlightField(metaInfo.Tag, query, caseSensitive)),
-		}
-		match := searchMatch{
-			Path:       path,
-			Mode:       mode,
-			MatchCount: 1,
-			Snippets:   lines,
-			Meta:       metaInfo,
-		}
+		match := tagSearchMatch(path, metaInfo, tm)
 		populateMatchDisplay(&match, store)
 		return match, true, nil
 	}
@@ -418,6 +576,8 @@ func searchPDFMetadata(path string, mode searchMode, query string, caseSensitive
 		field = metaInfo.Author
 	case searchModeTag:
 		field = metaInfo.Tag
+	case searchModeYear:
+		field = metaInfo.Year
 	}
 
 	if strings.TrimSpace(field) == "" || needPDFInfo {
@@ -445,8 +605,6 @@ func searchPDFMetadata(path string, mode searchMode, query string, caseSensitive
 				field = metaInfo.Title
 			}
 		}
-	} else if mode == searchModeYear {
-		field = metaInfo.CreationDate + " " + metaInfo.ModDate
 	}
 
 	if strings.TrimSpace(field) == "" {
@@ -465,22 +623,35 @@ func searchPDFMetadata(path string, mode searchMode, query string, caseSensitive
 		return searchMatch{}, false, nil
 	}
 
-	target := field
-	needle := query
-	if !caseSensitive {
-		target = strings.ToLower(target)
-		needle = strings.ToLower(needle)
-	}
-	if !strings.Contains(target, needle) {
+	if mode == searchModeAuthor && stored != nil && len(stored.Authors) > 0 {
+		if !matchAuthors(stored.Authors, tm) {
+			return searchMatch{}, false, nil
+		}
+	} else if !tm.match(field) {
 		return searchMatch{}, false, nil
 	}
 
+	plain := tm.exact()
+	highlightTitle, highlightAuthor := plain.highlight(metaInfo.Title), plain.highlight(metaInfo.Author)
+	switch mode {
+	case searchModeTitle:
+		highlightTitle = tm.highlight(metaInfo.Title)
+	case searchModeAuthor:
+		highlightAuthor = tm.highlight(metaInfo.Author)
+	}
+
 	lines := []string{
-		fmt.Sprintf("Title        : %s", highlightField(metaInfo.Title, query, caseSensitive)),
-		fmt.Sprintf("Author       : %s", highlightField(metaInfo.Author, query, caseSensitive)),
-		fmt.Sprintf("Tag          : %s", highlightField(metaInfo.Tag, query, caseSensitive)),
-		fmt.Sprintf("CreationDate : %s", highlightField(metaInfo.CreationDate, query, caseSensitive)),
-		fmt.Sprintf("ModDate      : %s", highlightField(metaInfo.ModDate, query, caseSensitive)),
+		fmt.Sprintf("Title        : %s", highlightTitle),
+		fmt.Sprintf("Author       : %s", highlightAuthor),
+		fmt.Sprintf("Tag          : %s", plain.highlight(metaInfo.Tag)),
+	}
+	if mode == searchModeYear && metaInfo.Year != "" {
+		lines = append(lines, fmt.Sprintf("Year         : %s", tm.highlight(metaInfo.Year)))
+	} else {
+		lines = append(lines,
+			fmt.Sprintf("CreationDate : %s", plain.highlight(metaInfo.CreationDate)),
+			fmt.Sprintf("ModDate      : %s", plain.highlight(metaInfo.ModDate)),
+		)
 	}
 
 	match := searchMatch{
@@ -494,7 +665,82 @@ func searchPDFMetadata(path string, mode searchMode, query string, caseSensitive
 	return match, true, nil
 }
 
-func searchEPUBMetadata(path string, mode searchMode, query string, caseSensitive bool, store *meta.Store) (searchMatch, bool, error) {
+func tagSearchMatch(path string, metaInfo pdfMeta, tm textMatcher) searchMatch {
+	lines := []string{
+		fmt.Sprintf("Title : %s", tm.highlight(metaInfo.Title)),
+		fmt.Sprintf("Author: %s", tm.highlight(metaInfo.Author)),
+		fmt.Sprintf("Tags  : %s", tm.highlight(metaInfo.Tag)),
+	}
+	return searchMatch{
+		Path:       path,
+		Mode:       searchModeTag,
+		MatchCount: 1,
+		Snippets:   lines,
+		Meta:       metaInfo,
+	}
+}
+
+// searchStoredTags answers a tag search with one store query instead of a
+// directory walk; tags only live in the store, so no file has to be read.
+func searchStoredTags(req searchRequest) (searchAggregate, string, error) {
+	filter := req.metadataFilter()
+	if req.pattern == nil {
+		filter.Tags = []string{req.query}
+	}
+	list, err := req.metaStore.Query(context.Background(), filter)
+	if err != nil {
+		return searchAggregate{}, "", fmt.Errorf("query metadata: %w", err)
+	}
+	byPath := make(map[string]meta.Metadata, len(list))
+	for _, md := range list {
+		byPath[md.Path] = md
+	}
+	files, err := filterByFields(req.metaStore, storedDocumentFiles(req.root, req.skipDirs, list), req.fieldFilters)
+	if err != nil {
+		return searchAggregate{}, "", err
+	}
+
+	agg := searchAggregate{filesScanned: len(files)}
+	for _, path := range files {
+		md := byPath[path]
+		if !req.matcher().matchTags(md.Tag) {
+			continue
+		}
+		metaInfo := pdfMeta{
+			Title:  strings.TrimSpace(md.Title),
+			Author: strings.TrimSpace(md.Author),
+			Tag:    strings.TrimSpace(md.Tag),
+		}
+		match := tagSearchMatch(path, metaInfo, req.matcher())
+		match.Year = strings.TrimSpace(md.Year)
+		populateMatchDisplay(&match, req.metaStore)
+		agg.matches = append(agg.matches, match)
+		agg.filesMatched++
+		agg.totalMatches++
+	}
+	return agg, formatSearchSummary(req, agg), nil
+}
+
+// matchAuthors reports whether any single author's name matches, so an
+// author search never matches across two neighbouring names.
+func matchAuthors(authors []meta.Author, tm textMatcher) bool {
+	for _, a := range authors {
+		if tm.match(a.Full) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(target, needle string, caseSensitive bool) bool {
+	if !caseSensitive {
+		target = strings.ToLower(target)
+		needle = strings.ToLower(needle)
+	}
+	return strings.Contains(target, needle)
+}
+
+func searchEPUBMetadata(path string, mode searchMode, tm textMatcher, store *meta.Store) (searchMatch, bool, error) {
 	var stored *meta.Metadata
 	canonical := canonicalPath(path)
 	if store != nil {
@@ -545,21 +791,28 @@ func searchEPUBMetadata(path string, mode searchMode, query string, caseSensitiv
 		return searchMatch{}, false, nil
 	}
 
-	target := field
-	needle := query
-	if !caseSensitive {
-		target = strings.ToLower(target)
-		needle = strings.ToLower(needle)
-	}
-	if !strings.Contains(target, needle) {
+	if mode == searchModeAuthor && stored != nil && len(stored.Authors) > 0 {
+		if !matchAuthors(stored.Authors, tm) {
+			return searchMatch{}, false, nil
+		}
+	} else if !tm.match(field) {
 		return searchMatch{}, false, nil
 	}
 
+	plain := tm.exact()
+	highlightTitle, highlightAuthor := plain.highlight(metaInfo.Title), plain.highlight(metaInfo.Author)
+	switch mode {
+	case searchModeTitle:
+		highlightTitle = tm.highlight(metaInfo.Title)
+	case searchModeAuthor:
+		highlightAuthor = tm.highlight(metaInfo.Author)
+	}
+
 	lines := []string{
-		fmt.Sprintf("Title        : %s", highlightField(metaInfo.Title, query, caseSensitive)),
-		fmt.Sprintf("Author       : %s", highlightField(metaInfo.Author, query, caseSensitive)),
-		fmt.Sprintf("Year         : %s", highlightField(metaInfo.CreationDate, query, caseSensitive)),
-		fmt.Sprintf("Tag          : %s", highlightField(metaInfo.Tag, query, caseSensitive)),
+		fmt.Sprintf("Title        : %s", highlightTitle),
+		fmt.Sprintf("Author       : %s", highlightAuthor),
+		fmt.Sprintf("Year         : %s", plain.highlight(metaInfo.CreationDate)),
+		fmt.Sprintf("Tag          : %s", plain.highlight(metaInfo.Tag)),
 	}
 
 	match := searchMatch{
@@ -672,6 +925,8 @@ func readPDFInfo(path string) (pdfMeta, error) {
 	return parsePDFInfo(stdout.String()), nil
 }
 
+// matchTags reports whether any stored tag equals one of the query tags or
+// sits below it in the hierarchy, so "ml" also matches "ml/nlp".
 func matchTags(stored, query string, caseSensitive bool) bool {
 	storedTags := splitTags(stored)
 	queryTags := splitTags(query)
@@ -680,13 +935,7 @@ func matchTags(stored, query string, caseSensitive bool) bool {
 	}
 	for _, q := range queryTags {
 		for _, t := range storedTags {
-			if caseSensitive {
-				if t == q {
-					return true
-				}
-				continue
-			}
-			if strings.EqualFold(t, q) {
+			if meta.TagMatches(t, q, caseSensitive) {
 				return true
 			}
 		}
@@ -695,19 +944,7 @@ func matchTags(stored, query string, caseSensitive bool) bool {
 }
 
 func splitTags(value string) []string {
-	if strings.TrimSpace(value) == "" {
-		return nil
-	}
-	parts := strings.Split(value, ",")
-	out := make([]string, 0, len(parts))
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if part == "" {
-			continue
-		}
-		out = append(out, part)
-	}
-	return out
+	return meta.SplitTags(value)
 }
 
 func parsePDFInfo(output string) pdfMeta {
@@ -782,16 +1019,16 @@ func findAllMatches(text, query string, caseSensitive bool) []int {
 	return positions
 }
 
-func makeSnippet(text string, idx, matchLen int, query string, caseSensitive bool, wrapWidth int) string {
+func makeSnippet(text string, at textSpan, tm textMatcher, wrapWidth int) string {
 	const context = 80
-	if idx < 0 {
+	if at.start < 0 {
 		return ""
 	}
-	start := idx - context
+	start := at.start - context
 	if start < 0 {
 		start = 0
 	}
-	end := idx + matchLen + context
+	end := at.end + context
 	if end > len(text) {
 		end = len(text)
 	}
@@ -802,7 +1039,7 @@ func makeSnippet(text string, idx, matchLen int, query string, caseSensitive boo
 	snippet = rePunct.ReplaceAllString(snippet, "$1 $2")
 	reCamel := regexp.MustCompile(`([a-z])([A-Z])`)
 	snippet = reCamel.ReplaceAllString(snippet, "$1 $2")
-	snippet = highlight(snippet, query, caseSensitive)
+	snippet = tm.mark(snippet)
 	snippet = wrapSnippet(snippet, wrapWidth)
 	return snippet
 }
@@ -0,0 +1,225 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// searchReportInterval is how often a running search reports progress.
+const searchReportInterval = 150 * time.Millisecond
+
+// searchProgressMsg carries the matches a running search found since its
+// last report and how far it got. events delivers the job's next message.
+type searchProgressMsg struct {
+	job      int
+	matches  []searchMatch
+	scanned  int
+	total    int
+	indexing bool
+	events   <-chan tea.Msg
+}
+
+// searchProgress is one report of a running search.
+type searchProgress struct {
+	matches  []searchMatch
+	scanned  int
+	total    int
+	indexing bool
+}
+
+// searchScan is the running state of a search: its context, which stops it,
+// and the progress it reports while files are checked. A nil report keeps
+// the scan silent.
+type searchScan struct {
+	ctx    context.Context
+	report func(searchProgress)
+
+	mu       sync.Mutex
+	total    int
+	scanned  int
+	indexing bool
+	pending  []searchMatch
+	last     time.Time
+}
+
+func newSearchScan(ctx context.Context, report func(searchProgress)) *searchScan {
+	return &searchScan{ctx: ctx, report: report}
+}
+
+// stopped reports whether the search was cancelled.
+func (s *searchScan) stopped() bool {
+	return s.ctx.Err() != nil
+}
+
+// begin starts counting towards total files; indexing marks the files as
+// being added to the full-text index rather than matched.
+func (s *searchScan) begin(total int, indexing bool) {
+	s.mu.Lock()
+	s.total, s.scanned, s.indexing = total, 0, indexing
+	s.mu.Unlock()
+	s.flush()
+}
+
+// checked counts one more file and queues its match, if any, for the next
+// report.
+func (s *searchScan) checked(match *searchMatch) {
+	if s.report == nil {
+		return
+	}
+	s.mu.Lock()
+	s.scanned++
+	if match != nil {
+		s.pending = append(s.pending, *match)
+	}
+	due := time.Since(s.last) >= searchReportInterval
+	s.mu.Unlock()
+	if due {
+		s.flush()
+	}
+}
+
+// flush reports the queued matches and the current counts.
+func (s *searchScan) flush() {
+	if s.report == nil {
+		return
+	}
+	s.mu.Lock()
+	p := searchProgress{matches: s.pending, scanned: s.scanned, total: s.total, indexing: s.indexing}
+	s.pending = nil
+	s.last = time.Now()
+	s.mu.Unlock()
+	s.report(p)
+}
+
+// newSearchCmd runs req in the background. Progress and the final
+// searchResultMsg arrive one at a time through waitForSearchEvent; once ctx
+// is cancelled the job stops sending.
+func newSearchCmd(ctx context.Context, job int, req searchRequest) tea.Cmd {
+	events := make(chan tea.Msg)
+	send := func(msg tea.Msg) {
+		select {
+		case events <- msg:
+		case <-ctx.Done():
+		}
+	}
+	go func() {
+		defer close(events)
+		report := func(p searchProgress) {
+			send(searchProgressMsg{
+				job:      job,
+				matches:  p.matches,
+				scanned:  p.scanned,
+				total:    p.total,
+				indexing: p.indexing,
+				events:   events,
+			})
+		}
+		agg, summary, err := performSearchContext(ctx, req, report)
+		send(searchResultMsg{
+			job:          job,
+			req:          req,
+			matches:      agg.matches,
+			warnings:     agg.warnings,
+			filesMatched: agg.filesMatched,
+			totalMatches: agg.totalMatches,
+			summary:      summary,
+			err:          err,
+		})
+	}()
+	return waitForSearchEvent(events)
+}
+
+func waitForSearchEvent(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// isCurrentSearch reports whether a message of job belongs to the search
+// that is still running.
+func (m *Model) isCurrentSearch(job int) bool {
+	return m.searchCancel != nil && job == m.searchJob
+}
+
+// showSearchProgress adds the matches of a progress report to the results
+// view, opening it on the first report.
+func (m *Model) showSearchProgress(msg searchProgressMsg) {
+	if !m.searchStreaming {
+		m.clearCommandOutput()
+		m.clearSearchResults()
+		m.state = stateSearchResults
+		m.lastSearchQuery = m.runningSearch.query
+		m.lastSearchMode = m.runningSearch.mode
+		m.searchStreaming = true
+	}
+	for _, match := range msg.matches {
+		match.rank = len(m.searchResults)
+		m.searchResults = append(m.searchResults, match)
+	}
+	if len(msg.matches) > 0 && m.searchOrder != resultOrderRelevance {
+		sortSearchResults(m.searchResults, m.searchOrder)
+	}
+	m.searchScanned, m.searchTotal = msg.scanned, msg.total
+	if msg.indexing {
+		m.searchSummary = fmt.Sprintf("%s search: indexing %d/%d files", m.runningSearch.mode.displayName(), m.searchScanned, m.searchTotal)
+	} else {
+		m.searchSummary = fmt.Sprintf("%s search: %d/%d files scanned, %d file(s) matched so far", m.runningSearch.mode.displayName(), m.searchScanned, m.searchTotal, len(m.searchResults))
+	}
+	m.ensureSearchResultVisible()
+	m.setPersistentStatus(m.searchSummary + " (Esc stops)")
+}
+
+// finishSearch shows the final, ranked results of the running search. When
+// partial results are already on screen the cursor stays on its paper and
+// the chosen order is kept.
+func (m *Model) finishSearch(msg searchResultMsg) {
+	streaming, order := m.searchStreaming, m.searchOrder
+	var current string
+	if match := m.currentSearchMatch(); streaming && match != nil {
+		current = match.Path
+	}
+	m.cancelSearch()
+	m.clearCommandOutput()
+	m.enterSearchResults(msg)
+	if !streaming {
+		return
+	}
+	m.searchOrder = order
+	sortSearchResults(m.searchResults, order)
+	for i, match := range m.searchResults {
+		if match.Path == current {
+			m.searchResultCursor = i
+			break
+		}
+	}
+	m.ensureSearchResultVisible()
+}
+
+// cancelSearch stops the running search, if any, without a word.
+func (m *Model) cancelSearch() {
+	if m.searchCancel != nil {
+		m.searchCancel()
+		m.searchCancel = nil
+	}
+	m.searchStreaming = false
+}
+
+// stopSearch cancels the running search on request and keeps what it found.
+func (m *Model) stopSearch() {
+	streaming := m.searchStreaming
+	m.cancelSearch()
+	if !streaming {
+		m.setStatus("Search stopped")
+		return
+	}
+	m.searchSummary = fmt.Sprintf("%s search stopped: %d/%d files scanned, %d file(s) matched", m.lastSearchMode.displayName(), m.searchScanned, m.searchTotal, len(m.searchResults))
+	m.setPersistentStatus(m.searchSummary + " (Esc/q closes, Enter opens)")
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func writeSearchLibrary(t *testing.T, n int) string {
+	t.Helper()
+	libDir := filepath.Join(canonicalPath(t.TempDir()), "library")
+	if err := os.MkdirAll(libDir, 0o755); err != nil {
+		t.Fatalf("mkdir library: %v", err)
+	}
+	for i := 0; i < n; i++ {
+		body := "Nothing to see here."
+		if i%2 == 0 {
+			body = "Transformers everywhere."
+		}
+		writeTestEPUB(t, filepath.Join(libDir, fmt.Sprintf("paper%02d.epub", i)), body)
+	}
+	return libDir
+}
+
+func TestPerformSearchContextReportsProgress(t *testing.T) {
+	libDir := writeSearchLibrary(t, 8)
+	var reports []searchProgress
+	report := func(p searchProgress) { reports = append(reports, p) }
+
+	agg, _, err := performSearchContext(context.Background(), searchRequest{root: libDir, mode: searchModeContent, query: "transformers"}, report)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(agg.matches) != 4 {
+		t.Fatalf("expected 4 matches, got %d", len(agg.matches))
+	}
+	if len(reports) == 0 {
+		t.Fatalf("expected progress reports")
+	}
+	streamed := 0
+	for _, p := range reports {
+		streamed += len(p.matches)
+	}
+	if streamed != len(agg.matches) {
+		t.Fatalf("streamed %d matches, want %d", streamed, len(agg.matches))
+	}
+	if last := reports[len(reports)-1]; last.scanned != 8 || last.total != 8 {
+		t.Fatalf("expected the last report to count 8/8 files, got %d/%d", last.scanned, last.total)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := performSearchContext(ctx, searchRequest{root: libDir, mode: searchModeContent, query: "transformers"}, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled search, got %v", err)
+	}
+}
+
+func TestSearchJobStreamsAndStops(t *testing.T) {
+	libDir := writeSearchLibrary(t, 6)
+	req := searchRequest{root: libDir, mode: searchModeContent, query: "transformers"}
+
+	m := &Model{cwd: libDir, root: libDir}
+	cmd := m.runSearch(req)
+	var model tea.Model = *m
+	for cmd != nil {
+		model, cmd = model.Update(cmd())
+	}
+	got := model.(Model)
+	if got.state != stateSearchResults || len(got.searchResults) != 3 {
+		t.Fatalf("expected 3 results in the results view, got state %v with %d", got.state, len(got.searchResults))
+	}
+	if got.searchCancel != nil || got.searchStreaming {
+		t.Fatalf("expected the finished search to be cleared")
+	}
+
+	m = &Model{cwd: libDir, root: libDir}
+	cmd = m.runSearch(req)
+	model, _ = (*m).Update(cmd())
+	got = model.(Model)
+	model, _ = got.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	got = model.(Model)
+	if got.searchCancel != nil {
+		t.Fatalf("expected Esc to stop the search")
+	}
+	if got.state == stateSearchResults && !strings.Contains(got.searchSummary, "stopped") {
+		t.Fatalf("expected a stopped summary, got %q", got.searchSummary)
+	}
+	// A message from the stopped job is ignored.
+	model, _ = got.Update(searchResultMsg{job: got.searchJob, summary: "late"})
+	if model.(Model).searchSummary == "late" {
+		t.Fatalf("expected results of a stopped search to be dropped")
+	}
+}
@@ -0,0 +1,949 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorae/internal/meta"
+)
+
+// searchModeQuery marks a search made with the boolean query language, e.g.
+// `transformer author:bengio year:>=2020 NOT tag:survey`.
+const searchModeQuery searchMode = "query"
+
+// queryFieldAdded and queryFieldOpened compare the dates a paper was added
+// and last opened; only the query language searches them.
+const (
+	queryFieldAdded  searchMode = "added"
+	queryFieldOpened searchMode = "opened"
+)
+
+// neverOpenedWord is the query word for papers that were never opened.
+const neverOpenedWord = "never-opened"
+
+// queryFields are the field prefixes the query language understands.
+var queryFields = []searchMode{searchModeTitle, searchModeAuthor, searchModeYear, searchModeTag, searchModeContent, queryFieldAdded, queryFieldOpened}
+
+// queryNode is a node of a parsed boolean query.
+type queryNode interface {
+	String() string
+}
+
+type queryAnd struct{ children []queryNode }
+
+type queryOr struct{ children []queryNode }
+
+type queryNot struct{ child queryNode }
+
+// queryTerm matches one field against a value. Year, added and opened terms
+// are ranges: op is the comparison as typed (empty for a..b ranges, "never"
+// for never-opened) and the parsed bounds are in yearMin/yearMax or
+// after/before.
+type queryTerm struct {
+	field searchMode
+	op    string
+	value string
+
+	yearMin, yearMax int       // inclusive; zero is unbounded
+	after, before    time.Time // after is inclusive, before exclusive
+}
+
+func (n queryAnd) String() string { return joinQueryNodes(n.children, " AND ") }
+
+func (n queryOr) String() string { return joinQueryNodes(n.children, " OR ") }
+
+func (n queryNot) String() string {
+	if _, ok := n.child.(queryTerm); ok {
+		return "NOT " + n.child.String()
+	}
+	return "NOT (" + n.child.String() + ")"
+}
+
+// isText reports whether t matches words in a text field, as opposed to a
+// year or date range.
+func (t queryTerm) isText() bool {
+	switch t.field {
+	case searchModeYear, queryFieldAdded, queryFieldOpened:
+		return false
+	}
+	return true
+}
+
+func (t queryTerm) String() string {
+	if t.op == "never" {
+		return neverOpenedWord
+	}
+	value := t.value
+	if strings.ContainsAny(value, " \t") {
+		value = strconv.Quote(value)
+	}
+	return string(t.field) + ":" + t.op + value
+}
+
+func joinQueryNodes(nodes []queryNode, sep string) string {
+	parts := make([]string, len(nodes))
+	for i, n := range nodes {
+		parts[i] = n.String()
+		if _, ok := n.(queryTerm); !ok {
+			if _, ok := n.(queryNot); !ok {
+				parts[i] = "(" + parts[i] + ")"
+			}
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// isBooleanQuery reports whether the query words use the query language
+// rather than the plain one-mode syntax: an operator, a parenthesis, a year
+// comparison or range, a date filter, or a field prefix anywhere but on the
+// first word. A lone leading prefix such as "title:attention is all" keeps
+// its old meaning.
+func isBooleanQuery(parts []string) bool {
+	for i, part := range parts {
+		if !strings.ContainsAny(part, " \t") {
+			switch part {
+			case "AND", "OR", "NOT":
+				return true
+			}
+			if strings.EqualFold(strings.Trim(part, "()"), neverOpenedWord) {
+				return true
+			}
+			if strings.HasPrefix(part, "(") || strings.HasSuffix(part, ")") {
+				return true
+			}
+		}
+		field, value, ok := splitQueryField(part)
+		if !ok {
+			continue
+		}
+		if i > 0 {
+			return true
+		}
+		switch field {
+		case searchModeYear:
+			if strings.ContainsAny(value, "<>=") || strings.Contains(value, "..") {
+				return true
+			}
+		case queryFieldAdded, queryFieldOpened:
+			return true
+		}
+	}
+	return false
+}
+
+// regexQueryTerm returns the first word of a regex search (-r) that belongs
+// to the query language: an operator, never-opened, a date term or a field
+// term after the first word. The pattern is matched against a single field,
+// so these would silently become part of it. Parentheses and | are left to
+// the pattern.
+func regexQueryTerm(parts []string) (string, bool) {
+	for i, part := range parts {
+		switch part {
+		case "AND", "OR", "NOT":
+			return part, true
+		}
+		if strings.EqualFold(part, neverOpenedWord) {
+			return part, true
+		}
+		field, _, ok := splitQueryField(part)
+		if !ok {
+			continue
+		}
+		if i > 0 || field == queryFieldAdded || field == queryFieldOpened {
+			return part, true
+		}
+	}
+	return "", false
+}
+
+// splitQueryField splits "field:value" for the known query fields.
+func splitQueryField(word string) (searchMode, string, bool) {
+	name, value, ok := strings.Cut(word, ":")
+	if !ok {
+		return "", "", false
+	}
+	for _, field := range queryFields {
+		if strings.EqualFold(name, string(field)) {
+			return field, value, true
+		}
+	}
+	return "", "", false
+}
+
+type queryToken struct {
+	kind  string // "word", "(", ")", "AND", "OR", "NOT"
+	value string
+}
+
+// lexQuery turns the words of a search line into tokens. Words holding
+// whitespace were quoted on the prompt and stay phrases; parentheses are
+// split off the others.
+func lexQuery(parts []string) []queryToken {
+	var tokens []queryToken
+	for _, part := range parts {
+		if strings.ContainsAny(part, " \t") {
+			tokens = append(tokens, queryToken{kind: "word", value: part})
+			continue
+		}
+		for strings.HasPrefix(part, "(") {
+			tokens = append(tokens, queryToken{kind: "("})
+			part = part[1:]
+		}
+		closing := 0
+		for strings.HasSuffix(part, ")") {
+			closing++
+			part = part[:len(part)-1]
+		}
+		switch part {
+		case "":
+		case "AND", "OR", "NOT":
+			tokens = append(tokens, queryToken{kind: part})
+		default:
+			tokens = append(tokens, queryToken{kind: "word", value: part})
+		}
+		for ; closing > 0; closing-- {
+			tokens = append(tokens, queryToken{kind: ")"})
+		}
+	}
+	return tokens
+}
+
+// queryParser is a recursive-descent parser for
+//
+//	or   = and { "OR" and }
+//	and  = not { ["AND"] not }
+//	not  = "NOT" not | atom
+//	atom = "(" or ")" | term
+//
+// Bare words search defaultField.
+type queryParser struct {
+	tokens       []queryToken
+	pos          int
+	defaultField searchMode
+}
+
+func parseBooleanQuery(parts []string, defaultField searchMode) (queryNode, error) {
+	if defaultField == "" {
+		defaultField = searchModeContent
+	}
+	p := &queryParser{tokens: lexQuery(parts), defaultField: defaultField}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("Search query cannot be empty")
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("Unexpected %q in query", p.tokens[p.pos].display())
+	}
+	return node, nil
+}
+
+func (t queryToken) display() string {
+	if t.kind == "word" {
+		return t.value
+	}
+	return t.kind
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []queryNode{first}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != "OR" {
+			break
+		}
+		p.pos++
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return queryOr{children: children}, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	first, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	children := []queryNode{first}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == "OR" || tok.kind == ")" {
+			break
+		}
+		if tok.kind == "AND" {
+			p.pos++
+		}
+		next, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return queryAnd{children: children}, nil
+}
+
+func (p *queryParser) parseNot() (queryNode, error) {
+	tok, ok := p.peek()
+	if ok && tok.kind == "NOT" {
+		p.pos++
+		child, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return queryNot{child: child}, nil
+	}
+	return p.parseAtom()
+}
+
+func (p *queryParser) parseAtom() (queryNode, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("Query ends unexpectedly")
+	}
+	switch tok.kind {
+	case "(":
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing, ok := p.peek(); !ok || closing.kind != ")" {
+			return nil, fmt.Errorf("Missing ) in query")
+		}
+		p.pos++
+		return node, nil
+	case "word":
+		p.pos++
+		return p.parseTerm(tok.value)
+	}
+	return nil, fmt.Errorf("Unexpected %q in query", tok.display())
+}
+
+func (p *queryParser) parseTerm(word string) (queryNode, error) {
+	if strings.EqualFold(word, neverOpenedWord) {
+		return queryTerm{field: queryFieldOpened, op: "never"}, nil
+	}
+	term := queryTerm{field: p.defaultField, value: word}
+	if field, value, ok := splitQueryField(word); ok {
+		term.field, term.value = field, value
+	}
+	switch term.field {
+	case searchModeYear, queryFieldAdded, queryFieldOpened:
+		for _, op := range []string{">=", "<=", ">", "<", "="} {
+			if rest, ok := strings.CutPrefix(term.value, op); ok {
+				term.op, term.value = op, rest
+				break
+			}
+		}
+	}
+	term.value = strings.TrimSpace(term.value)
+	if term.value == "" {
+		return nil, fmt.Errorf("Missing value for %s: in query", term.field)
+	}
+	var err error
+	switch term.field {
+	case searchModeYear:
+		term.yearMin, term.yearMax, err = parseYearRange(term.op, term.value)
+		if term.op == "" && !strings.Contains(term.value, "..") {
+			term.op = "="
+		}
+	case queryFieldAdded, queryFieldOpened:
+		term.after, term.before, err = parseDateRange(term.op, term.value, time.Now())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return term, nil
+}
+
+// parseYearRange turns a year comparison (op and a year) or a from..to
+// range, either end of which may be left open, into inclusive bounds.
+func parseYearRange(op, value string) (lo, hi int, err error) {
+	parse := func(s string) (int, error) {
+		year, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || year <= 0 {
+			return 0, fmt.Errorf("Invalid year %q in query", strings.TrimSpace(s))
+		}
+		return year, nil
+	}
+	if from, to, ok := strings.Cut(value, ".."); ok {
+		if op != "" || strings.TrimSpace(from+to) == "" {
+			return 0, 0, fmt.Errorf("Invalid year range %q in query", op+value)
+		}
+		if strings.TrimSpace(from) != "" {
+			if lo, err = parse(from); err != nil {
+				return 0, 0, err
+			}
+		}
+		if strings.TrimSpace(to) != "" {
+			if hi, err = parse(to); err != nil {
+				return 0, 0, err
+			}
+		}
+		if hi != 0 && lo > hi {
+			return 0, 0, fmt.Errorf("Empty year range %q in query", value)
+		}
+		return lo, hi, nil
+	}
+	year, err := parse(value)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch op {
+	case ">=":
+		return year, 0, nil
+	case ">":
+		return year + 1, 0, nil
+	case "<=":
+		return 0, year, nil
+	case "<":
+		return 0, year - 1, nil
+	}
+	return year, year, nil
+}
+
+// parseDateRange turns a date filter into the instants it allows: after is
+// inclusive and before exclusive, and a zero bound is open. value is a
+// calendar date (2026, 2026-01 or 2026-01-15, meaning the whole period), an
+// age relative to now (30d, 2w, 6m, 1y) or a from..to range of either. An
+// age compares how long ago something happened, so <30d is the last 30
+// days and a bare age means the same.
+func parseDateRange(op, value string, now time.Time) (after, before time.Time, err error) {
+	if from, to, ok := strings.Cut(value, ".."); ok {
+		if op != "" || strings.TrimSpace(from+to) == "" {
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid date range %q in query", op+value)
+		}
+		if from = strings.TrimSpace(from); from != "" {
+			if after, _, _, err = parseQueryDate(from, now); err != nil {
+				return time.Time{}, time.Time{}, err
+			}
+		}
+		if to = strings.TrimSpace(to); to != "" {
+			start, end, age, err := parseQueryDate(to, now)
+			if err != nil {
+				return time.Time{}, time.Time{}, err
+			}
+			before = end
+			if age {
+				before = start
+			}
+		}
+		if !after.IsZero() && !before.IsZero() && !after.Before(before) {
+			return time.Time{}, time.Time{}, fmt.Errorf("Empty date range %q in query", value)
+		}
+		return after, before, nil
+	}
+	start, end, age, err := parseQueryDate(value, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if age {
+		switch op {
+		case ">", ">=":
+			return time.Time{}, start, nil
+		}
+		return start, time.Time{}, nil
+	}
+	switch op {
+	case ">":
+		return end, time.Time{}, nil
+	case ">=":
+		return start, time.Time{}, nil
+	case "<":
+		return time.Time{}, start, nil
+	case "<=":
+		return time.Time{}, end, nil
+	}
+	return start, end, nil
+}
+
+// parseQueryDate parses one end of a date filter. A calendar date yields the
+// local period [start, end); an age yields the instant that long before now
+// as both start and end, with age set.
+func parseQueryDate(value string, now time.Time) (start, end time.Time, age bool, err error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if n := len(value); n > 1 {
+		if count, convErr := strconv.Atoi(value[:n-1]); convErr == nil && count >= 0 {
+			var at time.Time
+			switch value[n-1] {
+			case 'd':
+				at = now.AddDate(0, 0, -count)
+			case 'w':
+				at = now.AddDate(0, 0, -7*count)
+			case 'm':
+				at = now.AddDate(0, -count, 0)
+			case 'y':
+				at = now.AddDate(-count, 0, 0)
+			}
+			if !at.IsZero() {
+				return at, at, true, nil
+			}
+		}
+	}
+	for _, layout := range []struct {
+		format string
+		years  int
+		months int
+		days   int
+	}{
+		{"2006-01-02", 0, 0, 1},
+		{"2006-01", 0, 1, 0},
+		{"2006", 1, 0, 0},
+	} {
+		if t, parseErr := time.ParseInLocation(layout.format, value, time.Local); parseErr == nil {
+			return t, t.AddDate(layout.years, layout.months, layout.days), false, nil
+		}
+	}
+	return time.Time{}, time.Time{}, false, fmt.Errorf("Invalid date %q in query (use 2026-01-15, 2026-01, 2026 or an age such as 30d, 2w, 6m, 1y)", value)
+}
+
+// queryConjuncts returns the nodes that must all hold for n to hold.
+func queryConjuncts(n queryNode) []queryNode {
+	if and, ok := n.(queryAnd); ok {
+		return and.children
+	}
+	return []queryNode{n}
+}
+
+// positiveQueryTerms lists the terms of n that are not negated, which are
+// the ones worth highlighting.
+func positiveQueryTerms(n queryNode) []queryTerm {
+	var terms []queryTerm
+	var walk func(queryNode)
+	walk = func(n queryNode) {
+		switch n := n.(type) {
+		case queryAnd:
+			for _, c := range n.children {
+				walk(c)
+			}
+		case queryOr:
+			for _, c := range n.children {
+				walk(c)
+			}
+		case queryTerm:
+			terms = append(terms, n)
+		}
+	}
+	walk(n)
+	return terms
+}
+
+func queryUsesField(n queryNode, field searchMode) bool {
+	switch n := n.(type) {
+	case queryAnd:
+		for _, c := range n.children {
+			if queryUsesField(c, field) {
+				return true
+			}
+		}
+	case queryOr:
+		for _, c := range n.children {
+			if queryUsesField(c, field) {
+				return true
+			}
+		}
+	case queryNot:
+		return queryUsesField(n.child, field)
+	case queryTerm:
+		return n.field == field
+	}
+	return false
+}
+
+// queryStoreFilter pushes the top-level tag, year, added and opened
+// conditions of n down to the store. Those fields only live there, so the
+// filter never drops a file that the full evaluation would keep. ok is false
+// when nothing applies.
+func queryStoreFilter(n queryNode, base meta.Filter) (meta.Filter, bool) {
+	filter := base
+	applied := false
+	for _, c := range queryConjuncts(n) {
+		term, isTerm := c.(queryTerm)
+		if !isTerm {
+			continue
+		}
+		switch term.field {
+		case searchModeTag:
+			filter.Tags = append(filter.Tags, term.value)
+			applied = true
+		case searchModeYear:
+			if lo := term.yearMin; lo != 0 && (filter.YearMin == 0 || lo > filter.YearMin) {
+				filter.YearMin = lo
+			}
+			if hi := term.yearMax; hi != 0 && (filter.YearMax == 0 || hi < filter.YearMax) {
+				filter.YearMax = hi
+			}
+			applied = true
+		case queryFieldAdded:
+			narrowTimeRange(&filter.AddedAfter, &filter.AddedBefore, term)
+			applied = true
+		case queryFieldOpened:
+			// Files the store does not know were never opened either, so
+			// never-opened stays with the full evaluation.
+			if term.op != "never" {
+				narrowTimeRange(&filter.OpenedAfter, &filter.OpenedBefore, term)
+				applied = true
+			}
+		}
+	}
+	return filter, applied
+}
+
+// narrowTimeRange intersects the range [after, before) with that of term.
+func narrowTimeRange(after, before *time.Time, term queryTerm) {
+	if !term.after.IsZero() && term.after.After(*after) {
+		*after = term.after
+	}
+	if !term.before.IsZero() && (before.IsZero() || term.before.Before(*before)) {
+		*before = term.before
+	}
+}
+
+// narrowQueryCandidates drops files that cannot match req.expr, using the
+// store for tag and year conditions and the full-text index for required
+// content words, so only the remaining files have to be evaluated.
+func narrowQueryCandidates(scan *searchScan, req searchRequest, files []string, agg *searchAggregate) ([]string, error) {
+	store := req.metaStore
+	if store == nil {
+		return files, nil
+	}
+	ctx := scan.ctx
+	keep := func(allowed map[string]bool) {
+		kept := files[:0:0]
+		for _, path := range files {
+			if allowed[canonicalPath(path)] {
+				kept = append(kept, path)
+			}
+		}
+		files = kept
+	}
+
+	if filter, ok := queryStoreFilter(req.expr, req.metadataFilter()); ok {
+		list, err := store.Query(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("query metadata: %w", err)
+		}
+		allowed := make(map[string]bool, len(list))
+		for _, md := range list {
+			allowed[md.Path] = true
+		}
+		keep(allowed)
+	}
+
+	if !queryUsesField(req.expr, searchModeContent) || len(files) == 0 {
+		return files, nil
+	}
+	root := canonicalPath(req.root)
+	if root == "" {
+		root = req.root
+	}
+	agg.warnings = append(agg.warnings, refreshFullTextIndex(scan, store, root, files)...)
+	for _, c := range queryConjuncts(req.expr) {
+		term, ok := c.(queryTerm)
+		if !ok || term.field != searchModeContent {
+			continue
+		}
+		paths, usable, err := store.SearchFullText(ctx, root, term.value)
+		if err != nil {
+			agg.warnings = append(agg.warnings, fmt.Sprintf("[WARN] full-text index: %v", err))
+			continue
+		}
+		if !usable {
+			continue
+		}
+		allowed := make(map[string]bool, len(paths))
+		for _, path := range paths {
+			allowed[path] = true
+		}
+		keep(allowed)
+	}
+	return files, nil
+}
+
+// queryDoc loads what a query needs to know about one file, each part at
+// most once and only when a term asks for it.
+type queryDoc struct {
+	path   string
+	req    searchRequest
+	stored *meta.Metadata
+
+	info       pdfMeta
+	infoLoaded bool
+	text       string
+	textLoaded bool
+	textErr    error
+}
+
+func newQueryDoc(path string, req searchRequest) (*queryDoc, error) {
+	doc := &queryDoc{path: path, req: req}
+	if req.metaStore != nil {
+		data, err := req.metaStore.Get(context.Background(), canonicalPath(path))
+		if err != nil {
+			return nil, fmt.Errorf("load metadata: %w", err)
+		}
+		doc.stored = data
+	}
+	return doc, nil
+}
+
+// fileInfo is the title, author and year embedded in the file itself.
+func (d *queryDoc) fileInfo() pdfMeta {
+	if !d.infoLoaded {
+		d.infoLoaded = true
+		if isEPUB(d.path) {
+			d.info, _ = parseEPUBMetadata(d.path)
+		} else {
+			d.info, _ = readPDFInfo(d.path)
+		}
+	}
+	return d.info
+}
+
+func (d *queryDoc) title() string {
+	if d.stored != nil && strings.TrimSpace(d.stored.Title) != "" {
+		return strings.TrimSpace(d.stored.Title)
+	}
+	if title := strings.TrimSpace(d.fileInfo().Title); title != "" {
+		return title
+	}
+	return strings.TrimSuffix(filepath.Base(d.path), filepath.Ext(d.path))
+}
+
+func (d *queryDoc) author() string {
+	if d.stored != nil && strings.TrimSpace(d.stored.Author) != "" {
+		return strings.TrimSpace(d.stored.Author)
+	}
+	return strings.TrimSpace(d.fileInfo().Author)
+}
+
+func (d *queryDoc) year() string {
+	if d.stored == nil {
+		return ""
+	}
+	return strings.TrimSpace(d.stored.Year)
+}
+
+func (d *queryDoc) tag() string {
+	if d.stored == nil {
+		return ""
+	}
+	return strings.TrimSpace(d.stored.Tag)
+}
+
+func (d *queryDoc) addedAt() time.Time {
+	if d.stored == nil {
+		return time.Time{}
+	}
+	return d.stored.AddedAt
+}
+
+func (d *queryDoc) openedAt() time.Time {
+	if d.stored == nil {
+		return time.Time{}
+	}
+	return d.stored.LastOpenedAt
+}
+
+// inTimeRange reports whether at, which is zero when unknown, falls in the
+// range of a date term.
+func inTimeRange(at time.Time, t queryTerm) bool {
+	if at.IsZero() {
+		return false
+	}
+	return (t.after.IsZero() || !at.Before(t.after)) && (t.before.IsZero() || at.Before(t.before))
+}
+
+// content prefers the full-text index and extracts the text otherwise.
+func (d *queryDoc) content() (string, error) {
+	if d.textLoaded {
+		return d.text, d.textErr
+	}
+	d.textLoaded = true
+	if d.req.metaStore != nil {
+		text, indexed, err := d.req.metaStore.IndexedText(context.Background(), canonicalPath(d.path))
+		if err == nil && indexed {
+			d.text = text
+			return d.text, nil
+		}
+	}
+	d.text, d.textErr = readDocumentText(d.path)
+	return d.text, d.textErr
+}
+
+func formatQueryDate(at time.Time) string {
+	if at.IsZero() {
+		return "never"
+	}
+	return at.Local().Format("2006-01-02 15:04")
+}
+
+func (d *queryDoc) eval(n queryNode) (bool, error) {
+	switch n := n.(type) {
+	case queryAnd:
+		for _, c := range n.children {
+			ok, err := d.eval(c)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case queryOr:
+		for _, c := range n.children {
+			ok, err := d.eval(c)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case queryNot:
+		ok, err := d.eval(n.child)
+		return !ok && err == nil, err
+	case queryTerm:
+		return d.evalTerm(n)
+	}
+	return false, fmt.Errorf("unknown query node %T", n)
+}
+
+func (d *queryDoc) evalTerm(t queryTerm) (bool, error) {
+	caseSensitive := d.req.caseSensitive
+	tm := textMatcher{query: t.value, caseSensitive: caseSensitive, fuzzy: d.req.fuzzy()}
+	switch t.field {
+	case searchModeTitle:
+		return tm.match(d.title()), nil
+	case searchModeAuthor:
+		if d.stored != nil && len(d.stored.Authors) > 0 {
+			return matchAuthors(d.stored.Authors, tm), nil
+		}
+		return tm.match(d.author()), nil
+	case searchModeTag:
+		return matchTags(d.tag(), t.value, caseSensitive), nil
+	case searchModeYear:
+		year, ok := meta.LeadingYear(d.year())
+		if !ok {
+			return false, nil
+		}
+		return (t.yearMin == 0 || year >= t.yearMin) && (t.yearMax == 0 || year <= t.yearMax), nil
+	case queryFieldAdded:
+		return inTimeRange(d.addedAt(), t), nil
+	case queryFieldOpened:
+		if t.op == "never" {
+			return d.openedAt().IsZero(), nil
+		}
+		return inTimeRange(d.openedAt(), t), nil
+	case searchModeContent:
+		text, err := d.content()
+		if err != nil {
+			return false, err
+		}
+		return containsFold(text, t.value, caseSensitive), nil
+	}
+	return false, nil
+}
+
+// evaluateQuery matches one file against req.expr and describes the match
+// with its metadata and snippets around the content words it contains.
+func evaluateQuery(path string, req searchRequest) (searchMatch, bool, error) {
+	doc, err := newQueryDoc(path, req)
+	if err != nil {
+		return searchMatch{}, false, err
+	}
+	ok, err := doc.eval(req.expr)
+	if err != nil || !ok {
+		return searchMatch{}, false, err
+	}
+
+	highlights := make(map[searchMode][]string)
+	for _, t := range positiveQueryTerms(req.expr) {
+		if t.isText() {
+			highlights[t.field] = append(highlights[t.field], t.value)
+		}
+	}
+	metaInfo := pdfMeta{Title: doc.title(), Author: doc.author(), Tag: doc.tag(), Year: doc.year()}
+	match := searchMatch{
+		Path:       path,
+		Mode:       searchModeQuery,
+		MatchCount: 1,
+		Meta:       metaInfo,
+		Year:       metaInfo.Year,
+		Snippets: []string{
+			"Title  : " + highlightTerms(metaInfo.Title, highlights[searchModeTitle], req.caseSensitive, req.fuzzy()),
+			"Author : " + highlightTerms(metaInfo.Author, highlights[searchModeAuthor], req.caseSensitive, req.fuzzy()),
+			"Year   : " + highlightTerms(metaInfo.Year, nil, req.caseSensitive, false),
+			"Tag    : " + highlightTerms(metaInfo.Tag, highlights[searchModeTag], req.caseSensitive, false),
+		},
+	}
+	if queryUsesField(req.expr, queryFieldAdded) {
+		match.Snippets = append(match.Snippets, "Added  : "+formatQueryDate(doc.addedAt()))
+	}
+	if queryUsesField(req.expr, queryFieldOpened) {
+		match.Snippets = append(match.Snippets, "Opened : "+formatQueryDate(doc.openedAt()))
+	}
+
+	if words := highlights[searchModeContent]; len(words) > 0 {
+		text, _ := doc.content()
+		match.termFreq = make(map[string]int, len(words))
+		match.docLen = len(strings.Fields(text))
+		count := 0
+		for _, word := range words {
+			tm := textMatcher{query: word, caseSensitive: req.caseSensitive}
+			positions := tm.find(text)
+			match.termFreq[word] = len(positions)
+			count += len(positions)
+			for _, pos := range positions {
+				if len(match.Excerpts) >= maxSnippetsPerFile {
+					break
+				}
+				match.Excerpts = append(match.Excerpts, makeSnippet(text, pos, tm, req.wrapWidth))
+			}
+		}
+		if count > 0 {
+			match.MatchCount = count
+		}
+		if extra := count - len(match.Excerpts); extra > 0 {
+			match.Excerpts = append(match.Excerpts, fmt.Sprintf("(+%d more match(es) in this file)", extra))
+		}
+	}
+	populateMatchDisplay(&match, req.metaStore)
+	return match, true, nil
+}
+
+// highlightTerms highlights every occurrence of any of terms in value,
+// fuzzily when fuzzy is set.
+func highlightTerms(value string, terms []string, caseSensitive, fuzzy bool) string {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return "(empty)"
+	}
+	var spans []textSpan
+	for _, term := range terms {
+		tm := textMatcher{query: term, caseSensitive: caseSensitive, fuzzy: fuzzy}
+		spans = append(spans, tm.find(trimmed)...)
+	}
+	return highlightSpans(trimmed, spans)
+}
@@ -0,0 +1,261 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"gorae/internal/meta"
+)
+
+func TestParseBooleanQuery(t *testing.T) {
+	cases := []struct {
+		parts []string
+		want  string
+	}{
+		{[]string{"transformer", "author:bengio", "year:>=2020", "NOT", "tag:survey"},
+			"content:transformer AND author:bengio AND year:>=2020 AND NOT tag:survey"},
+		{[]string{"(title:bert", "OR", "title:gpt)", "AND", "year:2023"},
+			"(title:bert OR title:gpt) AND year:=2023"},
+		{[]string{"title:deep learning", "OR", "tag:ml"}, `title:"deep learning" OR tag:ml`},
+		{[]string{"a", "OR", "b", "c"}, "content:a OR (content:b AND content:c)"},
+		{[]string{"NOT", "(tag:a", "OR", "tag:b)"}, "NOT (tag:a OR tag:b)"},
+	}
+	for _, tc := range cases {
+		if !isBooleanQuery(tc.parts) {
+			t.Fatalf("expected %q to use the query language", tc.parts)
+		}
+		node, err := parseBooleanQuery(tc.parts, searchModeContent)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.parts, err)
+		}
+		if got := node.String(); got != tc.want {
+			t.Fatalf("parse %q = %s, want %s", tc.parts, got, tc.want)
+		}
+	}
+
+	for _, parts := range [][]string{
+		{"title:attention", "is", "all"},
+		{"attention", "and", "memory"},
+	} {
+		if isBooleanQuery(parts) {
+			t.Fatalf("expected %q to stay a plain query", parts)
+		}
+	}
+
+	for _, parts := range [][]string{
+		{"(title:a", "OR", "title:b"},
+		{"title:a", "OR"},
+		{"year:>=soon", "AND", "tag:x"},
+		{"title:", "AND", "tag:x"},
+		{")", "AND", "tag:x"},
+	} {
+		if _, err := parseBooleanQuery(parts, searchModeContent); err == nil {
+			t.Fatalf("expected an error for %q", parts)
+		}
+	}
+}
+
+func TestBooleanQuerySearch(t *testing.T) {
+	dir := canonicalPath(t.TempDir())
+	libDir := filepath.Join(dir, "library")
+	if err := os.MkdirAll(libDir, 0o755); err != nil {
+		t.Fatalf("mkdir library: %v", err)
+	}
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	papers := []struct {
+		name, body string
+		md         meta.Metadata
+	}{
+		{"recent.epub", "Transformer models for translation.", meta.Metadata{Title: "Recent Transformers", Author: "Yoshua Bengio", Year: "2021", Tag: "nlp"}},
+		{"survey.epub", "A transformer survey.", meta.Metadata{Title: "A Survey", Author: "Yoshua Bengio", Year: "2022", Tag: "survey"}},
+		{"old.epub", "Transformer precursors.", meta.Metadata{Title: "Old Work", Author: "Yoshua Bengio", Year: "2015", Tag: "nlp"}},
+		{"other.epub", "Transformer vision models.", meta.Metadata{Title: "Vision", Author: "Geoffrey Hinton", Year: "2023", Tag: "vision"}},
+	}
+	for _, p := range papers {
+		path := filepath.Join(libDir, p.name)
+		writeTestEPUB(t, path, p.body)
+		md := p.md
+		md.Path = path
+		if err := store.Upsert(ctx, &md); err != nil {
+			t.Fatalf("upsert %s: %v", p.name, err)
+		}
+	}
+
+	m := &Model{cwd: libDir, root: libDir, meta: store}
+	search := func(line ...string) []string {
+		t.Helper()
+		req, err := m.buildSearchRequest(line)
+		if err != nil {
+			t.Fatalf("build %q: %v", line, err)
+		}
+		agg, _, err := performSearch(req)
+		if err != nil {
+			t.Fatalf("search %q: %v", line, err)
+		}
+		var names []string
+		for _, match := range agg.matches {
+			names = append(names, filepath.Base(match.Path))
+		}
+		return names
+	}
+
+	got := search("transformer", "author:bengio", "year:>=2020", "NOT", "tag:survey")
+	if len(got) != 1 || got[0] != "recent.epub" {
+		t.Fatalf("expected only recent.epub, got %v", got)
+	}
+	got = search("tag:vision", "OR", "(year:<2016", "AND", "content:precursors)")
+	if len(got) != 2 {
+		t.Fatalf("expected old.epub and other.epub, got %v", got)
+	}
+	got = search("-t", "survey", "OR", "vision")
+	if len(got) != 2 {
+		t.Fatalf("expected bare words to search titles with -t, got %v", got)
+	}
+
+	// Narrowing by tag before the content lookup must not prune the index
+	// entries of the papers it left out.
+	if got = search("tag:nlp", "content:transformer"); len(got) != 2 {
+		t.Fatalf("expected recent.epub and old.epub, got %v", got)
+	}
+	for _, name := range []string{"survey.epub", "other.epub"} {
+		if _, indexed, _ := store.IndexedText(ctx, filepath.Join(libDir, name)); !indexed {
+			t.Fatalf("expected %s to stay in the full-text index", name)
+		}
+	}
+}
+
+func TestDateRangeTerms(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.Local) }
+	cases := []struct {
+		op, value     string
+		after, before time.Time
+	}{
+		{">", "2026-01-01", day(2026, 1, 2), time.Time{}},
+		{">=", "2026-01", day(2026, 1, 1), time.Time{}},
+		{"", "2026-01", day(2026, 1, 1), day(2026, 2, 1)},
+		{"<=", "2025", time.Time{}, day(2026, 1, 1)},
+		{"<", "30d", now.AddDate(0, 0, -30), time.Time{}},
+		{">", "1y", time.Time{}, now.AddDate(-1, 0, 0)},
+		{"", "2026-09..2026-09-30", day(2026, 9, 1), day(2026, 10, 1)},
+		{"", "60d..30d", now.AddDate(0, 0, -60), now.AddDate(0, 0, -30)},
+	}
+	for _, tc := range cases {
+		after, before, err := parseDateRange(tc.op, tc.value, now)
+		if err != nil {
+			t.Fatalf("parse %s%s: %v", tc.op, tc.value, err)
+		}
+		if !after.Equal(tc.after) || !before.Equal(tc.before) {
+			t.Fatalf("parse %s%s = [%v, %v), want [%v, %v)", tc.op, tc.value, after, before, tc.after, tc.before)
+		}
+	}
+	for _, value := range []string{"yesterday", "2026-13", "30x", "2026-10..2026-01", ".."} {
+		if _, _, err := parseDateRange("", value, now); err == nil {
+			t.Fatalf("expected an error for %q", value)
+		}
+	}
+
+	lo, hi, err := parseYearRange("", "2019..2023")
+	if err != nil || lo != 2019 || hi != 2023 {
+		t.Fatalf("year range = %d..%d, %v", lo, hi, err)
+	}
+	if _, _, err := parseYearRange("", "2023..2019"); err == nil {
+		t.Fatalf("expected an error for an empty year range")
+	}
+	for _, parts := range [][]string{{"year:2019..2023"}, {"added:>2026-01-01"}, {"never-opened"}} {
+		if !isBooleanQuery(parts) {
+			t.Fatalf("expected %q to use the query language", parts)
+		}
+	}
+}
+
+func TestDateRangeSearch(t *testing.T) {
+	dir := canonicalPath(t.TempDir())
+	libDir := filepath.Join(dir, "library")
+	if err := os.MkdirAll(libDir, 0o755); err != nil {
+		t.Fatalf("mkdir library: %v", err)
+	}
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	now := time.Now()
+	papers := []struct {
+		name   string
+		year   string
+		added  time.Time
+		opened time.Time
+	}{
+		{"new-unread.epub", "2021", now.AddDate(0, 0, -10), time.Time{}},
+		{"new-read.epub", "2023", now.AddDate(0, 0, -5), now.AddDate(0, 0, -1)},
+		{"old-read.epub", "2015", now.AddDate(-2, 0, 0), now.AddDate(-1, -6, 0)},
+		{"dated.epub", "2020-05", now.AddDate(0, -2, 0), now.AddDate(0, -2, 0)},
+		{"undated.epub", "n.d.", now.AddDate(0, -2, 0), now.AddDate(0, -2, 0)},
+	}
+	for _, p := range papers {
+		path := filepath.Join(libDir, p.name)
+		writeTestEPUB(t, path, "Text.")
+		if err := store.Upsert(ctx, &meta.Metadata{Path: path, Title: p.name, Year: p.year, AddedAt: p.added}); err != nil {
+			t.Fatalf("upsert %s: %v", p.name, err)
+		}
+		if !p.opened.IsZero() {
+			if err := store.RecordOpened(ctx, path, p.opened); err != nil {
+				t.Fatalf("record opened %s: %v", p.name, err)
+			}
+		}
+	}
+	writeTestEPUB(t, filepath.Join(libDir, "unknown.epub"), "Text.")
+
+	m := &Model{cwd: libDir, root: libDir, meta: store}
+	search := func(line ...string) []string {
+		t.Helper()
+		req, err := m.buildSearchRequest(line)
+		if err != nil {
+			t.Fatalf("build %q: %v", line, err)
+		}
+		agg, _, err := performSearch(req)
+		if err != nil {
+			t.Fatalf("search %q: %v", line, err)
+		}
+		var names []string
+		for _, match := range agg.matches {
+			names = append(names, filepath.Base(match.Path))
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	for _, tc := range []struct {
+		line []string
+		want []string
+	}{
+		{[]string{"added:<30d", "never-opened"}, []string{"new-unread.epub"}},
+		{[]string{"opened:<1w"}, []string{"new-read.epub"}},
+		{[]string{"opened:>1y"}, []string{"old-read.epub"}},
+		{[]string{"never-opened"}, []string{"new-unread.epub", "unknown.epub"}},
+		{[]string{"NOT", "never-opened"}, []string{"dated.epub", "new-read.epub", "old-read.epub", "undated.epub"}},
+		{[]string{"year:2019..2023"}, []string{"dated.epub", "new-read.epub", "new-unread.epub"}},
+		{[]string{"-y", "2020.."}, []string{"dated.epub", "new-read.epub", "new-unread.epub"}},
+		{[]string{"year:<=2016"}, []string{"old-read.epub"}},
+		{[]string{"-y", "2015"}, []string{"old-read.epub"}},
+		{[]string{"added:" + now.AddDate(-2, 0, 0).Format("2006-01-02")}, []string{"old-read.epub"}},
+	} {
+		if got := search(tc.line...); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("search %q = %v, want %v", tc.line, got, tc.want)
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package app
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"gorae/internal/meta"
 )
 
 func TestCollectPDFFilesSkipsHelperDirs(t *testing.T) {
@@ -58,6 +62,128 @@ func TestCollectPDFFilesSkipsMultipleHelperDirs(t *testing.T) {
 	}
 }
 
+func TestTagSearchUsesStoreWithoutWalking(t *testing.T) {
+	root := canonicalPath(t.TempDir())
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	kept := filepath.Join(root, "kept.pdf")
+	writeDummyPDF(t, kept)
+	helperDir := filepath.Join(root, "Favorites")
+	if err := os.MkdirAll(helperDir, 0o755); err != nil {
+		t.Fatalf("mkdir helper: %v", err)
+	}
+	helper := filepath.Join(helperDir, "kept.pdf")
+	writeDummyPDF(t, helper)
+	for _, path := range []string{kept, helper, filepath.Join(root, "deleted.pdf")} {
+		if err := store.Upsert(ctx, &meta.Metadata{Path: path, Title: "Paper", Tag: "ml/nlp"}); err != nil {
+			t.Fatalf("upsert %s: %v", path, err)
+		}
+	}
+
+	req := searchRequest{root: root, mode: searchModeTag, query: "ml", metaStore: store, skipDirs: []string{helperDir}}
+	agg, _, err := performSearch(req)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(agg.matches) != 1 || agg.matches[0].Path != kept {
+		t.Fatalf("expected only %s, got %+v", kept, agg.matches)
+	}
+}
+
+func TestRegexSearch(t *testing.T) {
+	dir := canonicalPath(t.TempDir())
+	libDir := filepath.Join(dir, "library")
+	if err := os.MkdirAll(libDir, 0o755); err != nil {
+		t.Fatalf("mkdir library: %v", err)
+	}
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	papers := []struct {
+		name, body string
+		md         meta.Metadata
+	}{
+		{"base.epub", "We fine-tune BERT-base and GPT2 on the task.", meta.Metadata{Title: "BERT-base Probing", Tag: "nlp/bert"}},
+		{"large.epub", "Results with GPT-3 are reported.", meta.Metadata{Title: "Scaling BERT-large", Tag: "nlp"}},
+		{"other.epub", "GPTQ quantizes models.", meta.Metadata{Title: "RoBERTa Revisited", Tag: "vision"}},
+	}
+	for _, p := range papers {
+		path := filepath.Join(libDir, p.name)
+		writeTestEPUB(t, path, p.body)
+		md := p.md
+		md.Path = path
+		if err := store.Upsert(ctx, &md); err != nil {
+			t.Fatalf("upsert %s: %v", p.name, err)
+		}
+	}
+
+	m := &Model{cwd: libDir, root: libDir, meta: store}
+	search := func(line ...string) searchAggregate {
+		t.Helper()
+		req, err := m.buildSearchRequest(line)
+		if err != nil {
+			t.Fatalf("build %q: %v", line, err)
+		}
+		agg, _, err := performSearch(req)
+		if err != nil {
+			t.Fatalf("search %q: %v", line, err)
+		}
+		return agg
+	}
+
+	agg := search("-r", "-t", `^BERT(-base|-large)?\b`)
+	if len(agg.matches) != 1 || filepath.Base(agg.matches[0].Path) != "base.epub" {
+		t.Fatalf("expected only base.epub for the title pattern, got %+v", agg.matches)
+	}
+
+	agg = search("-r", "-c", `\bGPT-?[0-9]\b`)
+	if len(agg.matches) != 2 {
+		t.Fatalf("expected the GPT2 and GPT-3 papers, got %+v", agg.matches)
+	}
+	for _, match := range agg.matches {
+		if len(match.Snippets) == 0 || !strings.Contains(match.Snippets[0], "\033[1;31mGPT") {
+			t.Fatalf("expected a highlighted snippet for %s, got %q", match.Path, match.Snippets)
+		}
+	}
+
+	agg = search("--regex", "--tag", "^nlp$")
+	if len(agg.matches) != 1 || filepath.Base(agg.matches[0].Path) != "large.epub" {
+		t.Fatalf("expected the tag pattern to match whole tags, got %+v", agg.matches)
+	}
+
+	// Year patterns match the stored year, not the PDF's dates.
+	for name, year := range map[string]string{"early.pdf": "2019", "late.pdf": "2021"} {
+		path := filepath.Join(libDir, name)
+		writeDummyPDF(t, path)
+		if err := store.Upsert(ctx, &meta.Metadata{Path: path, Title: name, Year: year}); err != nil {
+			t.Fatalf("upsert %s: %v", name, err)
+		}
+	}
+	agg = search("-r", "-y", "^2019$")
+	if len(agg.matches) != 1 || filepath.Base(agg.matches[0].Path) != "early.pdf" {
+		t.Fatalf("expected the year pattern to match the stored year, got %+v", agg.matches)
+	}
+
+	for _, line := range [][]string{
+		{"-r", "BERT("}, {"-r", "x*"},
+		{"-r", "title:bert", "AND", "tag:nlp"}, {"-r", "bert", "tag:nlp"},
+		{"-r", "bert", "never-opened"}, {"-r", "added:<30d"},
+	} {
+		if _, err := m.buildSearchRequest(line); err == nil {
+			t.Fatalf("expected %q to be rejected", line)
+		}
+	}
+}
+
 func writeDummyPDF(t *testing.T, path string) {
 	t.Helper()
 	if err := os.WriteFile(path, []byte("%PDF-1.4\n"), 0o644); err != nil {
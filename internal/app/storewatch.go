@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"gorae/internal/meta"
+)
+
+// storeWatchInterval is how often the UI checks whether the library changed
+// behind its back, e.g. in another gorae process sharing metadata.db.
+const storeWatchInterval = 2 * time.Second
+
+// savedSearchResyncInterval is the least time between two smart folder
+// refreshes caused by library changes; every rating or opened file moves the
+// generation, and a refresh re-runs every saved search.
+const savedSearchResyncInterval = 30 * time.Second
+
+type storeGenerationMsg struct {
+	store      *meta.Store
+	generation int64
+	err        error
+}
+
+// watchStoreCmd reports the store's change generation after an interval.
+func watchStoreCmd(store *meta.Store) tea.Cmd {
+	if store == nil {
+		return nil
+	}
+	return tea.Tick(storeWatchInterval, func(time.Time) tea.Msg {
+		gen, err := store.Generation(context.Background())
+		return storeGenerationMsg{store: store, generation: gen, err: err}
+	})
+}
+
+// handleStoreGeneration drops the cached metadata when the generation moved
+// and schedules the next check. Our own writes move it too, which only costs
+// one extra reload. Smart folders are refreshed once the generation has held
+// still for a check, at most every savedSearchResyncInterval.
+func (m *Model) handleStoreGeneration(msg storeGenerationMsg) tea.Cmd {
+	if msg.store != m.meta {
+		// The library was switched; its own watch is already running.
+		return nil
+	}
+	if msg.err == nil && msg.generation != m.storeGeneration {
+		m.storeGeneration = msg.generation
+		m.reloadCurrentMetadata()
+		if m.state == stateNormal {
+			m.updateTextPreview()
+		}
+		// Any change may alter what a saved search finds.
+		m.loadSavedSearches()
+		m.savedSearchesStale = true
+		return watchStoreCmd(m.meta)
+	}
+	if m.savedSearchesStale && time.Since(m.savedSearchesSyncedAt) >= savedSearchResyncInterval {
+		m.savedSearchesStale = false
+		return tea.Batch(watchStoreCmd(m.meta), m.resyncSavedSearchesCmd())
+	}
+	return watchStoreCmd(m.meta)
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"gorae/internal/meta"
+)
+
+func (m *Model) handleTagCommand(args []string) tea.Cmd {
+	if m.meta == nil {
+		m.setStatus("Metadata store not available")
+		return nil
+	}
+	if len(args) == 0 {
+		return m.listTags()
+	}
+	sub := strings.ToLower(args[0])
+	rest := args[1:]
+	switch sub {
+	case "list", "ls":
+		return m.listTags()
+	case "rename":
+		if len(rest) != 2 {
+			m.setStatus("Usage: :tag rename <old> <new>")
+			return nil
+		}
+		count, err := m.meta.RenameTag(context.Background(), rest[0], rest[1])
+		if err != nil {
+			if errors.Is(err, meta.ErrTagExists) {
+				m.setStatus(fmt.Sprintf("Tag %q already exists; use :tag merge %s %s", rest[1], rest[0], rest[1]))
+				return nil
+			}
+			m.setStatus("Failed to rename tag: " + err.Error())
+			return nil
+		}
+		m.reloadCurrentMetadata()
+		m.setStatus(fmt.Sprintf("Renamed tag %s → %s on %d file(s)", rest[0], rest[1], count))
+	case "merge":
+		if len(rest) < 2 {
+			m.setStatus("Usage: :tag merge <tag>... <into>")
+			return nil
+		}
+		sources, target := rest[:len(rest)-1], rest[len(rest)-1]
+		count, err := m.meta.MergeTags(context.Background(), sources, target)
+		if err != nil {
+			m.setStatus("Failed to merge tags: " + err.Error())
+			return nil
+		}
+		m.reloadCurrentMetadata()
+		m.setStatus(fmt.Sprintf("Merged %s into %s on %d file(s)", strings.Join(sources, ", "), target, count))
+	case "show":
+		if len(rest) == 0 {
+			m.setStatus("Usage: :tag show <tag>")
+			return nil
+		}
+		return m.showTagResults(strings.Join(rest, " "))
+	default:
+		m.setStatus(fmt.Sprintf("Unknown tag command: %s", sub))
+	}
+	return nil
+}
+
+func (m *Model) listTags() tea.Cmd {
+	tags, err := m.meta.ListTags(context.Background())
+	if err != nil {
+		m.setStatus("Failed to load tags: " + err.Error())
+		return nil
+	}
+	if len(tags) == 0 {
+		m.setStatus("No tags recorded yet")
+		return nil
+	}
+	lines := make([]string, 0, len(tags)+1)
+	lines = append(lines, fmt.Sprintf("Tags (%d):", len(tags)))
+	for _, tag := range tags {
+		depth := strings.Count(tag.Name, meta.TagSeparator)
+		label := strings.Repeat("  ", depth) + tag.Name
+		lines = append(lines, fmt.Sprintf("  %-40s %d", label, tag.Papers))
+	}
+	m.setCommandOutput(lines)
+	m.setStatus(fmt.Sprintf("Listed %d tag(s)", len(tags)))
+	return nil
+}
+
+func (m *Model) showTagResults(tag string) tea.Cmd {
+	list, err := m.meta.ListByTag(context.Background(), tag)
+	if err != nil {
+		m.setStatus("Failed to load metadata: " + err.Error())
+		return nil
+	}
+	matches := metadataMatches(list)
+	summary := fmt.Sprintf("Tag %q: %d file(s)", tag, len(matches))
+	if len(matches) == 0 {
+		summary = fmt.Sprintf("Tag %q: no files", tag)
+	}
+	m.enterSearchResults(searchResultMsg{
+		req:     searchRequest{},
+		matches: matches,
+		summary: summary,
+	})
+	if len(matches) == 0 {
+		m.setStatus(summary)
+	} else {
+		m.setPersistentStatus(fmt.Sprintf("%s (Esc/q to exit)", summary))
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"regexp/syntax"
 	"sort"
 	"strconv"
 	"strings"
@@ -32,6 +33,7 @@ type metadataEditFinishedMsg struct {
 	err        error
 	tmpPath    string
 	targetPath string
+	version    int64 // of the record the editor started from
 }
 
 type noteEditFinishedMsg struct {
@@ -90,7 +92,18 @@ func (m *Model) currentYankTarget() string {
 	return m.currentEntryPath()
 }
 
+// Update handles msg and then fingerprints, in the background, the documents
+// of any directory listed while handling it.
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	updated, ok := next.(Model)
+	if !ok || len(updated.pendingIdentities) == 0 {
+		return next, cmd
+	}
+	return updated, tea.Batch(cmd, updated.reconcileFileIdentitiesCmd())
+}
+
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 
 	case tea.WindowSizeMsg:
@@ -125,6 +138,18 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.handleMetadataEditorFinished(msg)
 		return m, nil
 
+	case doctorFinishedMsg:
+		m.handleDoctorFinished(msg)
+		return m, nil
+
+	case doctorScanMsg:
+		m.handleDoctorScan(msg)
+		return m, nil
+
+	case duplicatesFoundMsg:
+		m.handleDuplicatesFound(msg)
+		return m, nil
+
 	case noteEditFinishedMsg:
 		m.handleNoteEditorFinished(msg)
 		return m, nil
@@ -175,6 +200,21 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.setStatus(summary)
 		return m, nil
 
+	case citationScanMsg:
+		m.handleCitationScanFinished(msg)
+		return m, nil
+
+	case storeGenerationMsg:
+		return m, m.handleStoreGeneration(msg)
+
+	case identitiesReconciledMsg:
+		m.handleIdentitiesReconciled(msg)
+		return m, nil
+
+	case savedSearchesSyncedMsg:
+		m.handleSavedSearchesSynced(msg)
+		return m, nil
+
 	case autoMetadataMsg:
 		if len(msg.Results) == 0 {
 			m.setStatus("Auto metadata completed")
@@ -256,13 +296,26 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, scheduleAutoMetadataScan(autoMetadataScanInterval))
 		return m, tea.Batch(cmds...)
 
+	case searchProgressMsg:
+		if !m.isCurrentSearch(msg.job) {
+			return m, nil
+		}
+		m.showSearchProgress(msg)
+		return m, waitForSearchEvent(msg.events)
+
 	case searchResultMsg:
+		if msg.job != 0 && !m.isCurrentSearch(msg.job) {
+			return m, nil
+		}
 		if msg.err != nil {
+			if m.searchStreaming {
+				m.exitSearchResults()
+			}
+			m.cancelSearch()
 			m.setStatus("Search failed: " + msg.err.Error())
 			return m, nil
 		}
-		m.clearCommandOutput()
-		m.enterSearchResults(msg)
+		m.finishSearch(msg)
 		if msg.summary != "" {
 			m.setPersistentStatus(msg.summary + " (Esc/q closes, Enter opens)")
 		} else {
@@ -273,6 +326,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		key := msg.String()
 
+		if key == "esc" && m.searchCancel != nil && (m.state == stateNormal || m.state == stateSearchResults) {
+			m.stopSearch()
+			return m, nil
+		}
+
 		if m.state == stateHelp {
 			if handled, cmd := m.handleHelpKey(key); handled {
 				return m, cmd
@@ -328,6 +386,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.applySortMode(sortByTitle)
 			case "y":
 				m.applySortMode(sortByYear)
+			case "r":
+				m.applySortMode(sortByRating)
+			case "p":
+				m.applySortMode(sortByPriority)
 			default:
 				m.setStatus("Sort cancelled")
 			}
@@ -483,6 +545,18 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, cmd
 				}
 				return m, nil
+			case "r":
+				return m, m.showCitations(m.metaEditingPath, false)
+			case "c":
+				return m, m.showCitations(m.metaEditingPath, true)
+			case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+				n := int(key[0] - '0')
+				if n > len(m.metaAttachments) {
+					m.setStatus(fmt.Sprintf("No attachment %d", n))
+					return m, nil
+				}
+				m.openAttachment(m.metaAttachments[n-1])
+				return m, nil
 			case "up", "k":
 				m.scrollMetaPopup(-1)
 				return m, nil
@@ -582,7 +656,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				req, err := m.buildSearchRequest(tokens)
 				if err != nil {
-					m.setStatus(err.Error())
+					// Keep the query so a bad pattern or flag can be fixed.
+					m.openSearchPrompt(line)
+					m.setPersistentStatus(err.Error())
 					return m, inputCmd
 				}
 				cmd := m.runSearch(req)
@@ -791,7 +867,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.updateTextPreview()
 			}
 			m.awaitingQuickFilter = true
-			m.setStatus("Filter: g r reading, g u unread, g d read (press other key to cancel)")
+			m.setStatus("Filter: g r reading, g u unread, g d read, g s top rated, g p high priority, g 1-9 saved search (press other key to cancel)")
 
 		case "G":
 			if n := len(m.entries); n > 0 {
@@ -866,7 +942,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "s":
 			m.awaitingSort = true
-			m.setStatus("Sort: 't' by title, 'y' by year")
+			m.setStatus("Sort: 't' by title, 'y' by year, 'r' by rating, 'p' by priority")
 			return m, nil
 
 		case "f":
@@ -902,6 +978,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.cycleReadingState()
 			return m, nil
 
+		case "0", "1", "2", "3", "4", "5":
+			m.setRating(int(key[0] - '0'))
+			return m, nil
+
+		case "P":
+			m.cyclePriority()
+			return m, nil
+
 		case " ":
 			if len(m.entries) == 0 {
 				return m, nil
@@ -1079,11 +1163,19 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			draft.ReadingState = normalizeReadingStateValue(draft.ReadingState)
 			m.metaDraft = draft
+			m.metaReading = meta.ReadingSummary{}
+			if m.meta != nil {
+				if sum, err := m.meta.ReadingSummary(context.Background(), canonical); err == nil {
+					m.metaReading = sum
+				}
+			}
+			m.metaCitations = m.loadCitationPanels(canonical)
+			m.metaAttachments = m.loadAttachments(canonical)
 			m.metaFieldIndex = 0
 			m.metaPopupOffset = 0
 			m.input.SetValue("")
 			m.input.Blur()
-			m.setPersistentStatus("Metadata preview: 'e' edit in editor, 'n' edit note, Esc close")
+			m.setPersistentStatus("Metadata preview: 'e' edit in editor, 'n' edit note, 'r' references, 'c' cited by, 1-9 open attachment, Esc close")
 			return m, nil
 
 		case ":":
@@ -1177,8 +1269,13 @@ func metaEditStatus(index int) string {
 }
 
 func (m *Model) handleMetadataEditorFinished(msg metadataEditFinishedMsg) {
+	keepTmp := false
 	if msg.tmpPath != "" {
-		defer os.Remove(msg.tmpPath)
+		defer func() {
+			if !keepTmp {
+				os.Remove(msg.tmpPath)
+			}
+		}()
 	}
 	m.state = stateNormal
 	m.metaEditingPath = ""
@@ -1204,7 +1301,7 @@ func (m *Model) handleMetadataEditorFinished(msg metadataEditFinishedMsg) {
 		m.setStatus("Failed to read metadata edit: " + err.Error())
 		return
 	}
-	md, err := parseMetadataEditorData(data, target)
+	md, err := parseMetadataEditorData(data, target, m.cfg)
 	if err != nil {
 		m.setStatus("Failed to parse metadata: " + err.Error())
 		return
@@ -1214,7 +1311,23 @@ func (m *Model) handleMetadataEditorFinished(msg metadataEditFinishedMsg) {
 		return
 	}
 	ctx := context.Background()
+	// The editor only covers descriptive fields; keep the flags as they are.
+	if existing, err := m.meta.Get(ctx, target); err == nil && existing != nil {
+		md.Favorite = existing.Favorite
+		md.ToRead = existing.ToRead
+		md.Rating = existing.Rating
+		md.Priority = existing.Priority
+		md.AddedAt = existing.AddedAt
+	}
+	md.Version = msg.version
 	if err := m.meta.Upsert(ctx, &md); err != nil {
+		if errors.Is(err, meta.ErrConflict) {
+			// Keep the edit so it is not lost; the user can merge it by hand.
+			keepTmp = true
+			m.reloadCurrentMetadata()
+			m.setPersistentStatus(fmt.Sprintf("Not saved: %s was changed by another gorae since you opened it. Your edit is in %s", filepath.Base(target), msg.tmpPath))
+			return
+		}
 		m.setStatus("Failed to save metadata: " + err.Error())
 		return
 	}
@@ -1245,13 +1358,21 @@ func (m *Model) launchMetadataEditor() tea.Cmd {
 	if strings.TrimSpace(m.metaDraft.Path) == "" {
 		m.metaDraft.Path = target
 	}
+	// Start from the stored record: the preview may have been open while
+	// another gorae process changed it.
+	if m.meta != nil {
+		if existing, err := m.meta.Get(context.Background(), target); err == nil && existing != nil {
+			existing.ReadingState = normalizeReadingStateValue(existing.ReadingState)
+			m.metaDraft = *existing
+		}
+	}
 	tmp, err := os.CreateTemp("", "gorae-metadata-*.json")
 	if err != nil {
 		m.setStatus("Failed to create temp file: " + err.Error())
 		return nil
 	}
 	tmpPath := tmp.Name()
-	data := metadataEditorFileFromMetadata(m.metaDraft)
+	data := metadataEditorFileFromMetadata(m.metaDraft, m.customFields())
 	if err := writeMetadataEditorFile(tmp, data); err != nil {
 		os.Remove(tmpPath)
 		m.setStatus("Failed to prepare metadata for editor: " + err.Error())
@@ -1273,11 +1394,13 @@ func (m *Model) launchMetadataEditor() tea.Cmd {
 	m.setPersistentStatus(fmt.Sprintf("Editing metadata for %s with %s (exit editor to return)", fileName, editor))
 
 	targetPath := target
+	version := m.metaDraft.Version
 	return tea.ExecProcess(cmd, func(err error) tea.Msg {
 		return metadataEditFinishedMsg{
 			err:        err,
 			tmpPath:    tmpPath,
 			targetPath: targetPath,
+			version:    version,
 		}
 	})
 }
@@ -1334,10 +1457,16 @@ type metadataEditorFile struct {
 	Abstract  string `json:"abstract"`
 	Tag       string `json:"tag"`
 	State     string `json:"reading_state,omitempty"`
+	// Identifiers lists identifiers as scheme:value, e.g. isbn:9780262033848.
+	// Removing the key leaves the stored ones untouched.
+	Identifiers []string `json:"identifiers"`
+	// Fields holds the custom fields; every configured field is listed so it
+	// can be filled in, and removed keys are left untouched.
+	Fields map[string]string `json:"fields,omitempty"`
 }
 
-func metadataEditorFileFromMetadata(md meta.Metadata) metadataEditorFile {
-	return metadataEditorFile{
+func metadataEditorFileFromMetadata(md meta.Metadata, defs []config.CustomField) metadataEditorFile {
+	data := metadataEditorFile{
 		Title:     md.Title,
 		Author:    md.Author,
 		Year:      md.Year,
@@ -1348,6 +1477,20 @@ func metadataEditorFileFromMetadata(md meta.Metadata) metadataEditorFile {
 		Tag:       md.Tag,
 		State:     md.ReadingState,
 	}
+	data.Identifiers = make([]string, 0, len(md.Identifiers))
+	for _, id := range md.Identifiers {
+		data.Identifiers = append(data.Identifiers, id.String())
+	}
+	if len(defs) > 0 || len(md.Fields) > 0 {
+		data.Fields = make(map[string]string, len(defs)+len(md.Fields))
+		for _, def := range defs {
+			data.Fields[def.Name] = ""
+		}
+		for name, value := range md.Fields {
+			data.Fields[name] = value
+		}
+	}
+	return data
 }
 
 func writeMetadataEditorFile(f *os.File, data metadataEditorFile) error {
@@ -1365,7 +1508,7 @@ func writeMetadataEditorFile(f *os.File, data metadataEditorFile) error {
 	return nil
 }
 
-func parseMetadataEditorData(raw []byte, path string) (meta.Metadata, error) {
+func parseMetadataEditorData(raw []byte, path string, cfg *config.Config) (meta.Metadata, error) {
 	var data metadataEditorFile
 	if err := json.Unmarshal(raw, &data); err != nil {
 		return meta.Metadata{}, fmt.Errorf("parse JSON: %w", err)
@@ -1382,6 +1525,33 @@ func parseMetadataEditorData(raw []byte, path string) (meta.Metadata, error) {
 		Tag:          strings.TrimSpace(data.Tag),
 		ReadingState: normalizeReadingStateValue(data.State),
 	}
+	if data.Identifiers != nil {
+		md.Identifiers = make([]meta.Identifier, 0, len(data.Identifiers))
+		for _, raw := range data.Identifiers {
+			if strings.TrimSpace(raw) == "" {
+				continue
+			}
+			id, ok := meta.ParseIdentifier(raw)
+			if !ok {
+				return meta.Metadata{}, fmt.Errorf("invalid identifier %q", strings.TrimSpace(raw))
+			}
+			md.Identifiers = append(md.Identifiers, id)
+		}
+	}
+	if data.Fields != nil {
+		md.Fields = make(map[string]string, len(data.Fields))
+		for name, value := range data.Fields {
+			name = strings.TrimSpace(name)
+			if def, ok := cfg.CustomField(name); ok {
+				normalized, err := def.Normalize(value)
+				if err != nil {
+					return meta.Metadata{}, err
+				}
+				name, value = def.Name, normalized
+			}
+			md.Fields[name] = strings.TrimSpace(value)
+		}
+	}
 	return md, nil
 }
 
@@ -1433,6 +1603,7 @@ func (m *Model) openPDF(path string) error {
 	if err := cmd.Start(); err != nil {
 		return err
 	}
+	m.trackReadingSession(path, cmd)
 	m.markReadingStateOnOpen(path)
 	return nil
 }
@@ -1516,6 +1687,24 @@ func splitCommandLine(input string) ([]string, error) {
 	return parts, nil
 }
 
+// joinCommandLine is the inverse of splitCommandLine: it quotes the tokens
+// that need it so splitting the result gives tokens back.
+func joinCommandLine(tokens []string) string {
+	parts := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		switch {
+		case token != "" && !strings.ContainsAny(token, " \t\n\"'\\"):
+			parts = append(parts, token)
+		case !strings.Contains(token, "'"):
+			parts = append(parts, "'"+token+"'")
+		default:
+			escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(token)
+			parts = append(parts, `"`+escaped+`"`)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
 func (m *Model) scrollMetaPopup(delta int) {
 	if delta == 0 {
 		return
@@ -1569,7 +1758,11 @@ func (m *Model) moveMetadataPaths(oldPath, newPath string, isDir bool) error {
 		}
 		return m.meta.MovePath(ctx, oldPath, newPath)
 	}
-	return m.meta.MovePath(ctx, oldPath, newPath)
+	if err := m.meta.MovePath(ctx, oldPath, newPath); err != nil {
+		return err
+	}
+	moveNoteFile(m.notesDir, oldPath, newPath)
+	return m.moveAttachmentsAlong(oldPath, newPath)
 }
 
 func (m *Model) syncRecentlyOpenedDirectory() error {
@@ -1629,6 +1822,28 @@ func (m *Model) runCommand(raw string) tea.Cmd {
 		return m.handleAutoMetadataCommand(args)
 	case "search":
 		return m.handleSearchCommand(args)
+	case "authors":
+		return m.handleAuthorsCommand(args)
+	case "tag", "tags":
+		return m.handleTagCommand(args)
+	case "history":
+		return m.handleHistoryCommand(args)
+	case "collection", "collections", "coll":
+		return m.handleCollectionCommand(args)
+	case "doctor":
+		return m.handleDoctorCommand(args)
+	case "stats":
+		return m.handleStatsCommand(args)
+	case "citations":
+		return m.handleCitationsCommand(args)
+	case "duplicates", "dups":
+		return m.handleDuplicatesCommand(args)
+	case "library", "lib":
+		return m.handleLibraryCommand(args)
+	case "attach":
+		return m.handleAttachCommand(args)
+	case "progress":
+		return m.handleProgressCommand(args)
 	case "q", "quit":
 		m.setStatus("Quitting...")
 		return tea.Quit
@@ -1733,6 +1948,12 @@ func (m *Model) handleQuickFilterPrefix(key string) (bool, tea.Cmd) {
 		return true, m.showQuickFilter(quickFilterUnread)
 	case "d":
 		return true, m.showQuickFilter(quickFilterRead)
+	case "s":
+		return true, m.showQuickFilter(quickFilterTopRated)
+	case "p":
+		return true, m.showQuickFilter(quickFilterPriority)
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		return true, m.runSavedSearchAt(int(lower[0] - '1'))
 	default:
 		return false, nil
 	}
@@ -1796,9 +2017,15 @@ func (m *Model) displayConfigSummary() {
 	if m.toReadDir != "" {
 		toReadDir = m.toReadDir
 	}
+	library := config.DefaultLibrary
+	if m.cfg != nil {
+		library = m.cfg.ActiveLibrary()
+	}
 	lines := []string{
 		"Config file:",
 		"  " + path,
+		"Library:",
+		"  " + library + " (" + m.root + ")",
 		"Theme file:",
 		"  " + themePath,
 		"Recently Added directory:",
@@ -1809,6 +2036,10 @@ func (m *Model) displayConfigSummary() {
 		"  " + favDir,
 		"To Read directory:",
 		"  " + toReadDir,
+		"Collections directory:",
+		"  " + m.collectionsDir,
+		"Saved searches directory:",
+		"  " + m.savedSearchesDir,
 		"Configured editor:",
 		"  " + editor,
 		"Configured PDF viewer:",
@@ -1888,24 +2119,48 @@ func buildHelpOutput() []string {
 		"  e ............ metadata preview + edit in editor",
 		"  n ............ edit note (Markdown)",
 		"  f / t / r .... favorite / to-read / cycle reading state",
+		"  0-5 / P ...... set star rating / cycle priority",
+		"  s t/y/r/p .... sort by title / year / rating / priority",
 		"  yy ............ copy BibTeX",
 		"  yt ........... copy Title / Author / Year",
 		"  :arxiv ....... fetch arXiv metadata (:arxiv -v for selected files)",
 		"  :autofetch ... detect DOI/arXiv IDs in PDFs and import metadata",
+		"  :history ..... metadata changes (:history revert <rev> [field])",
+		"  :progress .... set reading progress (:progress 42, 42/300 or 30%)",
+		"  :stats reading time spent per paper, tag and week",
+		"  :citations ... references / cited by in library (:citations refs|cited|scan)",
+		"  :attach ...... attach a file or URL to the current paper (list/open/remove/selected)",
 		"",
 		"Search & Lists",
 		"  / or :search . search content or metadata (-t/-a/-c/-y flags)",
+		"  -e/--exact ... no typo tolerance for title/author search",
+		"  -r/--regex ... treat the query as a regular expression (-r 'BERT(-base|-large)?')",
+		"  --field ...... filter on custom fields (--field dataset=imagenet)",
+		"  --rating/--priority filter by stars or priority (--rating 4)",
+		"  --id ......... find by identifier (--id arxiv:2301.01234, --id isbn:...)",
+		"  Esc ......... stop a running search (results found so far stay)",
+		"  title:/author:/year:/tag:/content: with AND, OR, NOT and ( ) combine fields",
+		"  added:/opened: dates and ranges (added:>2026-01-01, opened:<30d, year:2019..2023, never-opened)",
+		"  :authors ..... list authors (:authors <name> shows their papers)",
+		"  :tag ......... list tags (:tag show/rename/merge)",
 		"  F / T ........ favorites / to-read lists",
+		"  :collection .. named collections (create/add/remove/show/delete)",
+		"  :search save . save a search as a smart folder (save/saved/run/delete)",
 		"  g r / g u / g d... filter by reading state",
+		"  g s / g p .... top rated / high priority",
+		"  g 1-9 ........ re-run a saved search",
 		"  Recently Added: :recent rebuilds helper directory",
 		"  Recently Read : open PDFs to refresh helper directory",
 		"",
 		"Config & Theme",
 		"  :config ...... edit config (use :config show for summary)",
+		"  :library ..... list libraries (:library switch <name>)",
 		"  :config editor <cmd> sets your editor",
 		"  :theme reload / :theme show manage the active theme",
 		"",
 		"Other Commands",
+		"  :doctor ...... check library integrity (:doctor fix to resolve)",
+		"  :duplicates .. find duplicate papers (m merges into the selected copy)",
 		"  :pwd ......... show working directory",
 		"  :clear ....... hide this panel",
 		"  :q or Ctrl+C . quit",
@@ -2132,6 +2387,10 @@ func (m *Model) handleSearchCommand(args []string) tea.Cmd {
 		m.setStatus("Usage: :search [-mode title|author|year|content] [-case] [-root PATH] <query>")
 		return nil
 	}
+	switch strings.ToLower(args[0]) {
+	case "save", "saved", "run", "delete":
+		return m.handleSavedSearchCommand(args)
+	}
 
 	req, err := m.buildSearchRequest(args)
 	if err != nil {
@@ -2179,15 +2438,29 @@ func (m *Model) openSearchResultAtCursor() {
 	m.setStatus(fmt.Sprintf("Opened %s", filepath.Base(match.Path)))
 }
 
+// runSearch starts req as a background job, stopping any search that is
+// still running.
 func (m *Model) runSearch(req searchRequest) tea.Cmd {
-	m.setPersistentStatus(fmt.Sprintf("%s search for %q...", req.mode.displayName(), req.query))
-	return newSearchCmd(req)
+	m.cancelSearch()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.searchJob++
+	m.searchCancel = cancel
+	m.runningSearch = req
+	m.searchScanned, m.searchTotal = 0, 0
+	m.setPersistentStatus(fmt.Sprintf("%s search for %q... (Esc stops)", req.mode.displayName(), req.query))
+	return newSearchCmd(ctx, m.searchJob, req)
 }
 
 func (m *Model) buildSearchRequest(tokens []string) (searchRequest, error) {
-	root := canonicalPath(m.cwd)
+	return m.buildSearchRequestIn(m.cwd, tokens)
+}
+
+// buildSearchRequestIn parses a search command line that searches under dir
+// unless it sets -root.
+func (m *Model) buildSearchRequestIn(dir string, tokens []string) (searchRequest, error) {
+	root := canonicalPath(dir)
 	if root == "" {
-		root = m.cwd
+		root = dir
 	}
 	watchRoot := canonicalPath(m.root)
 	if watchRoot == "" {
@@ -2203,6 +2476,7 @@ func (m *Model) buildSearchRequest(tokens []string) (searchRequest, error) {
 	}
 
 	var queryParts []string
+	regex := false
 	for i := 0; i < len(tokens); i++ {
 		token := strings.TrimSpace(tokens[i])
 		if token == "" {
@@ -2244,6 +2518,68 @@ func (m *Model) buildSearchRequest(tokens []string) (searchRequest, error) {
 			req.mode = searchModeTag
 		case lower == "-case" || lower == "--case":
 			req.caseSensitive = true
+		case lower == "-e" || lower == "--exact":
+			req.exact = true
+		case lower == "-r" || lower == "--regex":
+			regex = true
+		case lower == "--field":
+			if i+1 >= len(tokens) {
+				return searchRequest{}, fmt.Errorf("Missing value for --field")
+			}
+			i++
+			filter, err := parseFieldFilter(m.cfg, tokens[i])
+			if err != nil {
+				return searchRequest{}, err
+			}
+			req.fieldFilters = append(req.fieldFilters, filter)
+		case strings.HasPrefix(lower, "--field="):
+			filter, err := parseFieldFilter(m.cfg, token[len("--field="):])
+			if err != nil {
+				return searchRequest{}, err
+			}
+			req.fieldFilters = append(req.fieldFilters, filter)
+		case lower == "--rating" || strings.HasPrefix(lower, "--rating="):
+			value, ok := strings.CutPrefix(token[len("--rating"):], "=")
+			if !ok {
+				if i+1 >= len(tokens) {
+					return searchRequest{}, fmt.Errorf("Missing value for --rating")
+				}
+				i++
+				value = tokens[i]
+			}
+			rating, err := parseRatingFilter(value)
+			if err != nil {
+				return searchRequest{}, err
+			}
+			req.ratingMin = rating
+		case lower == "--priority" || strings.HasPrefix(lower, "--priority="):
+			value, ok := strings.CutPrefix(token[len("--priority"):], "=")
+			if !ok {
+				if i+1 >= len(tokens) {
+					return searchRequest{}, fmt.Errorf("Missing value for --priority")
+				}
+				i++
+				value = tokens[i]
+			}
+			priorities, err := parsePriorityFilter(value)
+			if err != nil {
+				return searchRequest{}, err
+			}
+			req.priorities = append(req.priorities, priorities...)
+		case lower == "--id" || strings.HasPrefix(lower, "--id="):
+			value, ok := strings.CutPrefix(token[len("--id"):], "=")
+			if !ok {
+				if i+1 >= len(tokens) {
+					return searchRequest{}, fmt.Errorf("Missing value for --id")
+				}
+				i++
+				value = tokens[i]
+			}
+			id, ok := meta.ParseIdentifier(value)
+			if !ok {
+				return searchRequest{}, fmt.Errorf("Invalid identifier %q (use scheme:value, e.g. arxiv:2301.01234)", strings.TrimSpace(value))
+			}
+			req.identifiers = append(req.identifiers, id)
 		case lower == "-root" || lower == "--root":
 			if i+1 >= len(tokens) {
 				return searchRequest{}, fmt.Errorf("Missing value for -root")
@@ -2262,28 +2598,78 @@ func (m *Model) buildSearchRequest(tokens []string) (searchRequest, error) {
 				return searchRequest{}, err
 			}
 		default:
-			if req.mode == searchModeContent && len(queryParts) == 0 {
-				if prefMode, trimmed, ok := detectPrefixedSearchMode(token); ok {
-					req.mode = prefMode
-					if trimmed != "" {
-						queryParts = append(queryParts, trimmed)
-					}
-					continue
-				}
-			}
 			queryParts = append(queryParts, token)
 		}
 	}
 
+	if regex {
+		if term, ok := regexQueryTerm(queryParts); ok {
+			return searchRequest{}, fmt.Errorf("Regex search (-r) cannot use the query term %q; drop -r to combine terms", term)
+		}
+	}
+	// A pattern's parentheses and | are regex syntax, not query operators.
+	if !regex && isBooleanQuery(queryParts) {
+		expr, err := parseBooleanQuery(queryParts, req.mode)
+		if err != nil {
+			return searchRequest{}, err
+		}
+		req.expr = expr
+		req.mode = searchModeQuery
+	} else if req.mode == searchModeContent && len(queryParts) > 0 {
+		if prefMode, trimmed, ok := detectPrefixedSearchMode(queryParts[0]); ok {
+			req.mode = prefMode
+			queryParts[0] = trimmed
+		}
+	}
+	// A year search compares the stored year, so -y 2019..2023 works like
+	// the year:2019..2023 query term.
+	if !regex && req.mode == searchModeYear && len(queryParts) > 0 {
+		expr, err := parseBooleanQuery(queryParts, searchModeYear)
+		if err != nil {
+			return searchRequest{}, err
+		}
+		req.expr = expr
+		req.mode = searchModeQuery
+	}
+
 	query := strings.TrimSpace(strings.Join(queryParts, " "))
-	if query == "" {
+	if query == "" && !req.hasFilters() {
 		return searchRequest{}, fmt.Errorf("Search query cannot be empty")
 	}
 	req.query = query
+	if regex && query != "" {
+		pattern, err := compileSearchPattern(query, req.caseSensitive)
+		if err != nil {
+			return searchRequest{}, err
+		}
+		req.pattern = pattern
+	}
 	req.skipDirs = m.searchSkipDirs()
 	return req, nil
 }
 
+// compileSearchPattern compiles the query of a regex search, ignoring case
+// unless caseSensitive. A pattern that matches the empty string would match
+// every paper, so it is rejected.
+func compileSearchPattern(query string, caseSensitive bool) (*regexp.Regexp, error) {
+	expr := query
+	if !caseSensitive {
+		expr = "(?i)" + expr
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		var syntaxErr *syntax.Error
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("Invalid regex %q: %s", query, syntaxErr.Code)
+		}
+		return nil, fmt.Errorf("Invalid regex %q: %v", query, err)
+	}
+	if pattern.MatchString("") {
+		return nil, fmt.Errorf("Regex %q matches the empty string", query)
+	}
+	return pattern, nil
+}
+
 func (m *Model) runArxivFetch(id string, files []string) tea.Cmd {
 	if len(files) == 0 {
 		m.setStatus("No files selected for arXiv import")
@@ -2367,6 +2753,10 @@ func (m *Model) applySearchRoot(req *searchRequest, value string, watchRoot stri
 }
 
 func (m *Model) handleSearchResultsKey(key string) (bool, tea.Cmd) {
+	if m.pendingMerge != nil {
+		m.confirmDuplicateMerge(key)
+		return true, nil
+	}
 	switch key {
 	case "esc", "q":
 		m.exitSearchResults()
@@ -2413,6 +2803,14 @@ func (m *Model) handleSearchResultsKey(key string) (bool, tea.Cmd) {
 		}
 		// In search view, plain 't' keeps its normal meaning (jump?), so just ignore here.
 		return true, nil
+	case "m":
+		if m.duplicatesView {
+			m.promptDuplicateMerge()
+		}
+		return true, nil
+	case "o":
+		m.cycleSearchOrder()
+		return true, nil
 	case "g":
 		m.searchResultCursor = 0
 		m.ensureSearchResultVisible()
@@ -2452,7 +2850,8 @@ func (m *Model) fetchArxivMetadata(id string, files []string) tea.Cmd {
 			return arxivUpdateMsg{err: err}
 		}
 
-		authorStr := strings.Join(metadata.Authors, ", ")
+		authors := namesToAuthors(metadata.Authors)
+		authorStr := meta.FormatAuthors(authors)
 		yearStr := ""
 		if metadata.Year > 0 {
 			yearStr = strconv.Itoa(metadata.Year)
@@ -2471,12 +2870,13 @@ func (m *Model) fetchArxivMetadata(id string, files []string) tea.Cmd {
 			}
 			md.Title = metadata.Title
 			md.Author = authorStr
+			md.Authors = authors
 			md.Year = yearStr
 			if metadata.DOI != "" {
 				md.DOI = metadata.DOI
 			}
 			md.Abstract = metadata.Abstract
-			if err := store.Upsert(baseCtx, &md); err != nil {
+			if err := store.UpsertFrom(baseCtx, &md, meta.SourceArxiv); err != nil {
 				return arxivUpdateMsg{err: fmt.Errorf("save metadata for %s: %w", filepath.Base(path), err)}
 			}
 			updated = append(updated, path)
@@ -2623,6 +3023,17 @@ var commandNames = []string{
 	"arxiv",
 	"autofetch",
 	"search",
+	"authors",
+	"tag",
+	"history",
+	"collection",
+	"doctor",
+	"stats",
+	"progress",
+	"citations",
+	"duplicates",
+	"library",
+	"attach",
 	"q", "quit",
 }
 
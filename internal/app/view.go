@@ -62,17 +62,21 @@ func (m Model) renderTreePanel(width, height int) []string {
 	lines := []panelLine{
 		{text: fmt.Sprintf("Current: %s", filepath.Base(m.cwd)), kind: panelLineInfo},
 	}
+	render := func() []string {
+		lines = append(lines, m.savedSearchPanelLines()...)
+		return m.renderPanelBlock("Tree", lines, width, height, m.styles.Tree)
+	}
 
 	parent := filepath.Dir(m.cwd)
 	if parent == m.cwd || !strings.HasPrefix(parent, m.root) {
 		lines = append(lines, panelLine{text: "(root directory)", kind: panelLineInfo})
-		return m.renderPanelBlock("Tree", lines, width, height, m.styles.Tree)
+		return render()
 	}
 
 	ents, err := os.ReadDir(parent)
 	if err != nil {
 		lines = append(lines, panelLine{text: "(error reading parent)", kind: panelLineInfo})
-		return m.renderPanelBlock("Tree", lines, width, height, m.styles.Tree)
+		return render()
 	}
 
 	filtered := make([]os.DirEntry, 0, len(ents))
@@ -115,7 +119,24 @@ func (m Model) renderTreePanel(width, height int) []string {
 		lines = append(lines, panelLine{text: text, kind: kind})
 	}
 
-	return m.renderPanelBlock("Tree", lines, width, height, m.styles.Tree)
+	return render()
+}
+
+// savedSearchPanelLines lists the saved searches under the tree with the
+// g-key that re-runs each.
+func (m Model) savedSearchPanelLines() []panelLine {
+	if len(m.savedSearches) == 0 {
+		return nil
+	}
+	lines := []panelLine{{text: "Saved searches:", kind: panelLineInfo}}
+	for i, saved := range m.savedSearches {
+		key := "  "
+		if i < 9 {
+			key = fmt.Sprintf("g%d", i+1)
+		}
+		lines = append(lines, panelLine{text: fmt.Sprintf("%s %s", key, saved.Name), kind: panelLineBody})
+	}
+	return lines
 }
 
 // Middle panel: file list (what your old View used to show).
@@ -435,6 +456,44 @@ func (m Model) metaPopupContentLines(width int) []string {
 		popupLines = append(popupLines, fmt.Sprintf("%s%s: %s", prefix, fieldLabel, value))
 	}
 
+	if rows := customFieldRows(m.customFields(), m.metaDraft); len(rows) > 0 {
+		popupLines = append(popupLines, "", "Custom fields:")
+		for _, row := range rows {
+			value := strings.TrimSpace(row[1])
+			if value == "" {
+				value = "(empty)"
+			}
+			popupLines = append(popupLines, fmt.Sprintf("  %s: %s", row[0], value))
+		}
+	}
+
+	if len(m.metaDraft.Identifiers) > 0 {
+		popupLines = append(popupLines, "", "Identifiers:")
+		for _, id := range m.metaDraft.Identifiers {
+			popupLines = append(popupLines, "  "+id.String())
+		}
+	}
+
+	popupLines = append(popupLines, "", "Reading:")
+	popupLines = append(popupLines, "  Rating: "+formatRating(m.metaDraft.Rating))
+	popupLines = append(popupLines, "  Priority: "+priorityLabel(m.metaDraft.Priority))
+	popupLines = append(popupLines, "  Progress: "+formatProgress(m.metaDraft))
+	if sum := m.metaReading; sum.Sessions > 0 {
+		popupLines = append(popupLines, fmt.Sprintf("  Sessions: %d, %s total, last %s",
+			sum.Sessions, formatReadingDuration(sum.Total), sum.LastAt.Local().Format("2006-01-02 15:04")))
+	} else {
+		popupLines = append(popupLines, "  Sessions: none yet")
+	}
+
+	popupLines = append(popupLines, "", fmt.Sprintf("Attachments: %d", len(m.metaAttachments)))
+	if len(m.metaAttachments) == 0 {
+		popupLines = append(popupLines, "  (none - :attach <file|url> adds one)")
+	} else {
+		popupLines = append(popupLines, attachmentLines(m.metaAttachments)...)
+	}
+
+	popupLines = append(popupLines, citationPanelLines(m.metaCitations)...)
+
 	popupLines = append(popupLines, "", "Note preview:")
 	note := strings.TrimSpace(m.currentNote)
 	if note == "" {
@@ -450,6 +509,7 @@ func (m Model) metaPopupContentLines(width int) []string {
 		"Use ↑/↓ or PgUp/PgDn to scroll fields.",
 		"Press 'e' to edit fields in your editor.",
 		"Press 'n' to edit the note in your editor.",
+		"Press 1-9 to open an attachment.",
 		"Press 'Esc' or 'q' to close.",
 	)
 
@@ -688,7 +748,10 @@ func (m Model) renderSearchResultsView() string {
 		b.WriteString(m.styles.Tree.Info.Render(padStyledLine(line, width)) + "\n")
 	}
 
-	controls := "Controls: j/k move • PgUp/PgDn page • Enter open • Esc/q close • / search again"
+	controls := fmt.Sprintf("Controls: j/k move • PgUp/PgDn page • Enter open • o order (%s) • Esc/q close • / search again", m.searchOrder.label())
+	if m.duplicatesView {
+		controls = "Controls: j/k move • Enter open • m merge group into selected • Esc/q close"
+	}
 	b.WriteString(m.styles.Separator.Render(padStyledLine(controls, width)) + "\n")
 
 	listLines, title := m.searchResultListLines(listHeight)
@@ -820,6 +883,9 @@ func (m Model) searchResultListLines(visible int) ([]panelLine, string) {
 			display = fmt.Sprintf("%s (%s)", title, year)
 		}
 		info := []string{}
+		if match.Group > 0 {
+			info = append(info, fmt.Sprintf("group %d", match.Group))
+		}
 		if match.MatchCount > 0 {
 			hits := "hit"
 			if match.MatchCount > 1 {
@@ -962,6 +1028,10 @@ func (m Model) metadataPreviewLines(width int) []string {
 		fmt.Sprintf("  To-read  : %s (%s)", metadataStatusBadge(md.ToRead, m.toReadIcon()), boolLabel(md.ToRead)))
 	lines = append(lines,
 		fmt.Sprintf("  Reading  : %s %s", m.readingStateIcon(md.ReadingState), readingStateLabel(md.ReadingState)))
+	lines = append(lines,
+		fmt.Sprintf("  Rating   : %s", formatRating(md.Rating)))
+	lines = append(lines,
+		fmt.Sprintf("  Priority : %s", priorityLabel(md.Priority)))
 	lines = append(lines, "")
 	noteWidth := contentWidth - 2 // account for indent
 	if noteWidth < 10 {
@@ -1237,8 +1307,11 @@ func (m Model) searchResultDetailLines(limit, width int) []string {
 	lines := []string{
 		fmt.Sprintf("File: %s", match.Path),
 		fmt.Sprintf("Matches: %d", match.MatchCount),
-		"",
 	}
+	if match.Score > 0 {
+		lines = append(lines, fmt.Sprintf("Score: %.2f", match.Score))
+	}
+	lines = append(lines, "")
 	if match.Mode == searchModeContent {
 		lines = append(lines, "Snippets:")
 		lines = append(lines, formatContentSnippets(match.Snippets)...)
@@ -1247,6 +1320,10 @@ func (m Model) searchResultDetailLines(limit, width int) []string {
 		for _, snippet := range match.Snippets {
 			lines = append(lines, "  "+snippet)
 		}
+		if len(match.Excerpts) > 0 {
+			lines = append(lines, "", "Snippets:")
+			lines = append(lines, formatContentSnippets(match.Excerpts)...)
+		}
 	}
 	lines = trimLinesToWidth(lines, width)
 	if limit > 0 && len(lines) > limit {
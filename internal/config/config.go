@@ -34,9 +34,20 @@ type Config struct {
 	ThemePath           string `json:"theme_path,omitempty"`
 	EnableMouse         bool   `json:"enable_mouse"`
 
+	// CustomFields declares user-defined per-paper fields.
+	CustomFields []CustomField `json:"custom_fields,omitempty"`
+
+	// Libraries holds named library profiles besides the default one above.
+	Libraries map[string]Library `json:"libraries,omitempty"`
+
 	// Runtime-only fields (not persisted)
-	ConfigPath    string `json:"-"`
-	NeedsConfirm  bool   `json:"-"`
+	ConfigPath   string `json:"-"`
+	NeedsConfirm bool   `json:"-"`
+	Library      string `json:"-"` // active profile; empty for the default library
+
+	// base is the config as loaded when this one is a library profile or
+	// --root override of it.
+	base *Config
 }
 
 const (
@@ -298,7 +309,7 @@ func Save(cfg *Config) error {
 	if err := ensureNotesDirExists(cfg); err != nil {
 		return err
 	}
-	return writeConfig(path, cfg)
+	return writeConfig(path, cfg.persistent())
 }
 
 func (c *Config) ensureDefaults() (bool, error) {
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Custom field types accepted in CustomField.Type.
+const (
+	FieldTypeText   = "text"
+	FieldTypeNumber = "number"
+	FieldTypeDate   = "date"
+	FieldTypeEnum   = "enum"
+)
+
+// fieldDateLayouts are the accepted date formats, most precise first.
+var fieldDateLayouts = []string{"2006-01-02", "2006-01", "2006"}
+
+// CustomField is a user-defined per-paper field, e.g.
+//
+//	{"name": "venue rank", "type": "enum", "options": ["A*", "A", "B"]}
+type CustomField struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type,omitempty"`
+	Options []string `json:"options,omitempty"`
+}
+
+// Kind returns the field type, defaulting to text.
+func (f CustomField) Kind() string {
+	switch t := strings.ToLower(strings.TrimSpace(f.Type)); t {
+	case FieldTypeNumber, FieldTypeDate, FieldTypeEnum:
+		return t
+	default:
+		return FieldTypeText
+	}
+}
+
+// Normalize validates value against the field type and returns its canonical
+// form: trimmed text, a plain number, an ISO date or the option as declared.
+// Empty values are always valid and mean "unset".
+func (f CustomField) Normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	switch f.Kind() {
+	case FieldTypeNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", fmt.Errorf("field %q expects a number, got %q", f.Name, value)
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	case FieldTypeDate:
+		if _, err := ParseFieldDate(value); err != nil {
+			return "", fmt.Errorf("field %q expects a date (YYYY-MM-DD), got %q", f.Name, value)
+		}
+		return value, nil
+	case FieldTypeEnum:
+		for _, opt := range f.Options {
+			if strings.EqualFold(strings.TrimSpace(opt), value) {
+				return strings.TrimSpace(opt), nil
+			}
+		}
+		return "", fmt.Errorf("field %q expects one of %s, got %q", f.Name, strings.Join(f.Options, ", "), value)
+	default:
+		return value, nil
+	}
+}
+
+// ParseFieldDate parses a date field value in one of the accepted layouts.
+func ParseFieldDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	var lastErr error
+	for _, layout := range fieldDateLayouts {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, nil
+		}
+		lastErr = err
+	}
+	return time.Time{}, lastErr
+}
+
+// CustomField looks up a declared custom field by name (case-insensitive).
+func (c *Config) CustomField(name string) (CustomField, bool) {
+	if c == nil {
+		return CustomField{}, false
+	}
+	name = strings.TrimSpace(name)
+	for _, f := range c.CustomFields {
+		if strings.EqualFold(strings.TrimSpace(f.Name), name) {
+			return f, true
+		}
+	}
+	return CustomField{}, false
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultLibrary names the library described by the top-level watch_dir and
+// meta_dir, so it can be switched back to like any profile.
+const DefaultLibrary = "default"
+
+// Library is a named library profile. Unset directories are derived like the
+// top-level ones: the database and notes live in meta_dir (by default
+// <meta_dir>/libraries/<name>), the helper folders in watch_dir.
+type Library struct {
+	WatchDir          string `json:"watch_dir"`
+	MetaDir           string `json:"meta_dir,omitempty"`
+	NotesDir          string `json:"notes_dir,omitempty"`
+	RecentlyAddedDir  string `json:"recent_dir,omitempty"`
+	RecentlyOpenedDir string `json:"recently_opened_dir,omitempty"`
+}
+
+// DBPath returns the metadata database of the library c describes.
+func (c *Config) DBPath() string {
+	return filepath.Join(c.MetaDir, "metadata.db")
+}
+
+// ActiveLibrary returns the name of the library c describes.
+func (c *Config) ActiveLibrary() string {
+	if c.Library == "" {
+		return DefaultLibrary
+	}
+	return c.Library
+}
+
+// LibraryNames lists the default library followed by the profiles by name.
+func (c *Config) LibraryNames() []string {
+	base := c.root()
+	names := make([]string, 0, len(base.Libraries)+1)
+	for name := range base.Libraries {
+		if !strings.EqualFold(name, DefaultLibrary) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DefaultLibrary}, names...)
+}
+
+// ForLibrary returns the config for the library profile name. Settings other
+// than the library directories are shared with c. The default library
+// returns the config as loaded.
+func (c *Config) ForLibrary(name string) (*Config, error) {
+	base := c.root()
+	name = strings.TrimSpace(name)
+	if name == "" || strings.EqualFold(name, DefaultLibrary) {
+		return base, nil
+	}
+	profile, ok := base.Libraries[name]
+	if !ok {
+		for key, p := range base.Libraries {
+			if strings.EqualFold(key, name) {
+				name, profile, ok = key, p, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown library %q (have: %s)", name, strings.Join(c.LibraryNames(), ", "))
+	}
+	if strings.TrimSpace(profile.WatchDir) == "" {
+		return nil, fmt.Errorf("library %q has no watch_dir", name)
+	}
+
+	lib := *base
+	lib.base = base
+	lib.Library = name
+	lib.NeedsConfirm = false
+	lib.WatchDir = strings.TrimSpace(profile.WatchDir)
+	lib.MetaDir = strings.TrimSpace(profile.MetaDir)
+	if lib.MetaDir == "" {
+		lib.MetaDir = filepath.Join(base.MetaDir, "libraries", name)
+	}
+	lib.NotesDir = strings.TrimSpace(profile.NotesDir)
+	if lib.NotesDir == "" {
+		lib.NotesDir = defaultNotesDir(lib.MetaDir)
+	}
+	lib.RecentlyAddedDir = strings.TrimSpace(profile.RecentlyAddedDir)
+	if lib.RecentlyAddedDir == "" {
+		lib.RecentlyAddedDir = filepath.Join(lib.WatchDir, defaultRecentlyAddedDirName)
+	}
+	lib.RecentlyOpenedDir = strings.TrimSpace(profile.RecentlyOpenedDir)
+	if lib.RecentlyOpenedDir == "" {
+		lib.RecentlyOpenedDir = filepath.Join(lib.WatchDir, defaultRecentlyOpenedName)
+	}
+	if err := os.MkdirAll(lib.MetaDir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := ensureNotesDirExists(&lib); err != nil {
+		return nil, err
+	}
+	return &lib, nil
+}
+
+// WithRoot returns a copy of c rooted at root instead of watch_dir. Helper
+// folders kept at their default place inside watch_dir move along; folders
+// configured elsewhere stay put.
+func (c *Config) WithRoot(root string) *Config {
+	root = strings.TrimSpace(root)
+	if root == "" || filepath.Clean(root) == filepath.Clean(c.WatchDir) {
+		return c
+	}
+	derived := *c
+	derived.base = c.root()
+	derived.WatchDir = root
+	if followsWatchDir(c.RecentlyAddedDir, c.WatchDir, defaultRecentlyAddedDirName, legacyRecentDirName, legacyRecentlyAddedDirName) {
+		derived.RecentlyAddedDir = filepath.Join(root, defaultRecentlyAddedDirName)
+	}
+	if followsWatchDir(c.RecentlyOpenedDir, c.WatchDir, defaultRecentlyOpenedName, legacyRecentlyOpenedName) {
+		derived.RecentlyOpenedDir = filepath.Join(root, defaultRecentlyOpenedName)
+	}
+	return &derived
+}
+
+// followsWatchDir reports whether dir is unset or one of the default helper
+// folder names directly inside watch.
+func followsWatchDir(dir, watch string, names ...string) bool {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return true
+	}
+	for _, name := range names {
+		if filepath.Clean(dir) == filepath.Join(watch, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// root returns the config as loaded from disk, before any library or --root
+// override was applied.
+func (c *Config) root() *Config {
+	if c.base != nil {
+		return c.base
+	}
+	return c
+}
+
+// persistent returns what Save writes for c. A derived config hands the
+// shared settings it may have changed to its base and writes that, so library
+// directories and --root overrides never end up in the file.
+func (c *Config) persistent() *Config {
+	if c.base == nil {
+		return c
+	}
+	c.base.Editor = c.Editor
+	c.base.PDFViewer = c.PDFViewer
+	c.base.ThemePath = c.ThemePath
+	c.base.EnableMouse = c.EnableMouse
+	c.base.RecentlyAddedDays = c.RecentlyAddedDays
+	c.base.RecentlyOpenedLimit = c.RecentlyOpenedLimit
+	c.base.CustomFields = c.CustomFields
+	return c.base
+}
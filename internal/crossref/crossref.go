@@ -16,10 +16,20 @@ type Metadata struct {
 	DOI       string
 	Title     string
 	Authors   []string
+	People    []Person
 	Published string
 	Year      int
 	URL       string
 	Abstract  string
+	// References lists the works this one cites, as deposited by the
+	// publisher. Many entries only have the unstructured citation text.
+	References []Reference
+}
+
+// Reference is one entry of a work's reference list.
+type Reference struct {
+	DOI          string
+	Unstructured string
 }
 
 const userAgent = "gorae/0.1 (https://github.com/Han8931/gorae)"
@@ -58,28 +68,43 @@ func Fetch(ctx context.Context, doi string) (*Metadata, error) {
 
 	msg := payload.Message
 	meta := &Metadata{
-		DOI:       strings.TrimSpace(firstNonEmpty(msg.DOI, value)),
-		Title:     strings.TrimSpace(firstFrom(msg.Title)),
-		Authors:   parseAuthors(msg.Author),
-		Published: strings.TrimSpace(firstFrom(msg.ContainerTitle)),
-		Year:      pickYear(msg.PublishedPrint, msg.PublishedOnline, msg.Issued),
-		URL:       strings.TrimSpace(msg.URL),
-		Abstract:  cleanAbstract(msg.Abstract),
+		DOI:        strings.TrimSpace(firstNonEmpty(msg.DOI, value)),
+		Title:      strings.TrimSpace(firstFrom(msg.Title)),
+		Authors:    parseAuthors(msg.Author),
+		People:     parsePeople(msg.Author),
+		Published:  strings.TrimSpace(firstFrom(msg.ContainerTitle)),
+		Year:       pickYear(msg.PublishedPrint, msg.PublishedOnline, msg.Issued),
+		URL:        strings.TrimSpace(msg.URL),
+		Abstract:   cleanAbstract(msg.Abstract),
+		References: parseReferences(msg.Reference),
 	}
 	return meta, nil
 }
 
+// Person is a contributor with the name parts Crossref reports separately.
+type Person struct {
+	Given  string
+	Family string
+	Name   string
+}
+
 type workMessage struct {
-	Title           []string  `json:"title"`
-	Subtitle        []string  `json:"subtitle"`
-	Author          []author  `json:"author"`
-	ContainerTitle  []string  `json:"container-title"`
-	PublishedPrint  dateParts `json:"published-print"`
-	PublishedOnline dateParts `json:"published-online"`
-	Issued          dateParts `json:"issued"`
-	URL             string    `json:"URL"`
-	DOI             string    `json:"DOI"`
-	Abstract        string    `json:"abstract"`
+	Title           []string    `json:"title"`
+	Subtitle        []string    `json:"subtitle"`
+	Author          []author    `json:"author"`
+	ContainerTitle  []string    `json:"container-title"`
+	PublishedPrint  dateParts   `json:"published-print"`
+	PublishedOnline dateParts   `json:"published-online"`
+	Issued          dateParts   `json:"issued"`
+	URL             string      `json:"URL"`
+	DOI             string      `json:"DOI"`
+	Abstract        string      `json:"abstract"`
+	Reference       []reference `json:"reference"`
+}
+
+type reference struct {
+	DOI          string `json:"DOI"`
+	Unstructured string `json:"unstructured"`
 }
 
 type author struct {
@@ -137,6 +162,37 @@ func parseAuthors(items []author) []string {
 	return names
 }
 
+func parseReferences(items []reference) []Reference {
+	refs := make([]Reference, 0, len(items))
+	for _, r := range items {
+		ref := Reference{
+			DOI:          strings.TrimSpace(r.DOI),
+			Unstructured: strings.TrimSpace(r.Unstructured),
+		}
+		if ref.DOI == "" && ref.Unstructured == "" {
+			continue
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+func parsePeople(items []author) []Person {
+	people := make([]Person, 0, len(items))
+	for _, a := range items {
+		p := Person{
+			Given:  strings.TrimSpace(a.Given),
+			Family: strings.TrimSpace(a.Family),
+			Name:   strings.TrimSpace(a.Name),
+		}
+		if p.Given == "" && p.Family == "" && p.Name == "" {
+			continue
+		}
+		people = append(people, p)
+	}
+	return people
+}
+
 func pickYear(parts ...dateParts) int {
 	for _, p := range parts {
 		if year := datePartsYear(p); year > 0 {
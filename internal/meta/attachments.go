@@ -0,0 +1,127 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Attachment kinds: a file on disk or a web link.
+const (
+	AttachmentFile = "file"
+	AttachmentURL  = "url"
+)
+
+// Attachment is a supplementary file or link owned by a paper: slides, a
+// supplement, a code archive, a poster.
+type Attachment struct {
+	Owner   string
+	Target  string // absolute path for files
+	Kind    string
+	Label   string
+	AddedAt time.Time
+}
+
+// AttachmentKind guesses the kind of target from its form.
+func AttachmentKind(target string) string {
+	lower := strings.ToLower(strings.TrimSpace(target))
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+		return AttachmentURL
+	}
+	return AttachmentFile
+}
+
+// AddAttachment attaches a to its owner, replacing the label when the target
+// is already attached. A bare metadata row is created for owners gorae has
+// not seen yet.
+func (s *Store) AddAttachment(ctx context.Context, a Attachment) error {
+	a.Target = strings.TrimSpace(a.Target)
+	if a.Owner == "" || a.Target == "" {
+		return fmt.Errorf("attachment needs an owner and a target")
+	}
+	if a.Target == a.Owner {
+		return fmt.Errorf("a paper cannot be attached to itself")
+	}
+	if a.Kind == "" {
+		a.Kind = AttachmentKind(a.Target)
+	}
+	if a.AddedAt.IsZero() {
+		a.AddedAt = time.Now()
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO metadata (path, reading_state, added_at) VALUES (?, ?, ?) ON CONFLICT(path) DO NOTHING`,
+		a.Owner, defaultReadingState, a.AddedAt.Unix(),
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO attachments (owner, target, kind, label, added_at) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(owner, target) DO UPDATE SET label = excluded.label`,
+		a.Owner, a.Target, a.Kind, strings.TrimSpace(a.Label), a.AddedAt.Unix(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveAttachment detaches target from owner. The file itself is left alone.
+func (s *Store) RemoveAttachment(ctx context.Context, owner, target string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM attachments WHERE owner = ? AND target = ?`, owner, target)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Attachments returns the attachments of owner in the order they were added.
+func (s *Store) Attachments(ctx context.Context, owner string) ([]Attachment, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT owner, target, kind, IFNULL(label, ''), COALESCE(added_at, 0)
+  FROM attachments
+ WHERE owner = ?
+ ORDER BY added_at, id`, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]Attachment, 0)
+	for rows.Next() {
+		var (
+			a       Attachment
+			addedAt int64
+		)
+		if err := rows.Scan(&a.Owner, &a.Target, &a.Kind, &a.Label, &addedAt); err != nil {
+			return nil, err
+		}
+		if addedAt > 0 {
+			a.AddedAt = time.Unix(addedAt, 0).UTC()
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+func migrateAttachments(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS attachments (
+  id       INTEGER PRIMARY KEY,
+  owner    TEXT NOT NULL REFERENCES metadata(path) ON UPDATE CASCADE ON DELETE CASCADE,
+  target   TEXT NOT NULL,
+  kind     TEXT NOT NULL,
+  label    TEXT,
+  added_at INTEGER,
+  UNIQUE (owner, target)
+);
+CREATE INDEX IF NOT EXISTS attachments_target ON attachments(target);
+`)
+	return err
+}
@@ -0,0 +1,66 @@
+package meta_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestAttachmentsFollowMoves(t *testing.T) {
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	owner := "/lib/nlp/paper.pdf"
+	for _, a := range []meta.Attachment{
+		{Owner: owner, Target: "/lib/nlp/slides.pdf", Label: "Slides"},
+		{Owner: owner, Target: "https://github.com/example/code"},
+		{Owner: owner, Target: "/lib/nlp/slides.pdf", Label: "Talk slides"},
+	} {
+		if err := store.AddAttachment(ctx, a); err != nil {
+			t.Fatalf("attach %s: %v", a.Target, err)
+		}
+	}
+	if err := store.AddAttachment(ctx, meta.Attachment{Owner: owner, Target: owner}); err == nil {
+		t.Fatalf("expected self-attachment to fail")
+	}
+
+	list, err := store.Attachments(ctx, owner)
+	if err != nil {
+		t.Fatalf("attachments: %v", err)
+	}
+	if len(list) != 2 || list[0].Label != "Talk slides" || list[0].Kind != meta.AttachmentFile || list[1].Kind != meta.AttachmentURL {
+		t.Fatalf("unexpected attachments: %+v", list)
+	}
+
+	// Renaming the slides updates the target; renaming the owner carries the rows.
+	if err := store.MovePath(ctx, "/lib/nlp/slides.pdf", "/lib/nlp/talk.pdf"); err != nil {
+		t.Fatalf("move attachment: %v", err)
+	}
+	if err := store.MoveTree(ctx, "/lib/nlp", "/lib/language"); err != nil {
+		t.Fatalf("move tree: %v", err)
+	}
+	owner = "/lib/language/paper.pdf"
+	list, err = store.Attachments(ctx, owner)
+	if err != nil {
+		t.Fatalf("attachments after move: %v", err)
+	}
+	if len(list) != 2 || list[0].Target != "/lib/language/talk.pdf" || list[1].Target != "https://github.com/example/code" {
+		t.Fatalf("unexpected attachments after move: %+v", list)
+	}
+
+	if ok, err := store.RemoveAttachment(ctx, owner, "https://github.com/example/code"); err != nil || !ok {
+		t.Fatalf("remove: %v %v", ok, err)
+	}
+	if err := store.DeletePath(ctx, owner); err != nil {
+		t.Fatalf("delete owner: %v", err)
+	}
+	if list, err := store.Attachments(ctx, owner); err != nil || len(list) != 0 {
+		t.Fatalf("expected attachments to go with their owner, got %+v (%v)", list, err)
+	}
+}
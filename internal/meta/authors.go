@@ -0,0 +1,337 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"unicode"
+)
+
+// Author is one ordered contributor of a paper.
+type Author struct {
+	ID     int64
+	Given  string
+	Family string
+	Full   string
+}
+
+// AuthorSummary is an author together with the number of papers linked to it.
+type AuthorSummary struct {
+	Author
+	Papers int
+}
+
+// NewAuthor builds an Author from its name parts, filling Full when empty.
+func NewAuthor(given, family, full string) Author {
+	given = normalizeName(given)
+	family = normalizeName(family)
+	full = normalizeName(full)
+	if full == "" {
+		full = strings.TrimSpace(given + " " + family)
+	}
+	if given == "" && family == "" && full != "" {
+		given, family = splitFullName(full)
+	}
+	return Author{Given: given, Family: family, Full: full}
+}
+
+// ParseAuthors splits a free-text author list into structured authors. It
+// understands BibTeX style "A and B", semicolon lists and the comma-joined
+// form gorae writes itself; "Family, Given" is recognised inside " and " or
+// ";" separated lists and as a single author on its own.
+func ParseAuthors(raw string) []Author {
+	raw = normalizeName(raw)
+	if raw == "" {
+		return nil
+	}
+	var parts []string
+	lower := strings.ToLower(raw)
+	switch {
+	case strings.Contains(lower, " and "):
+		parts = splitFold(raw, " and ")
+	case strings.Contains(raw, ";"):
+		parts = strings.Split(raw, ";")
+	case isInvertedName(raw):
+		parts = []string{raw}
+	default:
+		parts = strings.Split(raw, ",")
+		return buildAuthors(parts, false)
+	}
+	return buildAuthors(parts, true)
+}
+
+// nameParticles start family names such as "van der Berg".
+var nameParticles = []string{"van", "von", "der", "den", "de", "del", "della", "di", "da", "du", "le", "la"}
+
+// isInvertedName reports whether raw is unambiguously one "Family, Given"
+// name rather than two comma-joined names: it has a single comma, and either
+// a one-word family name before given names ending in an initial, as in
+// "Knuth, Donald E.", or a particle-led family name, as in
+// "Van Gogh, Vincent". Anything else, such as "Knuth, Lamport" or
+// "Ada Lovelace, J. Smith", is read as two authors, the form gorae writes.
+func isInvertedName(raw string) bool {
+	if strings.Count(raw, ",") != 1 {
+		return false
+	}
+	family, given, _ := strings.Cut(raw, ",")
+	familyWords, givenWords := strings.Fields(family), strings.Fields(given)
+	if len(familyWords) == 0 || len(givenWords) == 0 {
+		return false
+	}
+	if len(familyWords) == 1 {
+		return isInitial(givenWords[len(givenWords)-1])
+	}
+	for _, particle := range nameParticles {
+		if strings.EqualFold(familyWords[0], particle) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInitial reports whether word abbreviates given names, like "D.", "DE",
+// "J.-P." or "D.E.".
+func isInitial(word string) bool {
+	letters := 0
+	for _, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			letters++
+		case r == '.' || r == '-':
+		default:
+			return false
+		}
+	}
+	return letters > 0 && (letters <= 2 || strings.Contains(word, "."))
+}
+
+func buildAuthors(parts []string, allowInverted bool) []Author {
+	out := make([]Author, 0, len(parts))
+	for _, part := range parts {
+		part = normalizeName(part)
+		if part == "" {
+			continue
+		}
+		if allowInverted {
+			if idx := strings.Index(part, ","); idx >= 0 {
+				family := normalizeName(part[:idx])
+				given := normalizeName(part[idx+1:])
+				out = append(out, NewAuthor(given, family, ""))
+				continue
+			}
+		}
+		out = append(out, NewAuthor("", "", part))
+	}
+	return out
+}
+
+// FormatAuthors joins authors the same way gorae stores Metadata.Author.
+func FormatAuthors(authors []Author) string {
+	names := make([]string, 0, len(authors))
+	for _, a := range authors {
+		if name := strings.TrimSpace(a.Full); name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func splitFold(s, sep string) []string {
+	lower := strings.ToLower(s)
+	var out []string
+	for {
+		idx := strings.Index(lower, sep)
+		if idx < 0 {
+			return append(out, s)
+		}
+		out = append(out, s[:idx])
+		s = s[idx+len(sep):]
+		lower = lower[idx+len(sep):]
+	}
+}
+
+func splitFullName(full string) (string, string) {
+	fields := strings.Fields(full)
+	switch len(fields) {
+	case 0:
+		return "", ""
+	case 1:
+		return "", fields[0]
+	default:
+		return strings.Join(fields[:len(fields)-1], " "), fields[len(fields)-1]
+	}
+}
+
+func normalizeName(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func authorNameKey(a Author) string {
+	return strings.ToLower(normalizeName(a.Full))
+}
+
+func (s *Store) loadAuthors(ctx context.Context, q querier, path string) ([]Author, error) {
+	rows, err := q.QueryContext(ctx, `
+SELECT a.id, IFNULL(a.given, ''), IFNULL(a.family, ''), IFNULL(a.full_name, '')
+  FROM paper_authors pa
+  JOIN authors a ON a.id = pa.author_id
+ WHERE pa.path = ?
+ ORDER BY pa.position`, path)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Author
+	for rows.Next() {
+		var a Author
+		if err := rows.Scan(&a.ID, &a.Given, &a.Family, &a.Full); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// writeAuthors replaces the author links of path. Authors are shared between
+// papers and deduplicated by their case-folded full name.
+func writeAuthors(ctx context.Context, tx *sql.Tx, path string, authors []Author) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM paper_authors WHERE path = ?`, path); err != nil {
+		return err
+	}
+	position := 0
+	for _, a := range authors {
+		key := authorNameKey(a)
+		if key == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO authors (given, family, full_name, name_key) VALUES (?, ?, ?, ?)
+ON CONFLICT(name_key) DO UPDATE SET
+  given  = CASE WHEN IFNULL(authors.given, '') = '' THEN excluded.given ELSE authors.given END,
+  family = CASE WHEN IFNULL(authors.family, '') = '' THEN excluded.family ELSE authors.family END
+`, a.Given, a.Family, a.Full, key); err != nil {
+			return err
+		}
+		var id int64
+		if err := tx.QueryRowContext(ctx, `SELECT id FROM authors WHERE name_key = ?`, key).Scan(&id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO paper_authors (path, author_id, position) VALUES (?, ?, ?)`,
+			path, id, position,
+		); err != nil {
+			return err
+		}
+		position++
+	}
+	return nil
+}
+
+// syncAuthors keeps the structured author links consistent with md.Author.
+// Structured input wins when it matches the text; otherwise the text is the
+// source of truth and gets re-parsed.
+func (s *Store) syncAuthors(ctx context.Context, tx *sql.Tx, md *Metadata) error {
+	text := normalizeName(md.Author)
+	if len(md.Authors) > 0 && (text == "" || FormatAuthors(md.Authors) == text) {
+		return writeAuthors(ctx, tx, md.Path, md.Authors)
+	}
+	existing, err := s.loadAuthors(ctx, tx, md.Path)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 && FormatAuthors(existing) == text {
+		return nil
+	}
+	if text == "" && len(existing) == 0 {
+		return nil
+	}
+	return writeAuthors(ctx, tx, md.Path, ParseAuthors(text))
+}
+
+// ListAuthors returns every author linked to at least one paper, with the
+// number of linked papers, ordered by family name.
+func (s *Store) ListAuthors(ctx context.Context) ([]AuthorSummary, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT a.id, IFNULL(a.given, ''), IFNULL(a.family, ''), IFNULL(a.full_name, ''), COUNT(pa.path)
+  FROM authors a
+  JOIN paper_authors pa ON pa.author_id = a.id
+ GROUP BY a.id
+ ORDER BY LOWER(a.family), LOWER(a.given), a.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := make([]AuthorSummary, 0)
+	for rows.Next() {
+		var sum AuthorSummary
+		if err := rows.Scan(&sum.ID, &sum.Given, &sum.Family, &sum.Full, &sum.Papers); err != nil {
+			return nil, err
+		}
+		results = append(results, sum)
+	}
+	return results, rows.Err()
+}
+
+// ListByAuthor returns the papers whose author list contains an author whose
+// full or family name matches name (case-insensitive substring).
+func (s *Store) ListByAuthor(ctx context.Context, name string) ([]Metadata, error) {
+	pattern := "%" + escapeLike(strings.ToLower(normalizeName(name))) + "%"
+	return s.listWhere(ctx, `
+ WHERE path IN (
+   SELECT pa.path
+     FROM paper_authors pa
+     JOIN authors a ON a.id = pa.author_id
+    WHERE a.name_key LIKE ? ESCAPE '\' OR LOWER(IFNULL(a.family, '')) LIKE ? ESCAPE '\'
+ )
+ ORDER BY LOWER(title), path`, pattern, pattern)
+}
+
+// migrateAuthors introduces the normalized authors tables and backfills them
+// from the free-text author column.
+func migrateAuthors(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS authors (
+  id        INTEGER PRIMARY KEY,
+  given     TEXT,
+  family    TEXT,
+  full_name TEXT NOT NULL,
+  name_key  TEXT NOT NULL UNIQUE
+);
+CREATE TABLE IF NOT EXISTS paper_authors (
+  path      TEXT NOT NULL REFERENCES metadata(path) ON UPDATE CASCADE ON DELETE CASCADE,
+  author_id INTEGER NOT NULL REFERENCES authors(id) ON DELETE CASCADE,
+  position  INTEGER NOT NULL,
+  PRIMARY KEY (path, position)
+);
+CREATE INDEX IF NOT EXISTS paper_authors_author ON paper_authors(author_id);
+`); err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT path, author FROM metadata WHERE TRIM(IFNULL(author, '')) <> ''`)
+	if err != nil {
+		return err
+	}
+	type pending struct {
+		path   string
+		author string
+	}
+	var backlog []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.path, &p.author); err != nil {
+			rows.Close()
+			return err
+		}
+		backlog = append(backlog, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, p := range backlog {
+		if err := writeAuthors(ctx, tx, p.path, ParseAuthors(p.author)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package meta_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestParseAuthors(t *testing.T) {
+	cases := []struct {
+		raw    string
+		full   []string
+		family []string
+	}{
+		{"Ashish Vaswani, Noam Shazeer", []string{"Ashish Vaswani", "Noam Shazeer"}, []string{"Vaswani", "Shazeer"}},
+		{"Vaswani, Ashish and Shazeer, Noam", []string{"Ashish Vaswani", "Noam Shazeer"}, []string{"Vaswani", "Shazeer"}},
+		{"Knuth; Lamport, Leslie", []string{"Knuth", "Leslie Lamport"}, []string{"Knuth", "Lamport"}},
+		{"Lamport, L.", []string{"L. Lamport"}, []string{"Lamport"}},
+		{"Knuth, Donald E.", []string{"Donald E. Knuth"}, []string{"Knuth"}},
+		{"van der Berg, Jan", []string{"Jan van der Berg"}, []string{"van der Berg"}},
+		{"Van Gogh, Vincent", []string{"Vincent Van Gogh"}, []string{"Van Gogh"}},
+		{"Knuth, Lamport", []string{"Knuth", "Lamport"}, []string{"Knuth", "Lamport"}},
+		{"Vaswani, Ashish", []string{"Vaswani", "Ashish"}, []string{"Vaswani", "Ashish"}},
+		{"Ada Lovelace, J. Smith", []string{"Ada Lovelace", "J. Smith"}, []string{"Lovelace", "Smith"}},
+		{"Ada Lovelace, Alan Turing, Grace Hopper", []string{"Ada Lovelace", "Alan Turing", "Grace Hopper"}, []string{"Lovelace", "Turing", "Hopper"}},
+		{"  ", nil, nil},
+	}
+	for _, tc := range cases {
+		got := meta.ParseAuthors(tc.raw)
+		if len(got) != len(tc.full) {
+			t.Fatalf("ParseAuthors(%q) = %+v, want %d authors", tc.raw, got, len(tc.full))
+		}
+		for i, a := range got {
+			if a.Full != tc.full[i] || a.Family != tc.family[i] {
+				t.Fatalf("ParseAuthors(%q)[%d] = %+v, want full=%q family=%q", tc.raw, i, a, tc.full[i], tc.family[i])
+			}
+		}
+	}
+}
+
+func TestAuthorsFollowMetadata(t *testing.T) {
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	if err := store.Upsert(ctx, &meta.Metadata{Path: "/lib/a.pdf", Title: "A", Author: "Ada Lovelace, Alan Turing"}); err != nil {
+		t.Fatalf("upsert a: %v", err)
+	}
+	if err := store.Upsert(ctx, &meta.Metadata{
+		Path:    "/lib/b.pdf",
+		Title:   "B",
+		Authors: []meta.Author{meta.NewAuthor("Alan", "Turing", "")},
+	}); err != nil {
+		t.Fatalf("upsert b: %v", err)
+	}
+
+	b, err := store.Get(ctx, "/lib/b.pdf")
+	if err != nil {
+		t.Fatalf("get b: %v", err)
+	}
+	if b.Author != "Alan Turing" || len(b.Authors) != 1 || b.Authors[0].Family != "Turing" {
+		t.Fatalf("unexpected authors for b: %q %+v", b.Author, b.Authors)
+	}
+
+	summaries, err := store.ListAuthors(ctx)
+	if err != nil {
+		t.Fatalf("list authors: %v", err)
+	}
+	counts := map[string]int{}
+	for _, s := range summaries {
+		counts[s.Full] = s.Papers
+	}
+	if counts["Alan Turing"] != 2 || counts["Ada Lovelace"] != 1 {
+		t.Fatalf("unexpected author counts: %v", counts)
+	}
+
+	if err := store.MovePath(ctx, "/lib/a.pdf", "/lib/moved.pdf"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	papers, err := store.ListByAuthor(ctx, "lovelace")
+	if err != nil {
+		t.Fatalf("list by author: %v", err)
+	}
+	if len(papers) != 1 || papers[0].Path != "/lib/moved.pdf" {
+		t.Fatalf("expected moved paper for lovelace, got %+v", papers)
+	}
+
+	if err := store.DeletePath(ctx, "/lib/moved.pdf"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	papers, err = store.ListByAuthor(ctx, "lovelace")
+	if err != nil {
+		t.Fatalf("list by author after delete: %v", err)
+	}
+	if len(papers) != 0 {
+		t.Fatalf("expected no papers after delete, got %+v", papers)
+	}
+
+	// Editing the free-text field re-derives the structured list.
+	b.Author = "Grace Hopper"
+	b.Authors = nil
+	if err := store.Upsert(ctx, b); err != nil {
+		t.Fatalf("upsert edited b: %v", err)
+	}
+	b, err = store.Get(ctx, "/lib/b.pdf")
+	if err != nil {
+		t.Fatalf("get edited b: %v", err)
+	}
+	if len(b.Authors) != 1 || b.Authors[0].Full != "Grace Hopper" {
+		t.Fatalf("expected re-parsed authors, got %+v", b.Authors)
+	}
+}
@@ -0,0 +1,188 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Reference kinds: the identifier scheme of a cited work.
+const (
+	RefDOI   = "doi"
+	RefArxiv = "arxiv"
+)
+
+// Where a paper's references came from. Each source is replaced on its own,
+// so a text scan does not wipe what Crossref reported and vice versa.
+const (
+	CitationSourceCrossref = "crossref"
+	CitationSourceText     = "text"
+)
+
+// arxivDOIPrefix is the DOI namespace arXiv registers its preprints under.
+const arxivDOIPrefix = "10.48550/arxiv."
+
+// Reference identifies a work cited by a paper. It need not be in the
+// library; References and CitedBy resolve identifiers to papers on demand.
+type Reference struct {
+	Kind string
+	ID   string
+}
+
+var arxivVersionSuffix = regexp.MustCompile(`v\d+$`)
+
+// NormalizeReference canonicalizes r so that the same work always has the
+// same identifier: DOIs are lowercased without resolver prefixes, arXiv IDs
+// lose their version, and arXiv DOIs become arXiv IDs. ok is false when
+// nothing is left.
+func NormalizeReference(r Reference) (Reference, bool) {
+	id := strings.ToLower(strings.TrimSpace(r.ID))
+	switch strings.ToLower(strings.TrimSpace(r.Kind)) {
+	case RefDOI:
+		id = NormalizeDOI(id)
+		if arxiv, ok := strings.CutPrefix(id, arxivDOIPrefix); ok {
+			return NormalizeReference(Reference{Kind: RefArxiv, ID: arxiv})
+		}
+		if !strings.HasPrefix(id, "10.") {
+			return Reference{}, false
+		}
+		return Reference{Kind: RefDOI, ID: id}, true
+	case RefArxiv:
+		id = strings.TrimPrefix(id, "arxiv:")
+		id = arxivVersionSuffix.ReplaceAllString(id, "")
+		if id == "" {
+			return Reference{}, false
+		}
+		return Reference{Kind: RefArxiv, ID: id}, true
+	}
+	return Reference{}, false
+}
+
+// NormalizeDOI strips resolver URLs and "doi:" prefixes and lowercases doi.
+func NormalizeDOI(doi string) string {
+	doi = strings.ToLower(strings.TrimSpace(doi))
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		doi = strings.TrimPrefix(doi, prefix)
+	}
+	return strings.TrimSpace(doi)
+}
+
+func containsReference(refs []Reference, r Reference) bool {
+	for _, existing := range refs {
+		if existing == r {
+			return true
+		}
+	}
+	return false
+}
+
+// SetReferences replaces the references of path that came from source. A
+// bare metadata row is created for files gorae has not seen yet.
+func (s *Store) SetReferences(ctx context.Context, path, source string, refs []Reference) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO metadata (path, reading_state, added_at) VALUES (?, ?, ?) ON CONFLICT(path) DO NOTHING`,
+		path, defaultReadingState, time.Now().Unix(),
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM citations WHERE citing = ? AND source = ?`, path, source); err != nil {
+		return err
+	}
+	// A paper's text usually carries its own DOI; that is not a citation.
+	self, err := scanMetadataRow(tx.QueryRowContext(ctx, `SELECT`+metadataSelectColumns+` FROM metadata WHERE path = ?`, path))
+	if err != nil {
+		return err
+	}
+	if self.Identifiers, err = loadIdentifiers(ctx, tx, path); err != nil {
+		return err
+	}
+	own := PaperIdentifiers(&self)
+	for _, raw := range refs {
+		r, ok := NormalizeReference(raw)
+		if !ok || containsReference(own, r) {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO citations (citing, kind, ref_id, source) VALUES (?, ?, ?, ?)`,
+			path, r.Kind, r.ID, source,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ReferenceCount returns how many distinct works path cites.
+func (s *Store) ReferenceCount(ctx context.Context, path string) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM (SELECT DISTINCT kind, ref_id FROM citations WHERE citing = ?)`, path,
+	).Scan(&n)
+	return n, err
+}
+
+// citedPaperMatch joins a citations row c to the papers m it identifies.
+const citedPaperMatch = `
+   (c.kind = 'doi' AND LOWER(TRIM(IFNULL(m.doi, ''))) = c.ref_id)
+OR m.path IN (SELECT i.path FROM identifiers i WHERE i.scheme = c.kind AND i.value = c.ref_id)
+OR (c.kind = 'arxiv' AND (
+      LOWER(TRIM(IFNULL(m.doi, ''))) = '` + arxivDOIPrefix + `' || c.ref_id
+   OR LOWER(IFNULL(m.url, '')) LIKE '%arxiv.org/abs/' || c.ref_id
+   OR LOWER(IFNULL(m.url, '')) LIKE '%arxiv.org/abs/' || c.ref_id || 'v%'))`
+
+// References returns the papers in the library that path cites, by title.
+func (s *Store) References(ctx context.Context, path string) ([]Metadata, error) {
+	return s.listWhere(ctx, `
+ WHERE path <> ?
+   AND path IN (
+   SELECT m.path
+     FROM citations c
+     JOIN metadata m ON `+citedPaperMatch+`
+    WHERE c.citing = ?
+ )
+ ORDER BY LOWER(IFNULL(title, '')), path`, path, path)
+}
+
+// CitedBy returns the papers in the library that cite path, by title.
+func (s *Store) CitedBy(ctx context.Context, path string) ([]Metadata, error) {
+	md, err := s.Get(ctx, path)
+	if err != nil || md == nil {
+		return nil, err
+	}
+	refs := PaperIdentifiers(md)
+	if len(refs) == 0 {
+		return []Metadata{}, nil
+	}
+	conds := make([]string, len(refs))
+	args := []any{path}
+	for i, r := range refs {
+		conds[i] = "(kind = ? AND ref_id = ?)"
+		args = append(args, r.Kind, r.ID)
+	}
+	return s.listWhere(ctx, `
+ WHERE path <> ?
+   AND path IN (SELECT citing FROM citations WHERE `+strings.Join(conds, " OR ")+`)
+ ORDER BY LOWER(IFNULL(title, '')), path`, args...)
+}
+
+func migrateCitations(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS citations (
+  citing TEXT NOT NULL REFERENCES metadata(path) ON UPDATE CASCADE ON DELETE CASCADE,
+  kind   TEXT NOT NULL,
+  ref_id TEXT NOT NULL,
+  source TEXT NOT NULL,
+  PRIMARY KEY (citing, kind, ref_id, source)
+);
+CREATE INDEX IF NOT EXISTS citations_ref ON citations(kind, ref_id);
+`)
+	return err
+}
@@ -0,0 +1,87 @@
+package meta_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestCitationsResolveWithinLibrary(t *testing.T) {
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	papers := []meta.Metadata{
+		{Path: "/lib/bert.pdf", Title: "BERT", DOI: "10.18653/v1/N19-1423"},
+		{Path: "/lib/attention.pdf", Title: "Attention Is All You Need", URL: "https://arxiv.org/abs/1706.03762v5"},
+		{Path: "/lib/resnet.pdf", Title: "Deep Residual Learning", DOI: "10.1109/CVPR.2016.90"},
+	}
+	for i := range papers {
+		if err := store.Upsert(ctx, &papers[i]); err != nil {
+			t.Fatalf("upsert %s: %v", papers[i].Path, err)
+		}
+	}
+
+	if err := store.SetReferences(ctx, "/lib/bert.pdf", meta.CitationSourceCrossref, []meta.Reference{
+		{Kind: meta.RefDOI, ID: "https://doi.org/10.48550/arXiv.1706.03762"},
+		{Kind: meta.RefDOI, ID: "10.1000/not-in-library"},
+		{Kind: meta.RefDOI, ID: "10.18653/v1/n19-1423"}, // self-citation
+	}); err != nil {
+		t.Fatalf("set crossref references: %v", err)
+	}
+	if err := store.SetReferences(ctx, "/lib/bert.pdf", meta.CitationSourceText, []meta.Reference{
+		{Kind: meta.RefDOI, ID: "10.1109/cvpr.2016.90"},
+		{Kind: meta.RefArxiv, ID: "arXiv:1706.03762v1"},
+	}); err != nil {
+		t.Fatalf("set text references: %v", err)
+	}
+
+	refs, err := store.References(ctx, "/lib/bert.pdf")
+	if err != nil {
+		t.Fatalf("references: %v", err)
+	}
+	if len(refs) != 2 || refs[0].Path != "/lib/attention.pdf" || refs[1].Path != "/lib/resnet.pdf" {
+		t.Fatalf("unexpected references: %+v", refs)
+	}
+	if n, err := store.ReferenceCount(ctx, "/lib/bert.pdf"); err != nil || n != 3 {
+		t.Fatalf("expected 3 distinct references besides itself, got %d (%v)", n, err)
+	}
+
+	for _, cited := range []string{"/lib/attention.pdf", "/lib/resnet.pdf"} {
+		citing, err := store.CitedBy(ctx, cited)
+		if err != nil {
+			t.Fatalf("cited by: %v", err)
+		}
+		if len(citing) != 1 || citing[0].Path != "/lib/bert.pdf" {
+			t.Fatalf("expected %s to be cited by bert, got %+v", cited, citing)
+		}
+	}
+
+	// Rescanning the text replaces only the text references.
+	if err := store.SetReferences(ctx, "/lib/bert.pdf", meta.CitationSourceText, nil); err != nil {
+		t.Fatalf("clear text references: %v", err)
+	}
+	refs, err = store.References(ctx, "/lib/bert.pdf")
+	if err != nil {
+		t.Fatalf("references: %v", err)
+	}
+	if len(refs) != 1 || refs[0].Path != "/lib/attention.pdf" {
+		t.Fatalf("expected only the crossref reference to remain, got %+v", refs)
+	}
+
+	if err := store.MovePath(ctx, "/lib/bert.pdf", "/lib/nlp/bert.pdf"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	citing, err := store.CitedBy(ctx, "/lib/attention.pdf")
+	if err != nil {
+		t.Fatalf("cited by: %v", err)
+	}
+	if len(citing) != 1 || citing[0].Path != "/lib/nlp/bert.pdf" {
+		t.Fatalf("expected citations to follow the move, got %+v", citing)
+	}
+}
@@ -0,0 +1,204 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrCollectionExists is returned when creating a collection whose name
+	// is already taken (names compare case-insensitively).
+	ErrCollectionExists = errors.New("collection already exists")
+	// ErrCollectionNotFound is returned when a named collection does not exist.
+	ErrCollectionNotFound = errors.New("collection not found")
+)
+
+// CollectionSummary is a named collection with its number of papers.
+type CollectionSummary struct {
+	Name   string
+	Papers int
+}
+
+// NormalizeCollectionName trims name and rejects names that cannot double as
+// a helper folder name.
+func NormalizeCollectionName(name string) (string, error) {
+	return normalizeFolderName("collection", name)
+}
+
+// normalizeFolderName trims the name of a kind of thing mirrored as a helper
+// folder and rejects names that cannot be a folder name.
+func normalizeFolderName(kind, name string) (string, error) {
+	name = normalizeName(name)
+	switch {
+	case name == "":
+		return "", fmt.Errorf("%s name cannot be empty", kind)
+	case strings.ContainsAny(name, `/\`):
+		return "", fmt.Errorf("%s name %q must not contain slashes", kind, name)
+	case strings.HasPrefix(name, "."):
+		return "", fmt.Errorf("%s name %q must not start with a dot", kind, name)
+	}
+	return name, nil
+}
+
+// CreateCollection adds an empty collection.
+func (s *Store) CreateCollection(ctx context.Context, name string) error {
+	name, err := NormalizeCollectionName(name)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO collections (name, created_at) VALUES (?, ?) ON CONFLICT(name) DO NOTHING`,
+		name, time.Now().Unix(),
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %s", ErrCollectionExists, name)
+	}
+	return nil
+}
+
+// DeleteCollection removes a collection and its memberships; the papers
+// themselves are untouched.
+func (s *Store) DeleteCollection(ctx context.Context, name string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM collections WHERE name = ?`, normalizeName(name))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	}
+	return nil
+}
+
+func (s *Store) collectionID(ctx context.Context, q querier, name string) (int64, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, `SELECT id FROM collections WHERE name = ?`, normalizeName(name)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	}
+	return id, err
+}
+
+// AddToCollection adds paths to the named collection, creating bare metadata
+// rows for files gorae has not seen yet. It returns the number of new members.
+func (s *Store) AddToCollection(ctx context.Context, name string, paths ...string) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := s.collectionID(ctx, tx, name)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().Unix()
+	added := 0
+	for _, path := range paths {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO metadata (path, reading_state, added_at) VALUES (?, ?, ?) ON CONFLICT(path) DO NOTHING`,
+			path, defaultReadingState, now,
+		); err != nil {
+			return 0, err
+		}
+		res, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO collection_items (collection_id, path, added_at) VALUES (?, ?, ?)`,
+			id, path, now,
+		)
+		if err != nil {
+			return 0, err
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			added += int(n)
+		}
+	}
+	return added, tx.Commit()
+}
+
+// RemoveFromCollection drops paths from the named collection and returns the
+// number of members removed.
+func (s *Store) RemoveFromCollection(ctx context.Context, name string, paths ...string) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := s.collectionID(ctx, tx, name)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, path := range paths {
+		res, err := tx.ExecContext(ctx, `DELETE FROM collection_items WHERE collection_id = ? AND path = ?`, id, path)
+		if err != nil {
+			return 0, err
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			removed += int(n)
+		}
+	}
+	return removed, tx.Commit()
+}
+
+// ListCollections returns every collection, including empty ones, by name.
+func (s *Store) ListCollections(ctx context.Context) ([]CollectionSummary, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT c.name, COUNT(ci.path)
+  FROM collections c
+  LEFT JOIN collection_items ci ON ci.collection_id = c.id
+ GROUP BY c.id
+ ORDER BY LOWER(c.name)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := make([]CollectionSummary, 0)
+	for rows.Next() {
+		var sum CollectionSummary
+		if err := rows.Scan(&sum.Name, &sum.Papers); err != nil {
+			return nil, err
+		}
+		results = append(results, sum)
+	}
+	return results, rows.Err()
+}
+
+// ListCollection returns the papers of the named collection.
+func (s *Store) ListCollection(ctx context.Context, name string) ([]Metadata, error) {
+	if _, err := s.collectionID(ctx, s.db, name); err != nil {
+		return nil, err
+	}
+	return s.listWhere(ctx, `
+ WHERE path IN (
+   SELECT ci.path
+     FROM collection_items ci
+     JOIN collections c ON c.id = ci.collection_id
+    WHERE c.name = ?
+ )
+ ORDER BY LOWER(title), path`, normalizeName(name))
+}
+
+func migrateCollections(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS collections (
+  id         INTEGER PRIMARY KEY,
+  name       TEXT NOT NULL UNIQUE COLLATE NOCASE,
+  created_at INTEGER
+);
+CREATE TABLE IF NOT EXISTS collection_items (
+  collection_id INTEGER NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+  path          TEXT NOT NULL REFERENCES metadata(path) ON UPDATE CASCADE ON DELETE CASCADE,
+  added_at      INTEGER,
+  PRIMARY KEY (collection_id, path)
+);
+CREATE INDEX IF NOT EXISTS collection_items_path ON collection_items(path);
+`)
+	return err
+}
@@ -0,0 +1,61 @@
+package meta_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestNamedCollections(t *testing.T) {
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	if err := store.CreateCollection(ctx, "thesis-ch3"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := store.CreateCollection(ctx, "Thesis-CH3"); !errors.Is(err, meta.ErrCollectionExists) {
+		t.Fatalf("expected ErrCollectionExists, got %v", err)
+	}
+	if err := store.CreateCollection(ctx, "a/b"); err == nil {
+		t.Fatalf("expected slash in name to be rejected")
+	}
+	if _, err := store.AddToCollection(ctx, "missing", "/lib/a.pdf"); !errors.Is(err, meta.ErrCollectionNotFound) {
+		t.Fatalf("expected ErrCollectionNotFound, got %v", err)
+	}
+
+	n, err := store.AddToCollection(ctx, "thesis-ch3", "/lib/a.pdf", "/lib/b.pdf", "/lib/a.pdf")
+	if err != nil || n != 2 {
+		t.Fatalf("add: n=%d err=%v", n, err)
+	}
+	if err := store.MovePath(ctx, "/lib/a.pdf", "/lib/moved.pdf"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if n, err := store.RemoveFromCollection(ctx, "thesis-ch3", "/lib/b.pdf"); err != nil || n != 1 {
+		t.Fatalf("remove: n=%d err=%v", n, err)
+	}
+	list, err := store.ListCollection(ctx, "THESIS-ch3")
+	if err != nil {
+		t.Fatalf("list collection: %v", err)
+	}
+	if len(list) != 1 || list[0].Path != "/lib/moved.pdf" {
+		t.Fatalf("unexpected members: %+v", list)
+	}
+
+	if err := store.DeleteCollection(ctx, "thesis-ch3"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	summaries, err := store.ListCollections(ctx)
+	if err != nil || len(summaries) != 0 {
+		t.Fatalf("expected no collections, got %v err=%v", summaries, err)
+	}
+	if md, _ := store.Get(ctx, "/lib/moved.pdf"); md == nil {
+		t.Fatalf("deleting a collection must keep its papers")
+	}
+}
@@ -0,0 +1,260 @@
+package meta
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Reasons papers are grouped as duplicates, strongest first.
+const (
+	DuplicateContent    = "same content"
+	DuplicateIdentifier = "same DOI/arXiv ID"
+	DuplicateTitle      = "similar title"
+)
+
+// duplicateTitleThreshold is the bigram similarity above which two titles of
+// papers from the same year are treated as the same work.
+const duplicateTitleThreshold = 0.9
+
+// duplicateTitleMinLength keeps short generic titles ("Notes", "Slides")
+// from matching each other.
+const duplicateTitleMinLength = 12
+
+// DuplicateGroup is a set of papers that are probably the same work.
+type DuplicateGroup struct {
+	Papers  []Metadata
+	Reasons []string
+}
+
+// PaperIdentifiers returns the identifiers other papers would cite md by.
+func PaperIdentifiers(md *Metadata) []Reference {
+	var refs []Reference
+	if r, ok := NormalizeReference(Reference{Kind: RefDOI, ID: md.DOI}); ok {
+		refs = append(refs, r)
+	}
+	url := strings.ToLower(md.URL)
+	if idx := strings.Index(url, "arxiv.org/abs/"); idx >= 0 {
+		id := url[idx+len("arxiv.org/abs/"):]
+		if end := strings.IndexAny(id, "?#"); end >= 0 {
+			id = id[:end]
+		}
+		if r, ok := NormalizeReference(Reference{Kind: RefArxiv, ID: id}); ok && !containsReference(refs, r) {
+			refs = append(refs, r)
+		}
+	}
+	for _, id := range md.Identifiers {
+		if r, ok := NormalizeReference(Reference{Kind: id.Scheme, ID: id.Value}); ok && !containsReference(refs, r) {
+			refs = append(refs, r)
+		}
+	}
+	return refs
+}
+
+// FindDuplicates groups papers that share a content hash (hashes maps paths
+// to hashes), a DOI or arXiv ID, or a near-identical title from the same or
+// an adjacent year. Groups are ordered by title, papers within a group by path.
+func FindDuplicates(papers []Metadata, hashes map[string]string) []DuplicateGroup {
+	parent := make([]int, len(papers))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	reasons := make(map[[2]int]string)
+	union := func(a, b int, reason string) {
+		ra, rb := find(a), find(b)
+		if ra != rb {
+			parent[rb] = ra
+		}
+		key := [2]int{min(a, b), max(a, b)}
+		if _, ok := reasons[key]; !ok {
+			reasons[key] = reason
+		}
+	}
+
+	byHash := make(map[string]int)
+	byID := make(map[Reference]int)
+	for i := range papers {
+		if hash := hashes[papers[i].Path]; hash != "" {
+			if first, ok := byHash[hash]; ok {
+				union(first, i, DuplicateContent)
+			} else {
+				byHash[hash] = i
+			}
+		}
+		for _, r := range PaperIdentifiers(&papers[i]) {
+			if first, ok := byID[r]; ok {
+				union(first, i, DuplicateIdentifier)
+			} else {
+				byID[r] = i
+			}
+		}
+	}
+
+	type titled struct {
+		index   int
+		bigrams map[string]int
+		size    int
+		year    int
+	}
+	var titles []titled
+	for i, md := range papers {
+		norm := normalizeTitle(md.Title)
+		if len([]rune(norm)) < duplicateTitleMinLength {
+			continue
+		}
+		grams, size := titleBigrams(norm)
+		year, _ := LeadingYear(md.Year)
+		titles = append(titles, titled{index: i, bigrams: grams, size: size, year: year})
+	}
+	// Similar titles have similar lengths, so after sorting by length each
+	// title only needs comparing with the next few.
+	sort.Slice(titles, func(i, j int) bool { return titles[i].size < titles[j].size })
+	for i := range titles {
+		a := titles[i]
+		for _, b := range titles[i+1:] {
+			if 2*float64(a.size)/float64(a.size+b.size) < duplicateTitleThreshold {
+				break
+			}
+			if a.year != 0 && b.year != 0 && (a.year-b.year > 1 || b.year-a.year > 1) {
+				continue
+			}
+			if bigramSimilarity(a.bigrams, a.size, b.bigrams, b.size) >= duplicateTitleThreshold {
+				union(a.index, b.index, DuplicateTitle)
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	for i := range papers {
+		root := find(i)
+		members[root] = append(members[root], i)
+	}
+	groupReasons := make(map[int]map[string]bool)
+	for key, reason := range reasons {
+		root := find(key[0])
+		if groupReasons[root] == nil {
+			groupReasons[root] = make(map[string]bool)
+		}
+		groupReasons[root][reason] = true
+	}
+
+	var groups []DuplicateGroup
+	for root, indexes := range members {
+		if len(indexes) < 2 {
+			continue
+		}
+		group := DuplicateGroup{Papers: make([]Metadata, 0, len(indexes))}
+		for _, i := range indexes {
+			group.Papers = append(group.Papers, papers[i])
+		}
+		sort.Slice(group.Papers, func(i, j int) bool { return group.Papers[i].Path < group.Papers[j].Path })
+		for _, reason := range []string{DuplicateContent, DuplicateIdentifier, DuplicateTitle} {
+			if groupReasons[root][reason] {
+				group.Reasons = append(group.Reasons, reason)
+			}
+		}
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		ti, tj := groupTitle(groups[i]), groupTitle(groups[j])
+		if ti != tj {
+			return ti < tj
+		}
+		return groups[i].Papers[0].Path < groups[j].Papers[0].Path
+	})
+	return groups
+}
+
+func groupTitle(g DuplicateGroup) string {
+	for _, md := range g.Papers {
+		if title := normalizeTitle(md.Title); title != "" {
+			return title
+		}
+	}
+	return ""
+}
+
+// normalizeTitle lowercases title and reduces it to letters and digits
+// separated by single spaces.
+func normalizeTitle(title string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			space = false
+			continue
+		}
+		space = true
+	}
+	return b.String()
+}
+
+func titleBigrams(s string) (map[string]int, int) {
+	runes := []rune(s)
+	grams := make(map[string]int, len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		grams[string(runes[i:i+2])]++
+	}
+	return grams, max(len(runes)-1, 0)
+}
+
+// bigramSimilarity is the Dice coefficient of two bigram multisets.
+func bigramSimilarity(a map[string]int, na int, b map[string]int, nb int) float64 {
+	if na+nb == 0 {
+		return 0
+	}
+	shared := 0
+	for gram, count := range a {
+		shared += min(count, b[gram])
+	}
+	return 2 * float64(shared) / float64(na+nb)
+}
+
+// MergeInto hands the collections, reading sessions, citations, custom
+// fields, attachments and identifiers of each path in from over to into,
+// then deletes their records. Values already set on into win. The caller
+// merges the metadata itself.
+func (s *Store) MergeInto(ctx context.Context, into string, from ...string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, path := range from {
+		if path == into {
+			continue
+		}
+		for _, stmt := range []string{
+			`UPDATE OR IGNORE collection_items SET path = ? WHERE path = ?`,
+			`UPDATE reading_sessions SET path = ? WHERE path = ?`,
+			`UPDATE OR IGNORE citations SET citing = ? WHERE citing = ?`,
+			`UPDATE OR IGNORE custom_fields SET path = ? WHERE path = ?`,
+			`UPDATE OR IGNORE attachments SET owner = ? WHERE owner = ?`,
+			`UPDATE OR IGNORE identifiers SET path = ? WHERE path = ?`,
+		} {
+			if _, err := tx.ExecContext(ctx, stmt, into, path); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM metadata WHERE path = ?`, path); err != nil {
+			return err
+		}
+		if err := deleteFullText(ctx, tx, path); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
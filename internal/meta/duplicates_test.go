@@ -0,0 +1,103 @@
+package meta_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorae/internal/meta"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	papers := []meta.Metadata{
+		{Path: "/lib/attention.pdf", Title: "Attention Is All You Need", Year: "2017"},
+		{Path: "/lib/attention-copy.pdf"},
+		{Path: "/lib/transformer.pdf", Title: "Attention is all you need!", Year: "2018"},
+		{Path: "/lib/bert.pdf", Title: "BERT", DOI: "10.18653/v1/N19-1423"},
+		{Path: "/lib/bert-preprint.pdf", Title: "BERT: Pre-training of Deep Bidirectional Transformers", DOI: "https://doi.org/10.18653/V1/N19-1423"},
+		{Path: "/lib/resnet.pdf", Title: "Deep Residual Learning for Image Recognition", Year: "2016"},
+		{Path: "/lib/resnet-v2.pdf", Title: "Identity Mappings in Deep Residual Networks", Year: "2016"},
+		{Path: "/lib/old.pdf", Title: "Attention Is All You Need", Year: "2009a"},
+	}
+	hashes := map[string]string{
+		"/lib/attention.pdf":      "aaa",
+		"/lib/attention-copy.pdf": "aaa",
+		"/lib/resnet.pdf":         "bbb",
+	}
+
+	groups := meta.FindDuplicates(papers, hashes)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %+v", groups)
+	}
+
+	attention := groups[0]
+	if len(attention.Papers) != 3 ||
+		attention.Papers[0].Path != "/lib/attention-copy.pdf" ||
+		attention.Papers[1].Path != "/lib/attention.pdf" ||
+		attention.Papers[2].Path != "/lib/transformer.pdf" {
+		t.Fatalf("unexpected attention group: %+v", attention.Papers)
+	}
+	if len(attention.Reasons) != 2 || attention.Reasons[0] != meta.DuplicateContent || attention.Reasons[1] != meta.DuplicateTitle {
+		t.Fatalf("unexpected attention reasons: %v", attention.Reasons)
+	}
+
+	bert := groups[1]
+	if len(bert.Papers) != 2 || len(bert.Reasons) != 1 || bert.Reasons[0] != meta.DuplicateIdentifier {
+		t.Fatalf("unexpected bert group: %+v", bert)
+	}
+}
+
+func TestMergeIntoMovesLinkedRecords(t *testing.T) {
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	for _, md := range []meta.Metadata{
+		{Path: "/lib/a.pdf", Title: "Paper", Fields: map[string]string{"dataset": "imagenet"}},
+		{Path: "/lib/b.pdf", Title: "Paper", Fields: map[string]string{"dataset": "cifar", "venue": "CVPR"}},
+	} {
+		if err := store.Upsert(ctx, &md); err != nil {
+			t.Fatalf("upsert %s: %v", md.Path, err)
+		}
+	}
+	if err := store.CreateCollection(ctx, "thesis"); err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+	if _, err := store.AddToCollection(ctx, "thesis", "/lib/b.pdf"); err != nil {
+		t.Fatalf("add to collection: %v", err)
+	}
+	id, err := store.StartSession(ctx, "/lib/b.pdf", time.Now())
+	if err != nil {
+		t.Fatalf("start session: %v", err)
+	}
+	if err := store.FinishSession(ctx, id, 10*time.Minute); err != nil {
+		t.Fatalf("finish session: %v", err)
+	}
+
+	if err := store.MergeInto(ctx, "/lib/a.pdf", "/lib/b.pdf"); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	if md, err := store.Get(ctx, "/lib/b.pdf"); err != nil || md != nil {
+		t.Fatalf("expected merged record to be gone, got %+v (%v)", md, err)
+	}
+	md, err := store.Get(ctx, "/lib/a.pdf")
+	if err != nil || md == nil {
+		t.Fatalf("get survivor: %+v (%v)", md, err)
+	}
+	if md.Fields["dataset"] != "imagenet" || md.Fields["venue"] != "CVPR" {
+		t.Fatalf("expected survivor fields to win and missing ones to move, got %v", md.Fields)
+	}
+	list, err := store.ListCollection(ctx, "thesis")
+	if err != nil || len(list) != 1 || list[0].Path != "/lib/a.pdf" {
+		t.Fatalf("expected collection to follow the merge, got %+v (%v)", list, err)
+	}
+	sum, err := store.ReadingSummary(ctx, "/lib/a.pdf")
+	if err != nil || sum.Sessions != 1 || sum.Total != 10*time.Minute {
+		t.Fatalf("expected reading session to follow the merge, got %+v (%v)", sum, err)
+	}
+}
@@ -0,0 +1,101 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+)
+
+// customFieldPrefix marks custom fields in the metadata history, e.g.
+// "field:dataset".
+const customFieldPrefix = "field:"
+
+// loadFields returns the custom field values of path keyed by field name.
+func (s *Store) loadFields(ctx context.Context, q querier, path string) (map[string]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT name, IFNULL(value, '') FROM custom_fields WHERE path = ?`, path)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	fields := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		fields[name] = value
+	}
+	return fields, rows.Err()
+}
+
+// writeFields replaces the custom field values of md. Empty values are
+// dropped so that clearing a field removes it.
+func writeFields(ctx context.Context, tx *sql.Tx, md *Metadata) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM custom_fields WHERE path = ?`, md.Path); err != nil {
+		return err
+	}
+	for name, value := range md.Fields {
+		name, value = normalizeName(name), strings.TrimSpace(value)
+		if name == "" || value == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR REPLACE INTO custom_fields (path, name, value) VALUES (?, ?, ?)`,
+			md.Path, name, value,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FieldValues returns the value of the custom field name for every paper that
+// has one, keyed by path.
+func (s *Store) FieldValues(ctx context.Context, name string) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT path, IFNULL(value, '') FROM custom_fields WHERE name = ? COLLATE NOCASE`, normalizeName(name))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := make(map[string]string)
+	for rows.Next() {
+		var path, value string
+		if err := rows.Scan(&path, &value); err != nil {
+			return nil, err
+		}
+		values[path] = value
+	}
+	return values, rows.Err()
+}
+
+// fieldNames returns the custom field names set in either before or after,
+// sorted, for diffing.
+func fieldNames(before, after map[string]string) []string {
+	seen := make(map[string]bool, len(before)+len(after))
+	names := make([]string, 0, len(before)+len(after))
+	for _, set := range []map[string]string{before, after} {
+		for name := range set {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func migrateCustomFields(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS custom_fields (
+  path  TEXT NOT NULL REFERENCES metadata(path) ON UPDATE CASCADE ON DELETE CASCADE,
+  name  TEXT NOT NULL,
+  value TEXT,
+  PRIMARY KEY (path, name)
+);
+CREATE INDEX IF NOT EXISTS custom_fields_name ON custom_fields(name, value);
+`)
+	return err
+}
@@ -0,0 +1,67 @@
+package meta_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestCustomFieldsStoredAndTracked(t *testing.T) {
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+	path := "/lib/paper.pdf"
+
+	md := &meta.Metadata{Path: path, Title: "Paper", Fields: map[string]string{"dataset": "ImageNet", "epochs": "90"}}
+	if err := store.Upsert(ctx, md); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	// A nil map leaves the stored fields alone.
+	if err := store.Upsert(ctx, &meta.Metadata{Path: path, Title: "Paper v2"}); err != nil {
+		t.Fatalf("upsert without fields: %v", err)
+	}
+	got, err := store.Get(ctx, path)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Fields["dataset"] != "ImageNet" || got.Fields["epochs"] != "90" {
+		t.Fatalf("unexpected fields: %+v", got.Fields)
+	}
+
+	got.Fields["epochs"] = ""
+	got.Fields["dataset"] = "COCO"
+	if err := store.Upsert(ctx, got); err != nil {
+		t.Fatalf("update fields: %v", err)
+	}
+	values, err := store.FieldValues(ctx, "Dataset")
+	if err != nil {
+		t.Fatalf("field values: %v", err)
+	}
+	if len(values) != 1 || values[path] != "COCO" {
+		t.Fatalf("unexpected dataset values: %+v", values)
+	}
+	if epochs, _ := store.FieldValues(ctx, "epochs"); len(epochs) != 0 {
+		t.Fatalf("expected cleared epochs, got %+v", epochs)
+	}
+
+	history, err := store.History(ctx, path)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	latest := history[0]
+	if len(latest.Changes) != 2 || latest.Changes[0].Field != "field:dataset" || latest.Changes[1].Field != "field:epochs" {
+		t.Fatalf("unexpected field revision: %+v", latest)
+	}
+	if _, err := store.RevertRevision(ctx, path, latest.ID, "field:epochs"); err != nil {
+		t.Fatalf("revert epochs: %v", err)
+	}
+	got, _ = store.Get(ctx, path)
+	if got.Fields["epochs"] != "90" || got.Fields["dataset"] != "COCO" {
+		t.Fatalf("expected only epochs restored, got %+v", got.Fields)
+	}
+}
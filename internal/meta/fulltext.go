@@ -0,0 +1,233 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// IndexStamp records the size and modification time of a file when its text
+// was last indexed; a mismatch means the file must be extracted again.
+type IndexStamp struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Fresh reports whether the stamp still describes a file of the given size and
+// modification time.
+func (st IndexStamp) Fresh(size int64, modTime time.Time) bool {
+	return st.Size == size && st.ModTime.Unix() == modTime.Unix()
+}
+
+// FullTextStamps returns the index stamp of every indexed file under root.
+func (s *Store) FullTextStamps(ctx context.Context, root string) (map[string]IndexStamp, error) {
+	prefix, err := normalizeDirPrefix(root)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT path, size, mtime FROM fulltext_files WHERE path LIKE ? ESCAPE '\'`,
+		escapeLike(prefix)+"%",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stamps := make(map[string]IndexStamp)
+	for rows.Next() {
+		var (
+			path  string
+			size  int64
+			mtime int64
+		)
+		if err := rows.Scan(&path, &size, &mtime); err != nil {
+			return nil, err
+		}
+		stamps[path] = IndexStamp{Size: size, ModTime: time.Unix(mtime, 0).UTC()}
+	}
+	return stamps, rows.Err()
+}
+
+// IndexText stores the extracted text of path, replacing any previous entry.
+func (s *Store) IndexText(ctx context.Context, path string, stamp IndexStamp, text string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteFullText(ctx, tx, path); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO fulltext_files (path, size, mtime, indexed_at) VALUES (?, ?, ?, ?)`,
+		path, stamp.Size, stamp.ModTime.Unix(), time.Now().Unix(),
+	)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO fulltext (rowid, body) VALUES (?, ?)`, id, text); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MarkIndexFailed stamps path without text after its extraction failed, so it
+// is not retried until the file changes. IndexedText reports it as not
+// indexed.
+func (s *Store) MarkIndexFailed(ctx context.Context, path string, stamp IndexStamp) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteFullText(ctx, tx, path); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO fulltext_files (path, size, mtime, indexed_at) VALUES (?, ?, ?, ?)`,
+		path, stamp.Size, stamp.ModTime.Unix(), time.Now().Unix(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveFromIndex drops the indexed text of path.
+func (s *Store) RemoveFromIndex(ctx context.Context, path string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := deleteFullText(ctx, tx, path); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func deleteFullText(ctx context.Context, tx *sql.Tx, path string) error {
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM fulltext WHERE rowid IN (SELECT id FROM fulltext_files WHERE path = ?)`, path,
+	); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM fulltext_files WHERE path = ?`, path)
+	return err
+}
+
+// IndexedText returns the stored text of path and whether it is indexed.
+func (s *Store) IndexedText(ctx context.Context, path string) (string, bool, error) {
+	var body string
+	err := s.db.QueryRowContext(ctx, `
+SELECT IFNULL(t.body, '')
+  FROM fulltext_files f
+  JOIN fulltext t ON t.rowid = f.id
+ WHERE f.path = ?`, path).Scan(&body)
+	switch err {
+	case sql.ErrNoRows:
+		return "", false, nil
+	case nil:
+		return body, true, nil
+	default:
+		return "", false, err
+	}
+}
+
+// SearchFullText returns the indexed paths under root whose text contains
+// query as a substring. The index ignores case, so callers verify exact
+// matches against IndexedText. The boolean is false when query is too short
+// for the index and the caller has to fall back to scanning.
+func (s *Store) SearchFullText(ctx context.Context, root, query string) ([]string, bool, error) {
+	expr := FullTextQuery(query)
+	if expr == "" {
+		return nil, false, nil
+	}
+	prefix, err := normalizeDirPrefix(root)
+	if err != nil {
+		return nil, false, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT f.path
+  FROM fulltext t
+  JOIN fulltext_files f ON f.id = t.rowid
+ WHERE fulltext MATCH ? AND f.path LIKE ? ESCAPE '\'
+ ORDER BY f.path`, expr, escapeLike(prefix)+"%")
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, false, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, true, rows.Err()
+}
+
+// FullTextQuery turns text into an FTS5 query for rows that contain it
+// anywhere, like the substring match of a content search; the trigram index
+// finds words mid-way, so "tention" still finds "attention". It returns ""
+// when text is shorter than the three characters the index looks up.
+func FullTextQuery(text string) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) < 3 {
+		return ""
+	}
+	return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+}
+
+// PruneFullText removes index entries under root whose path is not in keep,
+// typically because the file was deleted or moved away.
+func (s *Store) PruneFullText(ctx context.Context, root string, keep map[string]bool) error {
+	stamps, err := s.FullTextStamps(ctx, root)
+	if err != nil {
+		return err
+	}
+	for path := range stamps {
+		if keep[path] {
+			continue
+		}
+		if err := s.RemoveFromIndex(ctx, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateFullText(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS fulltext_files (
+  id         INTEGER PRIMARY KEY,
+  path       TEXT NOT NULL UNIQUE,
+  size       INTEGER NOT NULL,
+  mtime      INTEGER NOT NULL,
+  indexed_at INTEGER
+);
+CREATE VIRTUAL TABLE IF NOT EXISTS fulltext USING fts5(body, tokenize = 'unicode61');
+`)
+	return err
+}
+
+// migrateFullTextTrigram rebuilds the index with the trigram tokenizer, so
+// lookups match substrings the way content searches do. The stored text is
+// carried over; nothing has to be extracted again.
+func migrateFullTextTrigram(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE VIRTUAL TABLE fulltext_trigram USING fts5(body, tokenize = 'trigram');
+INSERT INTO fulltext_trigram (rowid, body) SELECT rowid, body FROM fulltext;
+DROP TABLE fulltext;
+ALTER TABLE fulltext_trigram RENAME TO fulltext;
+`)
+	return err
+}
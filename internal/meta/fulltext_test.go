@@ -0,0 +1,56 @@
+package meta_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorae/internal/meta"
+)
+
+func TestFullTextIndexFollowsMoves(t *testing.T) {
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	stamp := meta.IndexStamp{Size: 10, ModTime: time.Unix(1000, 0)}
+	if err := store.IndexText(ctx, "/lib/a/paper.pdf", stamp, "Self-attention is all you need"); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	paths, ok, err := store.SearchFullText(ctx, "/lib", "self-atten")
+	if err != nil || !ok || len(paths) != 1 {
+		t.Fatalf("search: paths=%v ok=%v err=%v", paths, ok, err)
+	}
+	if paths, ok, _ := store.SearchFullText(ctx, "/lib", "TENTION is"); !ok || len(paths) != 1 {
+		t.Fatalf("expected a mid-word substring to match, got %v ok=%v", paths, ok)
+	}
+	if paths, ok, _ := store.SearchFullText(ctx, "/lib", "attention all"); !ok || len(paths) != 0 {
+		t.Fatalf("expected only contiguous text to match, got %v", paths)
+	}
+	if _, ok, _ := store.SearchFullText(ctx, "/lib", "--"); ok {
+		t.Fatalf("expected a query too short for the index to fall back to scanning")
+	}
+
+	if err := store.MoveTree(ctx, "/lib/a", "/lib/b"); err != nil {
+		t.Fatalf("move tree: %v", err)
+	}
+	stamps, err := store.FullTextStamps(ctx, "/lib")
+	if err != nil {
+		t.Fatalf("stamps: %v", err)
+	}
+	st, ok := stamps["/lib/b/paper.pdf"]
+	if !ok || !st.Fresh(10, time.Unix(1000, 0)) {
+		t.Fatalf("expected index entry to follow the move, got %v", stamps)
+	}
+
+	if err := store.PruneFullText(ctx, "/lib", map[string]bool{}); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if paths, _, _ := store.SearchFullText(ctx, "/lib", "attention"); len(paths) != 0 {
+		t.Fatalf("expected pruned index to be empty, got %v", paths)
+	}
+}
@@ -0,0 +1,288 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sources recorded with every metadata revision.
+const (
+	SourceManual   = "manual"
+	SourceCrossref = "crossref"
+	SourceArxiv    = "arxiv"
+	SourceEPUB     = "epub"
+	SourceImport   = "import"
+	SourceRevert   = "revert"
+	SourceMerge    = "merge"
+)
+
+// FieldChange is one field of a revision with its value before and after.
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// Revision groups the field changes written by a single Upsert.
+type Revision struct {
+	ID        int64
+	Source    string
+	ChangedAt time.Time
+	Changes   []FieldChange
+}
+
+// historyFields lists the tracked metadata fields in display order.
+var historyFields = []string{
+	"title", "author", "year", "published", "url", "doi", "abstract", "tag",
+	"reading_state", "favorite", "to_read", "rating", "priority",
+}
+
+// identifiersField is the history name of the identifier list.
+const identifiersField = "identifiers"
+
+func metadataFieldValue(md *Metadata, field string) string {
+	if name, ok := strings.CutPrefix(field, customFieldPrefix); ok {
+		return md.Fields[name]
+	}
+	switch field {
+	case identifiersField:
+		return FormatIdentifiers(md.Identifiers)
+	case "title":
+		return md.Title
+	case "author":
+		return md.Author
+	case "year":
+		return md.Year
+	case "published":
+		return md.Published
+	case "url":
+		return md.URL
+	case "doi":
+		return md.DOI
+	case "abstract":
+		return md.Abstract
+	case "tag":
+		return md.Tag
+	case "reading_state":
+		return normalizeReadingState(md.ReadingState)
+	case "favorite":
+		return strconv.FormatBool(md.Favorite)
+	case "to_read":
+		return strconv.FormatBool(md.ToRead)
+	case "rating":
+		return strconv.Itoa(clampRating(md.Rating))
+	case "priority":
+		priority, _ := NormalizePriority(md.Priority)
+		return priority
+	}
+	return ""
+}
+
+func setMetadataField(md *Metadata, field, value string) error {
+	if name, ok := strings.CutPrefix(field, customFieldPrefix); ok {
+		if md.Fields == nil {
+			md.Fields = make(map[string]string)
+		}
+		md.Fields[name] = value
+		return nil
+	}
+	switch field {
+	case identifiersField:
+		md.Identifiers = ParseIdentifierList(value)
+	case "title":
+		md.Title = value
+	case "author":
+		md.Author = value
+		md.Authors = nil
+	case "year":
+		md.Year = value
+	case "published":
+		md.Published = value
+	case "url":
+		md.URL = value
+	case "doi":
+		md.DOI = value
+	case "abstract":
+		md.Abstract = value
+	case "tag":
+		md.Tag = value
+	case "reading_state":
+		md.ReadingState = value
+	case "favorite":
+		md.Favorite = value == "true"
+	case "to_read":
+		md.ToRead = value == "true"
+	case "rating":
+		md.Rating, _ = strconv.Atoi(value)
+	case "priority":
+		md.Priority = value
+	default:
+		return fmt.Errorf("unknown metadata field %q", field)
+	}
+	return nil
+}
+
+func diffMetadata(before, after *Metadata) []FieldChange {
+	var changes []FieldChange
+	for _, field := range historyFields {
+		old, updated := metadataFieldValue(before, field), metadataFieldValue(after, field)
+		if old != updated {
+			changes = append(changes, FieldChange{Field: field, Old: old, New: updated})
+		}
+	}
+	if after.Fields != nil {
+		for _, name := range fieldNames(before.Fields, after.Fields) {
+			old, updated := strings.TrimSpace(before.Fields[name]), strings.TrimSpace(after.Fields[name])
+			if old != updated {
+				changes = append(changes, FieldChange{Field: customFieldPrefix + name, Old: old, New: updated})
+			}
+		}
+	}
+	if after.Identifiers != nil {
+		old, updated := FormatIdentifiers(before.Identifiers), FormatIdentifiers(after.Identifiers)
+		if old != updated {
+			changes = append(changes, FieldChange{Field: identifiersField, Old: old, New: updated})
+		}
+	}
+	return changes
+}
+
+// recordHistory stores the changes between before and after as one revision.
+// before is nil when the row is being created.
+func recordHistory(ctx context.Context, tx *sql.Tx, before, after *Metadata, source string) error {
+	if before == nil {
+		before = &Metadata{}
+	}
+	changes := diffMetadata(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+	var revision int64
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(revision), 0) + 1 FROM metadata_history`).Scan(&revision); err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for _, ch := range changes {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO metadata_history (revision, path, field, old_value, new_value, source, changed_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			revision, after.Path, ch.Field, ch.Old, ch.New, source, now,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// History returns the revisions of path, newest first.
+func (s *Store) History(ctx context.Context, path string) ([]Revision, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT revision, IFNULL(source, ''), changed_at, field, IFNULL(old_value, ''), IFNULL(new_value, '')
+  FROM metadata_history
+ WHERE path = ?
+ ORDER BY revision DESC, id`, path)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var revisions []Revision
+	for rows.Next() {
+		var (
+			rev       int64
+			source    string
+			changedAt int64
+			ch        FieldChange
+		)
+		if err := rows.Scan(&rev, &source, &changedAt, &ch.Field, &ch.Old, &ch.New); err != nil {
+			return nil, err
+		}
+		if n := len(revisions); n == 0 || revisions[n-1].ID != rev {
+			revisions = append(revisions, Revision{
+				ID:        rev,
+				Source:    source,
+				ChangedAt: time.Unix(changedAt, 0).UTC(),
+			})
+		}
+		last := &revisions[len(revisions)-1]
+		last.Changes = append(last.Changes, ch)
+	}
+	return revisions, rows.Err()
+}
+
+// RevertRevision restores the values a revision of path replaced. When fields
+// are given only those are restored. The revert is itself recorded as a new
+// revision, so it can be undone as well. It returns the number of fields
+// restored.
+func (s *Store) RevertRevision(ctx context.Context, path string, revision int64, fields ...string) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT field, IFNULL(old_value, '')
+  FROM metadata_history
+ WHERE path = ? AND revision = ?
+ ORDER BY id`, path, revision)
+	if err != nil {
+		return 0, err
+	}
+	var changes []FieldChange
+	for rows.Next() {
+		var ch FieldChange
+		if err := rows.Scan(&ch.Field, &ch.Old); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		changes = append(changes, ch)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(changes) == 0 {
+		return 0, fmt.Errorf("revision %d not found", revision)
+	}
+
+	wanted := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		wanted[f] = true
+	}
+	md, err := s.Get(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	if md == nil {
+		return 0, fmt.Errorf("no metadata for %s", path)
+	}
+	restored := 0
+	for _, ch := range changes {
+		if len(wanted) > 0 && !wanted[ch.Field] {
+			continue
+		}
+		if err := setMetadataField(md, ch.Field, ch.Old); err != nil {
+			return 0, err
+		}
+		restored++
+	}
+	if restored == 0 {
+		return 0, fmt.Errorf("revision %d did not change %v", revision, fields)
+	}
+	return restored, s.UpsertFrom(ctx, md, SourceRevert)
+}
+
+func migrateHistory(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS metadata_history (
+  id         INTEGER PRIMARY KEY,
+  revision   INTEGER NOT NULL,
+  path       TEXT NOT NULL REFERENCES metadata(path) ON UPDATE CASCADE ON DELETE CASCADE,
+  field      TEXT NOT NULL,
+  old_value  TEXT,
+  new_value  TEXT,
+  source     TEXT,
+  changed_at INTEGER
+);
+CREATE INDEX IF NOT EXISTS metadata_history_path ON metadata_history(path, revision);
+`)
+	return err
+}
@@ -0,0 +1,69 @@
+package meta_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestHistoryRecordsAndRevertsChanges(t *testing.T) {
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+	path := "/lib/paper.pdf"
+
+	if err := store.Upsert(ctx, &meta.Metadata{Path: path, Title: "Curated title", Abstract: "Hand-written"}); err != nil {
+		t.Fatalf("initial upsert: %v", err)
+	}
+	md, err := store.Get(ctx, path)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	md.Title = "FETCHED TITLE"
+	md.Abstract = "Fetched abstract"
+	md.Year = "2020"
+	if err := store.UpsertFrom(ctx, md, meta.SourceCrossref); err != nil {
+		t.Fatalf("fetch upsert: %v", err)
+	}
+	// Writing identical data must not create an empty revision.
+	if err := store.UpsertFrom(ctx, md, meta.SourceCrossref); err != nil {
+		t.Fatalf("no-op upsert: %v", err)
+	}
+
+	history, err := store.History(ctx, path)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 revisions, got %+v", history)
+	}
+	fetched := history[0]
+	if fetched.Source != meta.SourceCrossref || len(fetched.Changes) != 3 {
+		t.Fatalf("unexpected newest revision: %+v", fetched)
+	}
+
+	if n, err := store.RevertRevision(ctx, path, fetched.ID, "title"); err != nil || n != 1 {
+		t.Fatalf("revert title: n=%d err=%v", n, err)
+	}
+	md, _ = store.Get(ctx, path)
+	if md.Title != "Curated title" || md.Abstract != "Fetched abstract" {
+		t.Fatalf("expected only title reverted, got %+v", md)
+	}
+
+	if _, err := store.RevertRevision(ctx, path, fetched.ID); err != nil {
+		t.Fatalf("revert revision: %v", err)
+	}
+	md, _ = store.Get(ctx, path)
+	if md.Abstract != "Hand-written" || md.Year != "" {
+		t.Fatalf("expected whole revision reverted, got %+v", md)
+	}
+	history, _ = store.History(ctx, path)
+	if history[0].Source != meta.SourceRevert {
+		t.Fatalf("expected revert to be recorded, got %+v", history[0])
+	}
+}
@@ -0,0 +1,373 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Identifier schemes gorae normalizes. Any other lowercase scheme name is
+// accepted as is, e.g. "openalex:W2741809807".
+const (
+	SchemeDOI   = RefDOI
+	SchemeArxiv = RefArxiv
+	SchemeISBN  = "isbn"
+	SchemePMID  = "pmid"
+	SchemePMCID = "pmcid"
+)
+
+// Identifier is one persistent identifier of a paper. A paper can have any
+// number of them, several per scheme even (an ISBN for each edition).
+type Identifier struct {
+	Scheme string
+	Value  string
+}
+
+func (id Identifier) String() string {
+	return id.Scheme + ":" + id.Value
+}
+
+var (
+	schemeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9._-]*$`)
+	digitsPattern     = regexp.MustCompile(`^\d+$`)
+)
+
+// NormalizeIdentifier canonicalizes value under scheme so that the same work
+// always has the same identifier: DOIs and arXiv IDs as for references,
+// ISBN-10s as their ISBN-13, PubMed IDs as bare numbers. ok is false when the
+// value is not valid for its scheme.
+func NormalizeIdentifier(scheme, value string) (Identifier, bool) {
+	scheme = strings.ToLower(strings.TrimSpace(scheme))
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Identifier{}, false
+	}
+	switch scheme {
+	case SchemeDOI, SchemeArxiv:
+		r, ok := NormalizeReference(Reference{Kind: scheme, ID: value})
+		return Identifier{Scheme: r.Kind, Value: r.ID}, ok
+	case SchemeISBN:
+		isbn, ok := normalizeISBN(value)
+		return Identifier{Scheme: SchemeISBN, Value: isbn}, ok
+	case SchemePMID:
+		value = strings.TrimSpace(strings.TrimPrefix(strings.ToLower(value), "pmid:"))
+		value = strings.TrimLeft(value, "0")
+		if !digitsPattern.MatchString(value) {
+			return Identifier{}, false
+		}
+		return Identifier{Scheme: SchemePMID, Value: value}, true
+	case SchemePMCID:
+		value = strings.TrimPrefix(strings.ToUpper(value), "PMC")
+		if !digitsPattern.MatchString(value) {
+			return Identifier{}, false
+		}
+		return Identifier{Scheme: SchemePMCID, Value: "PMC" + value}, true
+	}
+	if !schemeNamePattern.MatchString(scheme) {
+		return Identifier{}, false
+	}
+	return Identifier{Scheme: scheme, Value: value}, true
+}
+
+// ParseIdentifier reads an identifier written as "scheme:value", a URN such
+// as "urn:isbn:...", a resolver URL (doi.org, arxiv.org, PubMed) or a bare
+// DOI.
+func ParseIdentifier(raw string) (Identifier, bool) {
+	raw = strings.TrimSpace(raw)
+	lower := strings.ToLower(raw)
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+		return identifierFromURL(lower)
+	}
+	if rest, ok := strings.CutPrefix(lower, "urn:"); ok {
+		raw = raw[len("urn:"):]
+		lower = rest
+	}
+	if strings.HasPrefix(lower, "10.") {
+		return NormalizeIdentifier(SchemeDOI, raw)
+	}
+	scheme, value, ok := strings.Cut(raw, ":")
+	if !ok {
+		return Identifier{}, false
+	}
+	return NormalizeIdentifier(scheme, value)
+}
+
+func identifierFromURL(url string) (Identifier, bool) {
+	if end := strings.IndexAny(url, "?#"); end >= 0 {
+		url = url[:end]
+	}
+	for _, host := range []string{"doi.org/", "dx.doi.org/"} {
+		if idx := strings.Index(url, "://"+host); idx >= 0 {
+			return NormalizeIdentifier(SchemeDOI, url[idx+len("://"+host):])
+		}
+	}
+	for _, prefix := range []string{"arxiv.org/abs/", "arxiv.org/pdf/"} {
+		if idx := strings.Index(url, prefix); idx >= 0 {
+			return NormalizeIdentifier(SchemeArxiv, strings.TrimSuffix(url[idx+len(prefix):], ".pdf"))
+		}
+	}
+	if idx := strings.Index(url, "pubmed.ncbi.nlm.nih.gov/"); idx >= 0 {
+		return NormalizeIdentifier(SchemePMID, strings.Trim(url[idx+len("pubmed.ncbi.nlm.nih.gov/"):], "/"))
+	}
+	return Identifier{}, false
+}
+
+// normalizeISBN strips separators, checks the check digit and turns ISBN-10s
+// into ISBN-13s.
+func normalizeISBN(value string) (string, bool) {
+	value = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "isbn")
+	value = strings.TrimLeft(value, ": ")
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == 'x' || r == 'X':
+			b.WriteRune('X')
+		case r == '-' || r == ' ':
+		default:
+			return "", false
+		}
+	}
+	isbn := b.String()
+	switch len(isbn) {
+	case 10:
+		sum := 0
+		for i, r := range isbn {
+			d := int(r - '0')
+			if r == 'X' {
+				if i != 9 {
+					return "", false
+				}
+				d = 10
+			}
+			sum += d * (10 - i)
+		}
+		if sum%11 != 0 {
+			return "", false
+		}
+		body := "978" + isbn[:9]
+		return body + string(rune('0'+isbn13CheckDigit(body))), true
+	case 13:
+		if !digitsPattern.MatchString(isbn) || int(isbn[12]-'0') != isbn13CheckDigit(isbn[:12]) {
+			return "", false
+		}
+		return isbn, true
+	}
+	return "", false
+}
+
+func isbn13CheckDigit(body string) int {
+	sum := 0
+	for i, r := range body {
+		d := int(r - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
+
+// FormatIdentifiers joins ids as "scheme:value, ..." in a stable order.
+func FormatIdentifiers(ids []Identifier) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = id.String()
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+// ParseIdentifierList is the inverse of FormatIdentifiers. Entries that do
+// not parse are skipped.
+func ParseIdentifierList(raw string) []Identifier {
+	ids := make([]Identifier, 0)
+	for _, part := range strings.Split(raw, ",") {
+		if id, ok := ParseIdentifier(part); ok && !containsIdentifier(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func containsIdentifier(ids []Identifier, id Identifier) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
+
+// derivedIdentifiers are the identifiers implied by the DOI and URL columns.
+// They are kept in the identifiers table so lookups need only look there.
+func derivedIdentifiers(md *Metadata) []Identifier {
+	if md == nil {
+		return nil
+	}
+	var ids []Identifier
+	if id, ok := NormalizeIdentifier(SchemeDOI, md.DOI); ok {
+		ids = append(ids, id)
+	}
+	if id, ok := identifierFromURL(strings.ToLower(strings.TrimSpace(md.URL))); ok && !containsIdentifier(ids, id) {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// loadIdentifiers returns the identifiers of path by scheme and value.
+func loadIdentifiers(ctx context.Context, q querier, path string) ([]Identifier, error) {
+	rows, err := q.QueryContext(ctx, `SELECT scheme, value FROM identifiers WHERE path = ? ORDER BY scheme, value`, path)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := make([]Identifier, 0)
+	for rows.Next() {
+		var id Identifier
+		if err := rows.Scan(&id.Scheme, &id.Value); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// writeIdentifiers stores the identifiers of md and returns them. A nil
+// md.Identifiers keeps the stored ones. Either way the identifiers implied by
+// the DOI and URL are added, and those implied only by their old values are
+// dropped.
+func writeIdentifiers(ctx context.Context, tx *sql.Tx, before, md *Metadata) ([]Identifier, error) {
+	var base []Identifier
+	switch {
+	case md.Identifiers != nil:
+		base = md.Identifiers
+	case before != nil:
+		base = before.Identifiers
+	}
+	current := derivedIdentifiers(md)
+	var stale []Identifier
+	for _, id := range derivedIdentifiers(before) {
+		if !containsIdentifier(current, id) {
+			stale = append(stale, id)
+		}
+	}
+
+	ids := make([]Identifier, 0, len(base)+len(current))
+	for _, raw := range append(append([]Identifier{}, base...), current...) {
+		id, ok := NormalizeIdentifier(raw.Scheme, raw.Value)
+		if !ok || containsIdentifier(stale, id) || containsIdentifier(ids, id) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i].Scheme != ids[j].Scheme {
+			return ids[i].Scheme < ids[j].Scheme
+		}
+		return ids[i].Value < ids[j].Value
+	})
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM identifiers WHERE path = ?`, md.Path); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO identifiers (path, scheme, value) VALUES (?, ?, ?)`,
+			md.Path, id.Scheme, id.Value,
+		); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// AddIdentifiers records more identifiers for path without touching the ones
+// it has. Invalid values are skipped. A bare metadata row is created for
+// files gorae has not seen yet.
+func (s *Store) AddIdentifiers(ctx context.Context, path string, ids []Identifier) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO metadata (path, reading_state, added_at) VALUES (?, ?, ?) ON CONFLICT(path) DO NOTHING`,
+		path, defaultReadingState, time.Now().Unix(),
+	); err != nil {
+		return err
+	}
+	for _, raw := range ids {
+		id, ok := NormalizeIdentifier(raw.Scheme, raw.Value)
+		if !ok {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO identifiers (path, scheme, value) VALUES (?, ?, ?)`,
+			path, id.Scheme, id.Value,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func migrateIdentifiers(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS identifiers (
+  path   TEXT NOT NULL REFERENCES metadata(path) ON UPDATE CASCADE ON DELETE CASCADE,
+  scheme TEXT NOT NULL,
+  value  TEXT NOT NULL,
+  PRIMARY KEY (path, scheme, value)
+);
+CREATE INDEX IF NOT EXISTS identifiers_value ON identifiers(scheme, value);
+CREATE TRIGGER IF NOT EXISTS identifiers_insert_generation AFTER INSERT ON identifiers
+BEGIN
+  UPDATE store_generation SET generation = generation + 1 WHERE id = 1;
+END;
+CREATE TRIGGER IF NOT EXISTS identifiers_delete_generation AFTER DELETE ON identifiers
+BEGIN
+  UPDATE store_generation SET generation = generation + 1 WHERE id = 1;
+END;
+`); err != nil {
+		return err
+	}
+
+	// Seed the table from the DOI and URL columns.
+	rows, err := tx.QueryContext(ctx, `SELECT path, IFNULL(doi, ''), IFNULL(url, '') FROM metadata WHERE IFNULL(doi, '') <> '' OR IFNULL(url, '') <> ''`)
+	if err != nil {
+		return err
+	}
+	var papers []Metadata
+	for rows.Next() {
+		var md Metadata
+		if err := rows.Scan(&md.Path, &md.DOI, &md.URL); err != nil {
+			rows.Close()
+			return err
+		}
+		papers = append(papers, md)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range papers {
+		for _, id := range derivedIdentifiers(&papers[i]) {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT OR IGNORE INTO identifiers (path, scheme, value) VALUES (?, ?, ?)`,
+				papers[i].Path, id.Scheme, id.Value,
+			); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package meta_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestNormalizeIdentifier(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want string
+		ok   bool
+	}{
+		{"doi:10.1000/ABC", "doi:10.1000/abc", true},
+		{"https://doi.org/10.1000/abc", "doi:10.1000/abc", true},
+		{"10.1000/abc", "doi:10.1000/abc", true},
+		{"arxiv:2301.01234v3", "arxiv:2301.01234", true},
+		{"https://arxiv.org/pdf/2301.01234v2.pdf", "arxiv:2301.01234", true},
+		{"doi:10.48550/arXiv.2301.01234", "arxiv:2301.01234", true},
+		{"isbn:0-262-03384-4", "isbn:9780262033848", true},
+		{"urn:isbn:978-0-262-03384-8", "isbn:9780262033848", true},
+		{"isbn:0-262-03384-5", "", false},
+		{"pmid:0012345", "pmid:12345", true},
+		{"pmid:12a", "", false},
+		{"pmcid:pmc98765", "pmcid:PMC98765", true},
+		{"OpenAlex:W2741809807", "openalex:W2741809807", true},
+		{"no scheme", "", false},
+		{"bad scheme!:x", "", false},
+	}
+	for _, tc := range cases {
+		id, ok := meta.ParseIdentifier(tc.raw)
+		if ok != tc.ok || (ok && id.String() != tc.want) {
+			t.Fatalf("ParseIdentifier(%q) = %v, %v; want %q, %v", tc.raw, id, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestIdentifiersStoredAndQueried(t *testing.T) {
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	book := &meta.Metadata{
+		Path:        "/lib/book.epub",
+		Title:       "Introduction to Algorithms",
+		DOI:         "10.1000/ALGO",
+		Identifiers: []meta.Identifier{{Scheme: "isbn", Value: "0262033844"}},
+	}
+	if err := store.Upsert(ctx, book); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if got := meta.FormatIdentifiers(book.Identifiers); got != "doi:10.1000/algo, isbn:9780262033848" {
+		t.Fatalf("unexpected identifiers after upsert: %s", got)
+	}
+	if err := store.AddIdentifiers(ctx, "/lib/paper.pdf", []meta.Identifier{{Scheme: "arxiv", Value: "2301.01234v2"}}); err != nil {
+		t.Fatalf("add identifiers: %v", err)
+	}
+
+	for raw, want := range map[string]string{
+		"isbn:978-0-262-03384-8": "/lib/book.epub",
+		"doi:10.1000/algo":       "/lib/book.epub",
+		"arxiv:2301.01234":       "/lib/paper.pdf",
+	} {
+		id, _ := meta.ParseIdentifier(raw)
+		list, err := store.Query(ctx, meta.Filter{Identifiers: []meta.Identifier{id}})
+		if err != nil {
+			t.Fatalf("query %s: %v", raw, err)
+		}
+		if len(list) != 1 || list[0].Path != want {
+			t.Fatalf("query %s returned %+v, want %s", raw, list, want)
+		}
+	}
+
+	// Changing the DOI replaces its identifier; a nil list keeps the rest.
+	stored, err := store.Get(ctx, book.Path)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	stored.DOI = "10.1000/algo-3e"
+	stored.Identifiers = nil
+	if err := store.Upsert(ctx, stored); err != nil {
+		t.Fatalf("upsert doi change: %v", err)
+	}
+	stored, err = store.Get(ctx, book.Path)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got := meta.FormatIdentifiers(stored.Identifiers); got != "doi:10.1000/algo-3e, isbn:9780262033848" {
+		t.Fatalf("unexpected identifiers after doi change: %s", got)
+	}
+
+	history, err := store.History(ctx, book.Path)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	found := false
+	for _, rev := range history {
+		for _, c := range rev.Changes {
+			if c.Field == "identifiers" {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("expected an identifiers history entry, got %+v", history)
+	}
+}
@@ -0,0 +1,161 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// FileIdentity fingerprints the document behind a metadata row so the row can
+// be found again after the file was moved outside gorae. Size and ModTime let
+// callers skip re-hashing files that have not changed.
+type FileIdentity struct {
+	Hash    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Matches reports whether size and modTime still describe the fingerprinted
+// file, i.e. whether Hash can be trusted without re-reading the file.
+func (id FileIdentity) Matches(size int64, modTime time.Time) bool {
+	return id.Hash != "" && id.Size == size && id.ModTime.Unix() == modTime.Unix()
+}
+
+// Identity returns the recorded fingerprint of path. The boolean reports
+// whether a metadata row exists at all.
+func (s *Store) Identity(ctx context.Context, path string) (FileIdentity, bool, error) {
+	var (
+		hash  string
+		size  int64
+		mtime int64
+	)
+	err := s.db.QueryRowContext(ctx, `
+SELECT IFNULL(content_hash, ''), COALESCE(file_size, 0), COALESCE(file_mtime, 0)
+  FROM metadata WHERE path = ?`, path).Scan(&hash, &size, &mtime)
+	switch err {
+	case sql.ErrNoRows:
+		return FileIdentity{}, false, nil
+	case nil:
+	default:
+		return FileIdentity{}, false, err
+	}
+	id := FileIdentity{Hash: hash, Size: size}
+	if mtime > 0 {
+		id.ModTime = time.Unix(mtime, 0).UTC()
+	}
+	return id, true, nil
+}
+
+// SetIdentity records the fingerprint of an existing metadata row.
+func (s *Store) SetIdentity(ctx context.Context, path string, id FileIdentity) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE metadata SET content_hash = ?, file_size = ?, file_mtime = ? WHERE path = ?`,
+		id.Hash, id.Size, id.ModTime.Unix(), path,
+	)
+	return err
+}
+
+// PathsByHash returns every metadata path recorded with the given content
+// hash. Callers decide which of them no longer exist on disk.
+func (s *Store) PathsByHash(ctx context.Context, hash string) ([]string, error) {
+	if hash == "" {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT path FROM metadata WHERE content_hash = ? ORDER BY path`, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
+
+// AdoptPath moves the row of oldPath to newPath like MovePath. A row already
+// at newPath is replaced when it holds nothing but defaults, such as the one a
+// directory listing adds before the file is fingerprinted; when it holds
+// anything else AdoptPath changes nothing and returns false.
+func (s *Store) AdoptPath(ctx context.Context, oldPath, newPath string) (bool, error) {
+	if oldPath == newPath {
+		return false, nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var bare bool
+	err = tx.QueryRowContext(ctx, `SELECT `+bareRowExpr+` FROM metadata m WHERE path = ?`, newPath).Scan(&bare)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return false, err
+	case !bare:
+		return false, nil
+	default:
+		if _, err := tx.ExecContext(ctx, `DELETE FROM metadata WHERE path = ?`, newPath); err != nil {
+			return false, err
+		}
+	}
+	if err := movePathTx(ctx, tx, oldPath, newPath); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// BarePaths returns the paths of every row AdoptPath would replace.
+func (s *Store) BarePaths(ctx context.Context) (map[string]bool, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT path FROM metadata m WHERE `+bareRowExpr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	paths := make(map[string]bool)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths[path] = true
+	}
+	return paths, rows.Err()
+}
+
+// bareRowExpr is true for a metadata row m that nobody has filled in yet:
+// every field is at its default and no user data hangs off it.
+const bareRowExpr = `
+IFNULL(m.title, '') || IFNULL(m.author, '') || IFNULL(m.year, '') || IFNULL(m.published, '') ||
+IFNULL(m.url, '') || IFNULL(m.doi, '') || IFNULL(m.abstract, '') || IFNULL(m.tag, '') = ''
+AND IFNULL(m.priority, '') IN ('', 'normal')
+AND COALESCE(m.reading_state, '') IN ('', 'unread')
+AND COALESCE(m.favorite, 0) = 0 AND COALESCE(m.to_read, 0) = 0 AND COALESCE(m.rating, 0) = 0
+AND COALESCE(m.last_opened_at, 0) = 0
+AND COALESCE(m.progress_page, 0) = 0 AND COALESCE(m.progress_percent, 0) = 0
+AND NOT EXISTS (SELECT 1 FROM custom_fields WHERE path = m.path)
+AND NOT EXISTS (SELECT 1 FROM identifiers WHERE path = m.path)
+AND NOT EXISTS (SELECT 1 FROM paper_authors WHERE path = m.path)
+AND NOT EXISTS (SELECT 1 FROM paper_tags WHERE path = m.path)
+AND NOT EXISTS (SELECT 1 FROM collection_items WHERE path = m.path)
+AND NOT EXISTS (SELECT 1 FROM attachments WHERE owner = m.path)
+AND NOT EXISTS (SELECT 1 FROM reading_sessions WHERE path = m.path)`
+
+func migrateFileIdentity(ctx context.Context, tx *sql.Tx) error {
+	for _, col := range []struct{ name, typ string }{
+		{"content_hash", "TEXT"},
+		{"file_size", "INTEGER"},
+		{"file_mtime", "INTEGER"},
+	} {
+		if err := addColumnIfMissing(ctx, tx, "metadata", col.name, col.typ); err != nil {
+			return err
+		}
+	}
+	_, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS metadata_content_hash ON metadata(content_hash)`)
+	return err
+}
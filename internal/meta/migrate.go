@@ -0,0 +1,205 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSchemaTooNew is returned by Open when the database was written by a newer
+// gorae release that knows migrations this binary does not.
+var ErrSchemaTooNew = errors.New("metadata database schema is newer than this gorae build")
+
+// migration is a single, ordered schema step. Each step runs inside its own
+// transaction together with the schema_version bookkeeping, so a failure
+// leaves the database at the previous version.
+type migration struct {
+	version int
+	name    string
+	up      func(ctx context.Context, tx *sql.Tx) error
+}
+
+// migrations must stay sorted by version and never be edited once released;
+// add a new step instead.
+var migrations = []migration{
+	{version: 1, name: "baseline metadata table", up: migrateBaselineMetadata},
+	{version: 2, name: "normalized authors", up: migrateAuthors},
+	{version: 3, name: "tags", up: migrateTags},
+	{version: 4, name: "content hash identity", up: migrateFileIdentity},
+	{version: 5, name: "full-text index", up: migrateFullText},
+	{version: 6, name: "metadata history", up: migrateHistory},
+	{version: 7, name: "named collections", up: migrateCollections},
+	{version: 8, name: "custom fields", up: migrateCustomFields},
+	{version: 9, name: "reading sessions", up: migrateReadingSessions},
+	{version: 10, name: "rating and priority", up: migrateRatingPriority},
+	{version: 11, name: "citations", up: migrateCitations},
+	{version: 12, name: "row versions and change tracking", up: migrateRowVersions},
+	{version: 13, name: "attachments", up: migrateAttachments},
+	{version: 14, name: "identifiers", up: migrateIdentifiers},
+	{version: 15, name: "saved searches", up: migrateSavedSearches},
+	{version: 16, name: "substring full-text index", up: migrateFullTextTrigram},
+}
+
+// SchemaVersion is the schema version produced by the migrations compiled
+// into this build.
+func SchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].version
+}
+
+func (s *Store) migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS schema_version (
+  version    INTEGER PRIMARY KEY,
+  name       TEXT,
+  applied_at INTEGER
+);
+`); err != nil {
+		return fmt.Errorf("create schema_version: %w", err)
+	}
+
+	current, err := s.schemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if latest := SchemaVersion(); current > latest {
+		return fmt.Errorf("%w (database v%d, supported v%d)", ErrSchemaTooNew, current, latest)
+	}
+
+	for _, step := range migrations {
+		if step.version <= current {
+			continue
+		}
+		if err := s.applyMigration(ctx, step); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", step.version, step.name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) schemaVersion(ctx context.Context) (int, error) {
+	var version sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_version`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	if !version.Valid {
+		return 0, nil
+	}
+	return int(version.Int64), nil
+}
+
+// applyMigration runs step unless another process got there first: the
+// store opens transactions with BEGIN IMMEDIATE, so the version read here
+// cannot change before this transaction commits.
+func (s *Store) applyMigration(ctx context.Context, step migration) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var applied sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_version`).Scan(&applied); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	if applied.Valid && int(applied.Int64) >= step.version {
+		return nil
+	}
+	if err := step.up(ctx, tx); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)`,
+		step.version, step.name, time.Now().Unix(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrateBaselineMetadata creates the metadata table, or brings a table made
+// by a pre-migration gorae up to the baseline column set.
+func migrateBaselineMetadata(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS metadata (
+  path   TEXT PRIMARY KEY,
+  title  TEXT,
+  author TEXT,
+  year   TEXT,
+  published TEXT,
+  url    TEXT,
+  doi    TEXT,
+  abstract TEXT,
+  tag TEXT,
+  reading_state TEXT,
+  favorite INTEGER DEFAULT 0,
+  to_read INTEGER DEFAULT 0,
+  added_at INTEGER,
+  last_opened_at INTEGER
+);
+`); err != nil {
+		return err
+	}
+	legacyColumns := []struct {
+		name string
+		typ  string
+	}{
+		{"favorite", "INTEGER DEFAULT 0"},
+		{"to_read", "INTEGER DEFAULT 0"},
+		{"reading_state", "TEXT"},
+		{"published", "TEXT"},
+		{"url", "TEXT"},
+		{"doi", "TEXT"},
+		{"abstract", "TEXT"},
+		{"tag", "TEXT"},
+		{"added_at", "INTEGER"},
+		{"last_opened_at", "INTEGER"},
+	}
+	for _, col := range legacyColumns {
+		if err := addColumnIfMissing(ctx, tx, "metadata", col.name, col.typ); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			typ       string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dfltValue, &pk); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+func addColumnIfMissing(ctx context.Context, tx *sql.Tx, table, name, typ string) error {
+	columns, err := tableColumns(ctx, tx, table)
+	if err != nil {
+		return err
+	}
+	if columns[name] {
+		return nil
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, name, typ))
+	return err
+}
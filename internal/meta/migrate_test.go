@@ -0,0 +1,136 @@
+package meta_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestOpenUpgradesLegacyMetadataTable(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "meta.db")
+
+	raw, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open raw db: %v", err)
+	}
+	if _, err := raw.Exec(`CREATE TABLE metadata (path TEXT PRIMARY KEY, title TEXT, author TEXT, year TEXT)`); err != nil {
+		t.Fatalf("create legacy table: %v", err)
+	}
+	if _, err := raw.Exec(`INSERT INTO metadata (path, title) VALUES ('/tmp/legacy.pdf', 'Legacy')`); err != nil {
+		t.Fatalf("insert legacy row: %v", err)
+	}
+	raw.Close()
+
+	store, err := meta.Open(dbPath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	ctx := context.Background()
+	md, err := store.Get(ctx, "/tmp/legacy.pdf")
+	if err != nil || md == nil {
+		t.Fatalf("expected legacy row, got %v err=%v", md, err)
+	}
+	if md.Title != "Legacy" {
+		t.Fatalf("expected legacy title preserved, got %q", md.Title)
+	}
+	md.DOI = "10.1000/xyz"
+	md.Favorite = true
+	if err := store.Upsert(ctx, md); err != nil {
+		t.Fatalf("upsert after upgrade: %v", err)
+	}
+}
+
+func TestOpenIsIdempotent(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "meta.db")
+	for i := 0; i < 2; i++ {
+		store, err := meta.Open(dbPath)
+		if err != nil {
+			t.Fatalf("open #%d: %v", i+1, err)
+		}
+		store.Close()
+	}
+
+	raw, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open raw db: %v", err)
+	}
+	defer raw.Close()
+	var version, rows int
+	if err := raw.QueryRow(`SELECT MAX(version), COUNT(*) FROM schema_version`).Scan(&version, &rows); err != nil {
+		t.Fatalf("read schema_version: %v", err)
+	}
+	if version != meta.SchemaVersion() {
+		t.Fatalf("expected schema version %d, got %d", meta.SchemaVersion(), version)
+	}
+	if rows != meta.SchemaVersion() {
+		t.Fatalf("expected one row per migration, got %d", rows)
+	}
+}
+
+func TestOpenRefusesNewerSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "meta.db")
+	store, err := meta.Open(dbPath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	store.Close()
+
+	raw, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open raw db: %v", err)
+	}
+	if _, err := raw.Exec(`INSERT INTO schema_version (version, name) VALUES (?, 'future')`, meta.SchemaVersion()+1); err != nil {
+		t.Fatalf("bump schema version: %v", err)
+	}
+	raw.Close()
+
+	if _, err := meta.Open(dbPath); !errors.Is(err, meta.ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestConcurrentOpenMigratesOnce(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "meta.db")
+	const processes = 4
+	errs := make(chan error, processes)
+	var wg sync.WaitGroup
+	for i := 0; i < processes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store, err := meta.Open(dbPath)
+			if err == nil {
+				err = store.Close()
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent open: %v", err)
+		}
+	}
+
+	raw, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open raw db: %v", err)
+	}
+	defer raw.Close()
+	var rows int
+	if err := raw.QueryRow(`SELECT COUNT(*) FROM schema_version`).Scan(&rows); err != nil {
+		t.Fatalf("count versions: %v", err)
+	}
+	if rows != meta.SchemaVersion() {
+		t.Fatalf("expected each migration recorded once, got %d rows", rows)
+	}
+}
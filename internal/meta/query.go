@@ -0,0 +1,265 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SortField names a column Query can order by.
+type SortField string
+
+const (
+	SortTitle  SortField = "title"
+	SortYear   SortField = "year"
+	SortAdded  SortField = "added"
+	SortOpened SortField = "opened"
+	SortPath   SortField = "path"
+	// SortRating and SortPriority order from lowest to highest; use Desc
+	// to put the best rated or most pressing papers first.
+	SortRating   SortField = "rating"
+	SortPriority SortField = "priority"
+)
+
+// Sort is one ordering key of a Query.
+type Sort struct {
+	Field SortField
+	Desc  bool
+}
+
+// Filter selects papers for Query. Every predicate that is set must hold;
+// zero values impose no constraint. Text predicates match substrings
+// case-insensitively. Range bounds ending in After/Min are inclusive, those
+// ending in Before are exclusive and YearMax is inclusive.
+type Filter struct {
+	// PathPrefix limits results to papers below this directory.
+	PathPrefix string
+
+	Title  string
+	Author string // matched against each author on its own
+	DOI    string
+
+	Favorite bool
+	ToRead   bool
+	// States limits results to any of the given reading states.
+	States []string
+	// RatingMin requires at least this many stars.
+	RatingMin int
+	// Priorities limits results to any of the given priorities.
+	Priorities []string
+
+	YearMin int
+	YearMax int
+
+	AddedAfter   time.Time
+	AddedBefore  time.Time
+	OpenedAfter  time.Time
+	OpenedBefore time.Time
+
+	// Tags must all be present; each also matches its descendants.
+	Tags []string
+	// Fields requires custom fields to equal the given values.
+	Fields map[string]string
+	// Identifiers must all be recorded for a paper, e.g. arxiv:2301.01234.
+	Identifiers []Identifier
+
+	// Sort defaults to title then path. Path is always the final tiebreaker.
+	Sort   []Sort
+	Limit  int
+	Offset int
+}
+
+// LeadingYear reads the year from the leading digits of a stored year such as
+// "2020" or "2020-05", the way Query's year bounds do. ok is false when the
+// trimmed year does not start with a digit.
+func LeadingYear(year string) (int, bool) {
+	year = strings.TrimSpace(year)
+	n := 0
+	for n < len(year) && year[n] >= '0' && year[n] <= '9' {
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	value, err := strconv.Atoi(year[:n])
+	return value, err == nil
+}
+
+// Query returns the papers matching f in a single SQL query.
+func (s *Store) Query(ctx context.Context, f Filter) ([]Metadata, error) {
+	clause, args, err := f.sql()
+	if err != nil {
+		return nil, err
+	}
+	return s.listWhere(ctx, clause, args...)
+}
+
+func (f Filter) sql() (string, []any, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, values ...any) {
+		where = append(where, cond)
+		args = append(args, values...)
+	}
+	contains := func(value string) string {
+		return "%" + escapeLike(strings.TrimSpace(value)) + "%"
+	}
+
+	if strings.TrimSpace(f.PathPrefix) != "" {
+		prefix, err := normalizeDirPrefix(f.PathPrefix)
+		if err != nil {
+			return "", nil, err
+		}
+		add(`path LIKE ? ESCAPE '\'`, escapeLike(prefix)+"%")
+	}
+	if strings.TrimSpace(f.Title) != "" {
+		add(`IFNULL(title, '') LIKE ? ESCAPE '\'`, contains(f.Title))
+	}
+	if strings.TrimSpace(f.Author) != "" {
+		pattern := contains(f.Author)
+		add(`(path IN (
+   SELECT pa.path
+     FROM paper_authors pa
+     JOIN authors a ON a.id = pa.author_id
+    WHERE a.full_name LIKE ? ESCAPE '\'
+ ) OR (IFNULL(author, '') LIKE ? ESCAPE '\' AND path NOT IN (SELECT path FROM paper_authors)))`, pattern, pattern)
+	}
+	if strings.TrimSpace(f.DOI) != "" {
+		add(`IFNULL(doi, '') LIKE ? ESCAPE '\'`, contains(f.DOI))
+	}
+	if f.Favorite {
+		add(`COALESCE(favorite, 0) = 1`)
+	}
+	if f.ToRead {
+		add(`COALESCE(to_read, 0) = 1`)
+	}
+	if len(f.States) > 0 {
+		marks := make([]string, len(f.States))
+		for i, state := range f.States {
+			marks[i] = "?"
+			args = append(args, normalizeReadingState(state))
+		}
+		where = append(where, `LOWER(IFNULL(reading_state, '`+defaultReadingState+`')) IN (`+strings.Join(marks, ", ")+`)`)
+	}
+	if f.RatingMin > 0 {
+		add(`COALESCE(rating, 0) >= ?`, f.RatingMin)
+	}
+	if len(f.Priorities) > 0 {
+		marks := make([]string, len(f.Priorities))
+		for i, priority := range f.Priorities {
+			marks[i] = "?"
+			args = append(args, PriorityRank(priority))
+		}
+		where = append(where, priorityRankExpr+` IN (`+strings.Join(marks, ", ")+`)`)
+	}
+	// Years are compared by their leading digits, as LeadingYear reads them;
+	// a year without any is NULL and matches no bound.
+	const yearExpr = `CASE WHEN TRIM(IFNULL(year, '')) GLOB '[0-9]*' THEN CAST(TRIM(year) AS INTEGER) END`
+	if f.YearMin != 0 {
+		add(yearExpr+` >= ?`, f.YearMin)
+	}
+	if f.YearMax != 0 {
+		add(yearExpr+` <= ?`, f.YearMax)
+	}
+	if !f.AddedAfter.IsZero() {
+		add(`COALESCE(added_at, 0) >= ?`, f.AddedAfter.Unix())
+	}
+	if !f.AddedBefore.IsZero() {
+		add(`COALESCE(added_at, 0) > 0 AND added_at < ?`, f.AddedBefore.Unix())
+	}
+	if !f.OpenedAfter.IsZero() {
+		add(`COALESCE(last_opened_at, 0) >= ?`, f.OpenedAfter.Unix())
+	}
+	if !f.OpenedBefore.IsZero() {
+		add(`COALESCE(last_opened_at, 0) > 0 AND last_opened_at < ?`, f.OpenedBefore.Unix())
+	}
+	for _, tag := range f.Tags {
+		key := tagNameKey(tag)
+		if key == "" {
+			continue
+		}
+		add(`path IN (
+   SELECT pt.path
+     FROM paper_tags pt
+     JOIN tags t ON t.id = pt.tag_id
+    WHERE t.name_key = ? OR t.name_key LIKE ? ESCAPE '\'
+ )`, key, escapeLike(key+TagSeparator)+"%")
+	}
+	names := make([]string, 0, len(f.Fields))
+	for name := range f.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(`path IN (SELECT path FROM custom_fields WHERE name = ? COLLATE NOCASE AND value = ? COLLATE NOCASE)`,
+			normalizeName(name), strings.TrimSpace(f.Fields[name]))
+	}
+	for _, raw := range f.Identifiers {
+		id, ok := NormalizeIdentifier(raw.Scheme, raw.Value)
+		if !ok {
+			return "", nil, fmt.Errorf("invalid identifier %s", raw)
+		}
+		add(`path IN (SELECT path FROM identifiers WHERE scheme = ? AND value = ?)`, id.Scheme, id.Value)
+	}
+
+	var b strings.Builder
+	if len(where) > 0 {
+		b.WriteString("\n WHERE ")
+		b.WriteString(strings.Join(where, "\n   AND "))
+	}
+	order, err := f.orderBy()
+	if err != nil {
+		return "", nil, err
+	}
+	b.WriteString("\n ORDER BY ")
+	b.WriteString(order)
+	switch {
+	case f.Limit > 0:
+		b.WriteString("\n LIMIT ? OFFSET ?")
+		args = append(args, f.Limit, f.Offset)
+	case f.Offset > 0:
+		b.WriteString("\n LIMIT -1 OFFSET ?")
+		args = append(args, f.Offset)
+	}
+	return b.String(), args, nil
+}
+
+func (f Filter) orderBy() (string, error) {
+	keys := f.Sort
+	if len(keys) == 0 {
+		keys = []Sort{{Field: SortTitle}}
+	}
+	parts := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		var expr string
+		switch key.Field {
+		case SortTitle:
+			expr = "LOWER(IFNULL(title, ''))"
+		case SortYear:
+			expr = "CAST(NULLIF(TRIM(IFNULL(year, '')), '') AS INTEGER)"
+		case SortAdded:
+			expr = "COALESCE(added_at, 0)"
+		case SortOpened:
+			expr = "COALESCE(last_opened_at, 0)"
+		case SortPath:
+			expr = "path"
+		case SortRating:
+			expr = "COALESCE(rating, 0)"
+		case SortPriority:
+			expr = priorityRankExpr
+		default:
+			return "", fmt.Errorf("unknown sort field %q", key.Field)
+		}
+		if key.Desc {
+			expr += " DESC"
+		}
+		parts = append(parts, expr)
+	}
+	parts = append(parts, "path")
+	return strings.Join(parts, ", "), nil
+}
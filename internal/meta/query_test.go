@@ -0,0 +1,114 @@
+package meta_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorae/internal/meta"
+)
+
+func TestQueryCombinesPredicates(t *testing.T) {
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	papers := []meta.Metadata{
+		{Path: "/lib/ml/attention.pdf", Title: "Attention Is All You Need", Author: "Ashish Vaswani and Noam Shazeer", Year: "2017", Tag: "ml/nlp", Favorite: true, Rating: 5, Priority: "high", ReadingState: "read", AddedAt: time.Unix(1000, 0)},
+		{Path: "/lib/ml/bert.pdf", Title: "BERT", Author: "Jacob Devlin", Year: "2019", Tag: "ml/nlp, pretraining", ToRead: true, Rating: 3, Priority: "Urgent", AddedAt: time.Unix(2000, 0), Fields: map[string]string{"dataset": "Wikipedia"}},
+		{Path: "/lib/ml/resnet.pdf", Title: "Deep Residual Learning", Author: "Kaiming He", Year: "2016", Tag: "ml/vision", Favorite: true, Rating: 4, ReadingState: "reading", AddedAt: time.Unix(3000, 0), Fields: map[string]string{"dataset": "ImageNet"}},
+		{Path: "/other/notes.pdf", Title: "Attention notes", Year: "2020", Tag: "ml", AddedAt: time.Unix(4000, 0)},
+	}
+	for i := range papers {
+		if err := store.Upsert(ctx, &papers[i]); err != nil {
+			t.Fatalf("upsert %s: %v", papers[i].Path, err)
+		}
+	}
+	if err := store.RecordOpened(ctx, "/lib/ml/bert.pdf", time.Unix(5000, 0)); err != nil {
+		t.Fatalf("record opened: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		filter meta.Filter
+		want   []string
+	}{
+		{"path prefix", meta.Filter{PathPrefix: "/lib"}, []string{"/lib/ml/attention.pdf", "/lib/ml/bert.pdf", "/lib/ml/resnet.pdf"}},
+		{"title", meta.Filter{Title: "attention"}, []string{"/lib/ml/attention.pdf", "/other/notes.pdf"}},
+		{"single author", meta.Filter{Author: "shazeer"}, []string{"/lib/ml/attention.pdf"}},
+		{"author does not span names", meta.Filter{Author: "Vaswani and"}, nil},
+		{"favorite", meta.Filter{Favorite: true}, []string{"/lib/ml/attention.pdf", "/lib/ml/resnet.pdf"}},
+		{"states", meta.Filter{States: []string{"read", "reading"}}, []string{"/lib/ml/attention.pdf", "/lib/ml/resnet.pdf"}},
+		{"year range", meta.Filter{YearMin: 2017, YearMax: 2019}, []string{"/lib/ml/attention.pdf", "/lib/ml/bert.pdf"}},
+		{"added range", meta.Filter{AddedAfter: time.Unix(2000, 0), AddedBefore: time.Unix(4000, 0)}, []string{"/lib/ml/bert.pdf", "/lib/ml/resnet.pdf"}},
+		{"opened", meta.Filter{OpenedAfter: time.Unix(4500, 0)}, []string{"/lib/ml/bert.pdf"}},
+		{"hierarchical tags", meta.Filter{Tags: []string{"ml", "pretraining"}}, []string{"/lib/ml/bert.pdf"}},
+		{"custom field", meta.Filter{Fields: map[string]string{"Dataset": "imagenet"}}, []string{"/lib/ml/resnet.pdf"}},
+		{"rating", meta.Filter{RatingMin: 4}, []string{"/lib/ml/attention.pdf", "/lib/ml/resnet.pdf"}},
+		{"priorities", meta.Filter{Priorities: []string{"high", "urgent"}}, []string{"/lib/ml/attention.pdf", "/lib/ml/bert.pdf"}},
+		{"normal priority", meta.Filter{Priorities: []string{"normal"}}, []string{"/other/notes.pdf", "/lib/ml/resnet.pdf"}},
+		{"sort by priority", meta.Filter{Sort: []meta.Sort{{Field: meta.SortPriority, Desc: true}}}, []string{"/lib/ml/bert.pdf", "/lib/ml/attention.pdf", "/lib/ml/resnet.pdf", "/other/notes.pdf"}},
+		{"sort by rating", meta.Filter{Sort: []meta.Sort{{Field: meta.SortRating, Desc: true}}}, []string{"/lib/ml/attention.pdf", "/lib/ml/resnet.pdf", "/lib/ml/bert.pdf", "/other/notes.pdf"}},
+		{"sort and page", meta.Filter{Sort: []meta.Sort{{Field: meta.SortYear, Desc: true}}, Limit: 2, Offset: 1}, []string{"/lib/ml/bert.pdf", "/lib/ml/attention.pdf"}},
+	}
+	for _, tc := range cases {
+		got, err := store.Query(ctx, tc.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		paths := make([]string, 0, len(got))
+		for _, md := range got {
+			paths = append(paths, md.Path)
+		}
+		if len(paths) != len(tc.want) {
+			t.Fatalf("%s: got %v, want %v", tc.name, paths, tc.want)
+		}
+		for i := range paths {
+			if paths[i] != tc.want[i] {
+				t.Fatalf("%s: got %v, want %v", tc.name, paths, tc.want)
+			}
+		}
+	}
+
+	if _, err := store.Query(ctx, meta.Filter{Sort: []meta.Sort{{Field: "size"}}}); err == nil {
+		t.Fatalf("expected an error for an unknown sort field")
+	}
+}
+
+func TestRatingAndPriorityAreNormalized(t *testing.T) {
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	md := meta.Metadata{Path: "/lib/paper.pdf", Title: "Paper", Rating: 9, Priority: " HIGH "}
+	if err := store.Upsert(ctx, &md); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	got, err := store.Get(ctx, md.Path)
+	if err != nil || got == nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Rating != meta.MaxRating || got.Priority != meta.PriorityHigh {
+		t.Fatalf("expected rating %d and priority high, got %d and %q", meta.MaxRating, got.Rating, got.Priority)
+	}
+
+	got.Priority = "someday"
+	got.Rating = 0
+	if err := store.Upsert(ctx, got); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	got, err = store.Get(ctx, md.Path)
+	if err != nil || got == nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Rating != 0 || got.Priority != meta.PriorityNormal {
+		t.Fatalf("expected an unrated normal paper, got %d and %q", got.Rating, got.Priority)
+	}
+}
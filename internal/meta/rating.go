@@ -0,0 +1,62 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// MaxRating is the highest star rating; 0 means unrated.
+const MaxRating = 5
+
+// Triage priorities, lowest first. Papers without a priority are normal.
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// Priorities lists the priorities from lowest to highest.
+var Priorities = []string{PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent}
+
+// NormalizePriority maps value to one of Priorities. An empty value is
+// normal; ok is false for anything that is not a priority.
+func NormalizePriority(value string) (priority string, ok bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return PriorityNormal, true
+	}
+	for _, p := range Priorities {
+		if value == p {
+			return p, true
+		}
+	}
+	return PriorityNormal, false
+}
+
+// PriorityRank orders priorities from 0 (low) to 3 (urgent).
+func PriorityRank(priority string) int {
+	priority, _ = NormalizePriority(priority)
+	for i, p := range Priorities {
+		if p == priority {
+			return i
+		}
+	}
+	return 1
+}
+
+func clampRating(rating int) int {
+	return max(0, min(rating, MaxRating))
+}
+
+// priorityRankExpr ranks the priority column like PriorityRank.
+const priorityRankExpr = `CASE LOWER(IFNULL(priority, ''))
+    WHEN 'low' THEN 0 WHEN 'high' THEN 2 WHEN 'urgent' THEN 3 ELSE 1 END`
+
+func migrateRatingPriority(ctx context.Context, tx *sql.Tx) error {
+	if err := addColumnIfMissing(ctx, tx, "metadata", "rating", "INTEGER"); err != nil {
+		return err
+	}
+	return addColumnIfMissing(ctx, tx, "metadata", "priority", "TEXT")
+}
@@ -0,0 +1,126 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrSavedSearchNotFound is returned when a saved search does not exist.
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+// SavedSearch is a search query kept under a name. Query is the search
+// command line as typed, flags included.
+type SavedSearch struct {
+	Name      string
+	Query     string
+	CreatedAt time.Time
+}
+
+// NormalizeSavedSearchName trims name and rejects names that cannot double
+// as a helper folder name.
+func NormalizeSavedSearchName(name string) (string, error) {
+	return normalizeFolderName("saved search", name)
+}
+
+// SaveSearch stores query under name, replacing the query of an existing
+// saved search with that name (names compare case-insensitively).
+func (s *Store) SaveSearch(ctx context.Context, name, query string) error {
+	name, err := NormalizeSavedSearchName(name)
+	if err != nil {
+		return err
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return fmt.Errorf("saved search %q needs a query", name)
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO saved_searches (name, query, created_at) VALUES (?, ?, ?)
+ON CONFLICT(name) DO UPDATE SET query = excluded.query`,
+		name, query, time.Now().Unix(),
+	)
+	return err
+}
+
+// DeleteSavedSearch removes the named saved search.
+func (s *Store) DeleteSavedSearch(ctx context.Context, name string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM saved_searches WHERE name = ?`, normalizeName(name))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %s", ErrSavedSearchNotFound, name)
+	}
+	return nil
+}
+
+// GetSavedSearch returns the named saved search.
+func (s *Store) GetSavedSearch(ctx context.Context, name string) (SavedSearch, error) {
+	var (
+		saved   SavedSearch
+		created sql.NullInt64
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT name, query, created_at FROM saved_searches WHERE name = ?`, normalizeName(name),
+	).Scan(&saved.Name, &saved.Query, &created)
+	if err == sql.ErrNoRows {
+		return SavedSearch{}, fmt.Errorf("%w: %s", ErrSavedSearchNotFound, name)
+	}
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	if created.Valid {
+		saved.CreatedAt = time.Unix(created.Int64, 0)
+	}
+	return saved, nil
+}
+
+// ListSavedSearches returns every saved search by name.
+func (s *Store) ListSavedSearches(ctx context.Context) ([]SavedSearch, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT name, query, created_at FROM saved_searches ORDER BY LOWER(name)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := make([]SavedSearch, 0)
+	for rows.Next() {
+		var (
+			saved   SavedSearch
+			created sql.NullInt64
+		)
+		if err := rows.Scan(&saved.Name, &saved.Query, &created); err != nil {
+			return nil, err
+		}
+		if created.Valid {
+			saved.CreatedAt = time.Unix(created.Int64, 0)
+		}
+		results = append(results, saved)
+	}
+	return results, rows.Err()
+}
+
+func migrateSavedSearches(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS saved_searches (
+  name       TEXT PRIMARY KEY COLLATE NOCASE,
+  query      TEXT NOT NULL,
+  created_at INTEGER
+);
+CREATE TRIGGER IF NOT EXISTS saved_searches_insert_generation AFTER INSERT ON saved_searches
+BEGIN
+  UPDATE store_generation SET generation = generation + 1 WHERE id = 1;
+END;
+CREATE TRIGGER IF NOT EXISTS saved_searches_update_generation AFTER UPDATE ON saved_searches
+BEGIN
+  UPDATE store_generation SET generation = generation + 1 WHERE id = 1;
+END;
+CREATE TRIGGER IF NOT EXISTS saved_searches_delete_generation AFTER DELETE ON saved_searches
+BEGIN
+  UPDATE store_generation SET generation = generation + 1 WHERE id = 1;
+END;
+`)
+	return err
+}
@@ -0,0 +1,67 @@
+package meta_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestSavedSearches(t *testing.T) {
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	before, err := store.Generation(ctx)
+	if err != nil {
+		t.Fatalf("generation: %v", err)
+	}
+	if err := store.SaveSearch(ctx, "recent nlp", "--tag nlp year:>=2020"); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := store.SaveSearch(ctx, "Bengio", "-a bengio"); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := store.SaveSearch(ctx, "RECENT NLP", "--tag nlp year:>=2021"); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	if err := store.SaveSearch(ctx, "a/b", "x"); err == nil {
+		t.Fatalf("expected slash in name to be rejected")
+	}
+	if err := store.SaveSearch(ctx, "empty", "  "); err == nil {
+		t.Fatalf("expected an empty query to be rejected")
+	}
+	if after, _ := store.Generation(ctx); after == before {
+		t.Fatalf("expected saving to move the store generation")
+	}
+
+	list, err := store.ListSavedSearches(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "Bengio" || list[1].Name != "recent nlp" {
+		t.Fatalf("unexpected saved searches %+v", list)
+	}
+	saved, err := store.GetSavedSearch(ctx, "Recent NLP")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if saved.Query != "--tag nlp year:>=2021" || saved.CreatedAt.IsZero() {
+		t.Fatalf("unexpected saved search %+v", saved)
+	}
+
+	if err := store.DeleteSavedSearch(ctx, "bengio"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := store.DeleteSavedSearch(ctx, "bengio"); !errors.Is(err, meta.ErrSavedSearchNotFound) {
+		t.Fatalf("expected ErrSavedSearchNotFound, got %v", err)
+	}
+	if _, err := store.GetSavedSearch(ctx, "bengio"); !errors.Is(err, meta.ErrSavedSearchNotFound) {
+		t.Fatalf("expected ErrSavedSearchNotFound, got %v", err)
+	}
+}
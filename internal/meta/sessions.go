@@ -0,0 +1,215 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReadingSummary sums up the reading sessions of one paper.
+type ReadingSummary struct {
+	Sessions int
+	Total    time.Duration
+	LastAt   time.Time
+	LastPage int
+}
+
+// ReadingTotal is the time spent reading under one key of ReadingStats: a
+// paper path, a tag or a week ("2026-W07").
+type ReadingTotal struct {
+	Key      string
+	Label    string
+	Sessions int
+	Total    time.Duration
+}
+
+// ReadingStats breaks the recorded reading time down per paper, tag and
+// week, largest first (weeks newest first).
+type ReadingStats struct {
+	Papers []ReadingTotal
+	Tags   []ReadingTotal
+	Weeks  []ReadingTotal
+}
+
+// StartSession records that path was opened at start and returns the session
+// id to pass to FinishSession. A bare metadata row is created for files gorae
+// has not seen yet.
+func (s *Store) StartSession(ctx context.Context, path string, start time.Time) (int64, error) {
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO metadata (path, reading_state, added_at) VALUES (?, ?, ?) ON CONFLICT(path) DO NOTHING`,
+		path, defaultReadingState, start.Unix(),
+	); err != nil {
+		return 0, err
+	}
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO reading_sessions (path, started_at, duration) VALUES (?, ?, 0)`,
+		path, start.Unix(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// FinishSession stores how long the session ran.
+func (s *Store) FinishSession(ctx context.Context, id int64, duration time.Duration) error {
+	if duration < 0 {
+		duration = 0
+	}
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE reading_sessions SET duration = ? WHERE id = ?`, int64(duration/time.Second), id)
+	return err
+}
+
+// SetProgress stores the reading progress of path. page or percent may be 0
+// when unknown; percent is clamped to 0..100. The page is also recorded as
+// the last page of the latest session.
+func (s *Store) SetProgress(ctx context.Context, path string, page, percent int) error {
+	if page < 0 {
+		return fmt.Errorf("page must not be negative")
+	}
+	percent = max(0, min(percent, 100))
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO metadata (path, reading_state, added_at, progress_page, progress_percent)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(path) DO UPDATE SET
+  progress_page    = excluded.progress_page,
+  progress_percent = excluded.progress_percent`,
+		path, defaultReadingState, time.Now().Unix(), page, percent,
+	); err != nil {
+		return err
+	}
+	if page > 0 {
+		if _, err := tx.ExecContext(ctx, `
+UPDATE reading_sessions SET last_page = ?
+ WHERE id = (SELECT MAX(id) FROM reading_sessions WHERE path = ?)`, page, path); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ReadingSummary returns the session totals of path.
+func (s *Store) ReadingSummary(ctx context.Context, path string) (ReadingSummary, error) {
+	var (
+		sum      ReadingSummary
+		total    int64
+		lastAt   int64
+		lastPage int64
+	)
+	err := s.db.QueryRowContext(ctx, `
+SELECT COUNT(*), COALESCE(SUM(duration), 0), COALESCE(MAX(started_at), 0),
+       COALESCE((SELECT last_page FROM reading_sessions
+                  WHERE path = ? AND last_page IS NOT NULL
+                  ORDER BY started_at DESC, id DESC LIMIT 1), 0)
+  FROM reading_sessions
+ WHERE path = ?`, path, path).Scan(&sum.Sessions, &total, &lastAt, &lastPage)
+	if err != nil {
+		return ReadingSummary{}, err
+	}
+	sum.Total = time.Duration(total) * time.Second
+	if lastAt > 0 {
+		sum.LastAt = time.Unix(lastAt, 0).UTC()
+	}
+	sum.LastPage = int(lastPage)
+	return sum, nil
+}
+
+// ReadingStats aggregates the sessions started at or after since (all of
+// them when since is zero). Each list is capped at limit entries when limit
+// is positive.
+func (s *Store) ReadingStats(ctx context.Context, since time.Time, limit int) (ReadingStats, error) {
+	var stats ReadingStats
+	from := int64(0)
+	if !since.IsZero() {
+		from = since.Unix()
+	}
+	queries := []struct {
+		dest  *[]ReadingTotal
+		query string
+	}{
+		{&stats.Papers, `
+SELECT s.path, IFNULL(m.title, ''), COUNT(*), SUM(s.duration)
+  FROM reading_sessions s
+  JOIN metadata m ON m.path = s.path
+ WHERE s.started_at >= ?
+ GROUP BY s.path
+ ORDER BY SUM(s.duration) DESC, s.path`},
+		{&stats.Tags, `
+SELECT t.name_key, t.name, COUNT(*), SUM(s.duration)
+  FROM reading_sessions s
+  JOIN paper_tags pt ON pt.path = s.path
+  JOIN tags t ON t.id = pt.tag_id
+ WHERE s.started_at >= ?
+ GROUP BY t.id
+ ORDER BY SUM(s.duration) DESC, t.name_key`},
+		{&stats.Weeks, `
+SELECT strftime('%Y-W%W', s.started_at, 'unixepoch', 'localtime') AS week, '', COUNT(*), SUM(s.duration)
+  FROM reading_sessions s
+ WHERE s.started_at >= ?
+ GROUP BY week
+ ORDER BY week DESC`},
+	}
+	for _, q := range queries {
+		query := q.query
+		if limit > 0 {
+			query += fmt.Sprintf("\n LIMIT %d", limit)
+		}
+		totals, err := s.readingTotals(ctx, query, from)
+		if err != nil {
+			return ReadingStats{}, err
+		}
+		*q.dest = totals
+	}
+	return stats, nil
+}
+
+func (s *Store) readingTotals(ctx context.Context, query string, args ...any) ([]ReadingTotal, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	totals := make([]ReadingTotal, 0)
+	for rows.Next() {
+		var (
+			t       ReadingTotal
+			seconds int64
+		)
+		if err := rows.Scan(&t.Key, &t.Label, &t.Sessions, &seconds); err != nil {
+			return nil, err
+		}
+		t.Label = strings.TrimSpace(t.Label)
+		t.Total = time.Duration(seconds) * time.Second
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+func migrateReadingSessions(ctx context.Context, tx *sql.Tx) error {
+	for _, col := range []string{"progress_page", "progress_percent"} {
+		if err := addColumnIfMissing(ctx, tx, "metadata", col, "INTEGER"); err != nil {
+			return err
+		}
+	}
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS reading_sessions (
+  id         INTEGER PRIMARY KEY,
+  path       TEXT NOT NULL REFERENCES metadata(path) ON UPDATE CASCADE ON DELETE CASCADE,
+  started_at INTEGER NOT NULL,
+  duration   INTEGER NOT NULL DEFAULT 0,
+  last_page  INTEGER
+);
+CREATE INDEX IF NOT EXISTS reading_sessions_path ON reading_sessions(path, started_at);
+CREATE INDEX IF NOT EXISTS reading_sessions_started ON reading_sessions(started_at);
+`)
+	return err
+}
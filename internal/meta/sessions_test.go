@@ -0,0 +1,86 @@
+package meta_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorae/internal/meta"
+)
+
+func TestReadingSessionsAndProgress(t *testing.T) {
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	bert := "/lib/bert.pdf"
+	if err := store.Upsert(ctx, &meta.Metadata{Path: bert, Title: "BERT", Tag: "ml/nlp"}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local)
+	for i, d := range []time.Duration{20 * time.Minute, 40 * time.Minute} {
+		id, err := store.StartSession(ctx, bert, start.Add(time.Duration(i)*24*time.Hour))
+		if err != nil {
+			t.Fatalf("start session: %v", err)
+		}
+		if err := store.FinishSession(ctx, id, d); err != nil {
+			t.Fatalf("finish session: %v", err)
+		}
+	}
+	// Opening an unknown file creates its row.
+	other := "/lib/other.pdf"
+	id, err := store.StartSession(ctx, other, start.AddDate(0, 0, 14))
+	if err != nil {
+		t.Fatalf("start unknown: %v", err)
+	}
+	if err := store.FinishSession(ctx, id, 5*time.Minute); err != nil {
+		t.Fatalf("finish unknown: %v", err)
+	}
+
+	if err := store.SetProgress(ctx, bert, 42, 14); err != nil {
+		t.Fatalf("set progress: %v", err)
+	}
+	md, err := store.Get(ctx, bert)
+	if err != nil || md.ProgressPage != 42 || md.ProgressPercent != 14 {
+		t.Fatalf("unexpected progress: %+v (%v)", md, err)
+	}
+	// Progress is not part of a regular metadata save.
+	md.Title = "BERT (edited)"
+	if err := store.Upsert(ctx, md); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if md, _ = store.Get(ctx, bert); md.ProgressPage != 42 {
+		t.Fatalf("upsert dropped progress: %+v", md)
+	}
+
+	sum, err := store.ReadingSummary(ctx, bert)
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+	if sum.Sessions != 2 || sum.Total != time.Hour || sum.LastPage != 42 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+
+	stats, err := store.ReadingStats(ctx, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if len(stats.Papers) != 2 || stats.Papers[0].Key != bert || stats.Papers[0].Total != time.Hour {
+		t.Fatalf("unexpected paper totals: %+v", stats.Papers)
+	}
+	if len(stats.Tags) != 1 || stats.Tags[0].Label != "ml/nlp" || stats.Tags[0].Sessions != 2 {
+		t.Fatalf("unexpected tag totals: %+v", stats.Tags)
+	}
+	if len(stats.Weeks) != 2 || stats.Weeks[0].Total != 5*time.Minute || stats.Weeks[1].Total != time.Hour {
+		t.Fatalf("unexpected week totals: %+v", stats.Weeks)
+	}
+
+	recent, err := store.ReadingStats(ctx, start.AddDate(0, 0, 7), 0)
+	if err != nil || len(recent.Papers) != 1 || recent.Papers[0].Key != other {
+		t.Fatalf("expected only the recent session, got %+v (%v)", recent.Papers, err)
+	}
+}
@@ -17,6 +17,7 @@ type Metadata struct {
 	Path         string
 	Title        string
 	Author       string
+	Authors      []Author
 	Year         string
 	Published    string
 	URL          string
@@ -25,9 +26,27 @@ type Metadata struct {
 	Tag          string
 	Favorite     bool
 	ToRead       bool
+	Rating       int    // 0 (unrated) to MaxRating stars
+	Priority     string // one of Priorities; empty reads as normal
 	ReadingState string
 	AddedAt      time.Time
 	LastOpenedAt time.Time
+	// ProgressPage and ProgressPercent are set with SetProgress; Upsert
+	// leaves them alone.
+	ProgressPage    int
+	ProgressPercent int
+	// Fields holds user-defined custom field values by name. A nil map leaves
+	// the stored values untouched on Upsert; an empty map clears them.
+	Fields map[string]string
+	// Identifiers lists the paper's DOI, arXiv ID, ISBNs and so on, sorted by
+	// scheme. Those implied by DOI and URL are always included. A nil slice
+	// leaves the others untouched on Upsert; an empty one clears them.
+	Identifiers []Identifier
+	// Version is bumped by every change to the fields Upsert writes. Upsert
+	// refuses to overwrite a record whose version moved on since it was read;
+	// zero skips that check.
+	Version   int64
+	UpdatedAt time.Time
 }
 
 const defaultReadingState = "unread"
@@ -50,7 +69,13 @@ const metadataSelectColumns = `
   COALESCE(favorite, 0),
   COALESCE(to_read, 0),
   COALESCE(added_at, 0),
-  COALESCE(last_opened_at, 0)
+  COALESCE(last_opened_at, 0),
+  COALESCE(progress_page, 0),
+  COALESCE(progress_percent, 0),
+  COALESCE(rating, 0),
+  IFNULL(priority, ''),
+  COALESCE(version, 1),
+  COALESCE(updated_at, 0)
 `
 
 func Open(dbPath string) (*Store, error) {
@@ -59,84 +84,23 @@ func Open(dbPath string) (*Store, error) {
 	}
 
 	cleanPath := filepath.Clean(dbPath)
-	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)", filepath.ToSlash(cleanPath))
+	// WAL lets other gorae processes read while one writes, and immediate
+	// transactions take the write lock up front so two writers queue on
+	// busy_timeout instead of failing halfway through.
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_txlock=immediate", filepath.ToSlash(cleanPath))
 	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, err
 	}
 
 	s := &Store{db: db}
-	if err := s.initSchema(); err != nil {
+	if err := s.migrate(context.Background()); err != nil {
 		db.Close()
 		return nil, err
 	}
 	return s, nil
 }
 
-func (s *Store) initSchema() error {
-	_, err := s.db.Exec(`
-CREATE TABLE IF NOT EXISTS metadata (
-  path   TEXT PRIMARY KEY,
-  title  TEXT,
-  author TEXT,
-  year   TEXT,
-  published TEXT,
-  url    TEXT,
-  doi    TEXT,
-  abstract TEXT,
-  tag TEXT,
-  reading_state TEXT,
-  favorite INTEGER DEFAULT 0,
-  to_read INTEGER DEFAULT 0,
-  added_at INTEGER,
-  last_opened_at INTEGER
-);
-`)
-	if err != nil {
-		return err
-	}
-	if err := s.ensureColumn("favorite", "INTEGER DEFAULT 0"); err != nil {
-		return err
-	}
-	if err := s.ensureColumn("to_read", "INTEGER DEFAULT 0"); err != nil {
-		return err
-	}
-	if err := s.ensureColumn("reading_state", "TEXT"); err != nil {
-		return err
-	}
-	if err := s.ensureColumn("published", "TEXT"); err != nil {
-		return err
-	}
-	if err := s.ensureColumn("url", "TEXT"); err != nil {
-		return err
-	}
-	if err := s.ensureColumn("doi", "TEXT"); err != nil {
-		return err
-	}
-	if err := s.ensureColumn("abstract", "TEXT"); err != nil {
-		return err
-	}
-	if err := s.ensureColumn("tag", "TEXT"); err != nil {
-		return err
-	}
-	if err := s.ensureColumn("added_at", "INTEGER"); err != nil {
-		return err
-	}
-	return s.ensureColumn("last_opened_at", "INTEGER")
-}
-
-func (s *Store) ensureColumn(name, typ string) error {
-	query := fmt.Sprintf(`ALTER TABLE metadata ADD COLUMN %s %s`, name, typ)
-	_, err := s.db.Exec(query)
-	if err != nil {
-		errLower := strings.ToLower(err.Error())
-		if strings.Contains(errLower, "duplicate column name") {
-			return nil
-		}
-	}
-	return err
-}
-
 func (s *Store) Get(ctx context.Context, path string) (*Metadata, error) {
 	row := s.db.QueryRowContext(
 		ctx,
@@ -150,13 +114,29 @@ func (s *Store) Get(ctx context.Context, path string) (*Metadata, error) {
 	case sql.ErrNoRows:
 		return nil, nil
 	case nil:
-		return &m, nil
 	default:
 		return nil, err
 	}
+	if m.Authors, err = s.loadAuthors(ctx, s.db, path); err != nil {
+		return nil, err
+	}
+	if m.Fields, err = s.loadFields(ctx, s.db, path); err != nil {
+		return nil, err
+	}
+	if m.Identifiers, err = loadIdentifiers(ctx, s.db, path); err != nil {
+		return nil, err
+	}
+	return &m, nil
 }
 
+// Upsert writes m as a manual edit. See UpsertFrom.
 func (s *Store) Upsert(ctx context.Context, m *Metadata) error {
+	return s.UpsertFrom(ctx, m, SourceManual)
+}
+
+// UpsertFrom writes m and records the changed fields in the metadata history
+// under source (one of the Source constants).
+func (s *Store) UpsertFrom(ctx context.Context, m *Metadata, source string) error {
 	favorite := 0
 	if m.Favorite {
 		favorite = 1
@@ -166,14 +146,49 @@ func (s *Store) Upsert(ctx context.Context, m *Metadata) error {
 		toRead = 1
 	}
 	state := normalizeReadingState(m.ReadingState)
+	rating := clampRating(m.Rating)
+	priority, _ := NormalizePriority(m.Priority)
 	addedAt := m.AddedAt
 	if addedAt.IsZero() {
 		addedAt = time.Now()
 	}
 	addedAtUnix := addedAt.Unix()
-	_, err := s.db.ExecContext(ctx, `
-INSERT INTO metadata (path, title, author, year, published, url, doi, abstract, tag, reading_state, favorite, to_read, added_at)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	if len(m.Authors) > 0 && strings.TrimSpace(m.Author) == "" {
+		m.Author = FormatAuthors(m.Authors)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var before *Metadata
+	prev, err := scanMetadataRow(tx.QueryRowContext(ctx, `SELECT`+metadataSelectColumns+` FROM metadata WHERE path = ?`, m.Path))
+	switch err {
+	case nil:
+		before = &prev
+	case sql.ErrNoRows:
+	default:
+		return err
+	}
+	if before != nil && m.Version != 0 && m.Version != before.Version {
+		return fmt.Errorf("%s: %w", filepath.Base(m.Path), ErrConflict)
+	}
+	if before != nil && m.Fields != nil {
+		if before.Fields, err = s.loadFields(ctx, tx, m.Path); err != nil {
+			return err
+		}
+	}
+	if before != nil {
+		if before.Identifiers, err = loadIdentifiers(ctx, tx, m.Path); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+INSERT INTO metadata (path, title, author, year, published, url, doi, abstract, tag, reading_state, favorite, to_read, rating, priority, added_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 ON CONFLICT(path) DO UPDATE SET
   title    = excluded.title,
   author   = excluded.author,
@@ -186,24 +201,61 @@ ON CONFLICT(path) DO UPDATE SET
   reading_state = excluded.reading_state,
   favorite = excluded.favorite,
   to_read  = excluded.to_read,
+  rating   = excluded.rating,
+  priority = excluded.priority,
   added_at = CASE
                 WHEN COALESCE(metadata.added_at, 0) = 0 THEN excluded.added_at
                 ELSE metadata.added_at
              END
 `,
-		m.Path, m.Title, m.Author, m.Year, m.Published, m.URL, m.DOI, m.Abstract, m.Tag, state, favorite, toRead, addedAtUnix,
+		m.Path, m.Title, m.Author, m.Year, m.Published, m.URL, m.DOI, m.Abstract, m.Tag, state, favorite, toRead, rating, priority, addedAtUnix,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if err := s.syncAuthors(ctx, tx, m); err != nil {
+		return err
+	}
+	if err := s.syncTags(ctx, tx, m); err != nil {
+		return err
+	}
+	if m.Fields != nil {
+		if err := writeFields(ctx, tx, m); err != nil {
+			return err
+		}
+	}
+	ids, err := writeIdentifiers(ctx, tx, before, m)
+	if err != nil {
+		return err
+	}
+	if m.Identifiers != nil {
+		m.Identifiers = ids
+	}
+	if err := recordHistory(ctx, tx, before, m, source); err != nil {
+		return err
+	}
+	var updatedAt int64
+	if err := tx.QueryRowContext(ctx, `SELECT version, COALESCE(updated_at, 0) FROM metadata WHERE path = ?`, m.Path).Scan(&m.Version, &updatedAt); err != nil {
+		return err
+	}
+	m.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+	return tx.Commit()
 }
 
 type rowScanner interface {
 	Scan(dest ...any) error
 }
 
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 func scanMetadataRow(scanner rowScanner) (Metadata, error) {
 	md := Metadata{}
 	var favorite, toRead int64
-	var addedAt, openedAt sql.NullInt64
+	var addedAt, openedAt, updatedAt sql.NullInt64
 	err := scanner.Scan(
 		&md.Path,
 		&md.Title,
@@ -219,6 +271,12 @@ func scanMetadataRow(scanner rowScanner) (Metadata, error) {
 		&toRead,
 		&addedAt,
 		&openedAt,
+		&md.ProgressPage,
+		&md.ProgressPercent,
+		&md.Rating,
+		&md.Priority,
+		&md.Version,
+		&updatedAt,
 	)
 	if err != nil {
 		return Metadata{}, err
@@ -226,23 +284,23 @@ func scanMetadataRow(scanner rowScanner) (Metadata, error) {
 	md.ReadingState = normalizeReadingState(md.ReadingState)
 	md.Favorite = favorite != 0
 	md.ToRead = toRead != 0
+	md.Priority, _ = NormalizePriority(md.Priority)
 	if addedAt.Valid && addedAt.Int64 > 0 {
 		md.AddedAt = time.Unix(addedAt.Int64, 0).UTC()
 	}
 	if openedAt.Valid && openedAt.Int64 > 0 {
 		md.LastOpenedAt = time.Unix(openedAt.Int64, 0).UTC()
 	}
+	if updatedAt.Valid && updatedAt.Int64 > 0 {
+		md.UpdatedAt = time.Unix(updatedAt.Int64, 0).UTC()
+	}
 	return md, nil
 }
 
-func (s *Store) listByFlag(ctx context.Context, column string) ([]Metadata, error) {
-	query := fmt.Sprintf(`
-SELECT%[1]s
-  FROM metadata
- WHERE COALESCE(%s, 0) = 1
-ORDER BY LOWER(title), path
-`, metadataSelectColumns, column)
-	rows, err := s.db.QueryContext(ctx, query)
+func (s *Store) listWhere(ctx context.Context, clause string, args ...any) ([]Metadata, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT`+metadataSelectColumns+`
+  FROM metadata`+clause, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -263,37 +321,15 @@ ORDER BY LOWER(title), path
 }
 
 func (s *Store) ListFavorites(ctx context.Context) ([]Metadata, error) {
-	return s.listByFlag(ctx, "favorite")
+	return s.Query(ctx, Filter{Favorite: true})
 }
 
 func (s *Store) ListToRead(ctx context.Context) ([]Metadata, error) {
-	return s.listByFlag(ctx, "to_read")
+	return s.Query(ctx, Filter{ToRead: true})
 }
 
 func (s *Store) ListByReadingState(ctx context.Context, state string) ([]Metadata, error) {
-	state = normalizeReadingState(state)
-	query := `
-SELECT` + metadataSelectColumns + `
-  FROM metadata
- WHERE LOWER(IFNULL(reading_state, '')) = LOWER(?)
- ORDER BY LOWER(title), path`
-	rows, err := s.db.QueryContext(ctx, query, state)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	results := make([]Metadata, 0)
-	for rows.Next() {
-		md, err := scanMetadataRow(rows)
-		if err != nil {
-			return nil, err
-		}
-		results = append(results, md)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return results, nil
+	return s.Query(ctx, Filter{States: []string{state}})
 }
 
 func normalizeReadingState(value string) string {
@@ -334,30 +370,11 @@ func (s *Store) ListRecentlyOpened(ctx context.Context, limit int) ([]Metadata,
 	if limit <= 0 {
 		limit = 20
 	}
-	query := `
-SELECT` + metadataSelectColumns + `
-  FROM metadata
- WHERE COALESCE(last_opened_at, 0) > 0
- ORDER BY last_opened_at DESC, LOWER(title), path
- LIMIT ?`
-	rows, err := s.db.QueryContext(ctx, query, limit)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	results := make([]Metadata, 0, limit)
-	for rows.Next() {
-		md, err := scanMetadataRow(rows)
-		if err != nil {
-			return nil, err
-		}
-		results = append(results, md)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return results, nil
+	return s.Query(ctx, Filter{
+		OpenedAfter: time.Unix(1, 0), // opened at all
+		Sort:        []Sort{{Field: SortOpened, Desc: true}, {Field: SortTitle}},
+		Limit:       limit,
+	})
 }
 
 func (s *Store) Close() error {
@@ -368,8 +385,29 @@ func (s *Store) MovePath(ctx context.Context, oldPath, newPath string) error {
 	if oldPath == newPath {
 		return nil
 	}
-	_, err := s.db.ExecContext(ctx, `UPDATE metadata SET path = ? WHERE path = ?`, newPath, oldPath)
-	return err
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := movePathTx(ctx, tx, oldPath, newPath); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func movePathTx(ctx context.Context, tx *sql.Tx, oldPath, newPath string) error {
+	for _, stmt := range []string{
+		`UPDATE metadata SET path = ? WHERE path = ?`,
+		`UPDATE OR IGNORE attachments SET target = ? WHERE kind = 'file' AND target = ?`,
+		`UPDATE OR IGNORE fulltext_files SET path = ? WHERE path = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt, newPath, oldPath); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *Store) DeletePath(ctx context.Context, path string) error {
@@ -394,12 +432,30 @@ func (s *Store) MoveTree(ctx context.Context, oldDir, newDir string) error {
 	}
 	start := utf8.RuneCountInString(oldPrefix) + 1
 	pattern := escapeLike(oldPrefix) + "%"
-	_, err = s.db.ExecContext(ctx, `
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range []string{`
 UPDATE metadata
 SET path = ?1 || substr(path, ?2)
 WHERE path LIKE ?3 ESCAPE '\'
-	`, newPrefix, start, pattern)
-	return err
+	`, `
+UPDATE OR IGNORE attachments
+SET target = ?1 || substr(target, ?2)
+WHERE kind = 'file' AND target LIKE ?3 ESCAPE '\'
+	`, `
+UPDATE OR IGNORE fulltext_files
+SET path = ?1 || substr(path, ?2)
+WHERE path LIKE ?3 ESCAPE '\'
+	`} {
+		if _, err := tx.ExecContext(ctx, stmt, newPrefix, start, pattern); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func (s *Store) DeleteTree(ctx context.Context, dir string) error {
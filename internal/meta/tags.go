@@ -0,0 +1,318 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TagSeparator splits the levels of a hierarchical tag such as "ml/nlp".
+const TagSeparator = "/"
+
+// ErrTagExists is returned by RenameTag when the new name is already in use;
+// MergeTags is the operation for folding one tag into another.
+var ErrTagExists = errors.New("tag already exists")
+
+// TagSummary is a tag together with the number of papers carrying it.
+type TagSummary struct {
+	Name   string
+	Papers int
+}
+
+// SplitTags parses the free-text tag field. Tags may be separated by commas,
+// semicolons or pipes; hierarchy levels are trimmed and duplicates (compared
+// case-insensitively) are dropped.
+func SplitTags(raw string) []string {
+	raw = strings.NewReplacer(";", ",", "|", ",").Replace(raw)
+	parts := strings.Split(raw, ",")
+	out := make([]string, 0, len(parts))
+	seen := make(map[string]bool, len(parts))
+	for _, part := range parts {
+		tag := NormalizeTag(part)
+		if tag == "" {
+			continue
+		}
+		key := strings.ToLower(tag)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, tag)
+	}
+	return out
+}
+
+// FormatTags joins tags the way gorae stores Metadata.Tag.
+func FormatTags(tags []string) string {
+	return strings.Join(tags, ", ")
+}
+
+// NormalizeTag trims whitespace around a tag and each of its hierarchy levels
+// and drops empty levels, so " ml / nlp/ " becomes "ml/nlp".
+func NormalizeTag(tag string) string {
+	levels := strings.Split(tag, TagSeparator)
+	clean := make([]string, 0, len(levels))
+	for _, level := range levels {
+		level = normalizeName(level)
+		if level != "" {
+			clean = append(clean, level)
+		}
+	}
+	return strings.Join(clean, TagSeparator)
+}
+
+// TagMatches reports whether tag equals query or is one of its descendants,
+// e.g. "ml/nlp" matches the query "ml".
+func TagMatches(tag, query string, caseSensitive bool) bool {
+	tag = NormalizeTag(tag)
+	query = NormalizeTag(query)
+	if tag == "" || query == "" {
+		return false
+	}
+	if !caseSensitive {
+		tag = strings.ToLower(tag)
+		query = strings.ToLower(query)
+	}
+	return tag == query || strings.HasPrefix(tag, query+TagSeparator)
+}
+
+func tagNameKey(tag string) string {
+	return strings.ToLower(NormalizeTag(tag))
+}
+
+// writeTags replaces the tag links of path with tags.
+func writeTags(ctx context.Context, tx *sql.Tx, path string, tags []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM paper_tags WHERE path = ?`, path); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		key := tagNameKey(tag)
+		if key == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO tags (name, name_key) VALUES (?, ?) ON CONFLICT(name_key) DO NOTHING`,
+			tag, key,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+INSERT OR IGNORE INTO paper_tags (path, tag_id)
+SELECT ?, id FROM tags WHERE name_key = ?`, path, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func pruneTags(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM paper_tags)`)
+	return err
+}
+
+// syncTags rebuilds the tag links of md from its free-text tag field, which
+// stays the source of truth for editing.
+func (s *Store) syncTags(ctx context.Context, tx *sql.Tx, md *Metadata) error {
+	if err := writeTags(ctx, tx, md.Path, SplitTags(md.Tag)); err != nil {
+		return err
+	}
+	return pruneTags(ctx, tx)
+}
+
+// ListTags returns every tag in use with the number of papers carrying it,
+// ordered by name so that children follow their parent.
+func (s *Store) ListTags(ctx context.Context) ([]TagSummary, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT t.name, COUNT(pt.path)
+  FROM tags t
+  JOIN paper_tags pt ON pt.tag_id = t.id
+ GROUP BY t.id
+ ORDER BY t.name_key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := make([]TagSummary, 0)
+	for rows.Next() {
+		var sum TagSummary
+		if err := rows.Scan(&sum.Name, &sum.Papers); err != nil {
+			return nil, err
+		}
+		results = append(results, sum)
+	}
+	return results, rows.Err()
+}
+
+// ListByTag returns the papers tagged with tag or any of its descendants.
+func (s *Store) ListByTag(ctx context.Context, tag string) ([]Metadata, error) {
+	key := tagNameKey(tag)
+	return s.listWhere(ctx, `
+ WHERE path IN (
+   SELECT pt.path
+     FROM paper_tags pt
+     JOIN tags t ON t.id = pt.tag_id
+    WHERE t.name_key = ? OR t.name_key LIKE ? ESCAPE '\'
+ )
+ ORDER BY LOWER(title), path`, key, escapeLike(key+TagSeparator)+"%")
+}
+
+// RenameTag renames from, together with its descendants, to to. It returns
+// the number of papers updated and ErrTagExists if to is already in use.
+func (s *Store) RenameTag(ctx context.Context, from, to string) (int, error) {
+	from, to = NormalizeTag(from), NormalizeTag(to)
+	if from == "" || to == "" {
+		return 0, fmt.Errorf("tag names cannot be empty")
+	}
+	if tagNameKey(from) != tagNameKey(to) {
+		var exists int
+		if err := s.db.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM tags WHERE name_key = ?`, tagNameKey(to),
+		).Scan(&exists); err != nil {
+			return 0, err
+		}
+		if exists > 0 {
+			return 0, fmt.Errorf("%w: %s", ErrTagExists, to)
+		}
+	}
+	return s.retag(ctx, []string{from}, to)
+}
+
+// MergeTags folds every source tag, with its descendants, into target. The
+// target may already exist. It returns the number of papers updated.
+func (s *Store) MergeTags(ctx context.Context, sources []string, target string) (int, error) {
+	target = NormalizeTag(target)
+	if target == "" || len(sources) == 0 {
+		return 0, fmt.Errorf("tag names cannot be empty")
+	}
+	clean := make([]string, 0, len(sources))
+	for _, src := range sources {
+		if src = NormalizeTag(src); src != "" {
+			clean = append(clean, src)
+		}
+	}
+	if len(clean) == 0 {
+		return 0, fmt.Errorf("tag names cannot be empty")
+	}
+	return s.retag(ctx, clean, target)
+}
+
+// retag rewrites the tag field and links of every paper carrying one of
+// sources (or a descendant), replacing the source prefix with target.
+func (s *Store) retag(ctx context.Context, sources []string, target string) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT path, IFNULL(tag, '') FROM metadata WHERE TRIM(IFNULL(tag, '')) <> ''`)
+	if err != nil {
+		return 0, err
+	}
+	type pending struct {
+		path string
+		old  string
+		tags []string
+	}
+	var updates []pending
+	for rows.Next() {
+		var path, raw string
+		if err := rows.Scan(&path, &raw); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		tags := SplitTags(raw)
+		changed := false
+		for i, tag := range tags {
+			for _, src := range sources {
+				if !TagMatches(tag, src, false) {
+					continue
+				}
+				tags[i] = target + tag[len(src):]
+				changed = true
+				break
+			}
+		}
+		if changed {
+			updates = append(updates, pending{path: path, old: raw, tags: SplitTags(FormatTags(tags))})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, u := range updates {
+		if _, err := tx.ExecContext(ctx, `UPDATE metadata SET tag = ? WHERE path = ?`, FormatTags(u.tags), u.path); err != nil {
+			return 0, err
+		}
+		if err := writeTags(ctx, tx, u.path, u.tags); err != nil {
+			return 0, err
+		}
+		before := &Metadata{Path: u.path, Tag: u.old}
+		after := &Metadata{Path: u.path, Tag: FormatTags(u.tags)}
+		if err := recordHistory(ctx, tx, before, after, SourceManual); err != nil {
+			return 0, err
+		}
+	}
+	// A case-only rename keeps the same key, so update the display name too.
+	if _, err := tx.ExecContext(ctx, `UPDATE tags SET name = ? WHERE name_key = ?`, target, tagNameKey(target)); err != nil {
+		return 0, err
+	}
+	if err := pruneTags(ctx, tx); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(updates), nil
+}
+
+// migrateTags introduces the tags tables and backfills them from the
+// free-text tag column.
+func migrateTags(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS tags (
+  id       INTEGER PRIMARY KEY,
+  name     TEXT NOT NULL,
+  name_key TEXT NOT NULL UNIQUE
+);
+CREATE TABLE IF NOT EXISTS paper_tags (
+  path   TEXT NOT NULL REFERENCES metadata(path) ON UPDATE CASCADE ON DELETE CASCADE,
+  tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  PRIMARY KEY (path, tag_id)
+);
+CREATE INDEX IF NOT EXISTS paper_tags_tag ON paper_tags(tag_id);
+`); err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT path, tag FROM metadata WHERE TRIM(IFNULL(tag, '')) <> ''`)
+	if err != nil {
+		return err
+	}
+	type pending struct {
+		path string
+		tag  string
+	}
+	var backlog []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.path, &p.tag); err != nil {
+			rows.Close()
+			return err
+		}
+		backlog = append(backlog, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, p := range backlog {
+		if err := writeTags(ctx, tx, p.path, SplitTags(p.tag)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
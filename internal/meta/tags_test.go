@@ -0,0 +1,82 @@
+package meta_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestSplitTags(t *testing.T) {
+	got := meta.SplitTags(" ml / nlp; vision|ML/NLP, ,rl")
+	want := []string{"ml/nlp", "vision", "rl"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SplitTags = %v, want %v", got, want)
+	}
+	if !meta.TagMatches("ml/nlp", "ML", false) {
+		t.Fatalf("expected child tag to match parent query")
+	}
+	if meta.TagMatches("mlops", "ml", false) {
+		t.Fatalf("prefix without separator must not match")
+	}
+}
+
+func TestRenameAndMergeTags(t *testing.T) {
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	papers := map[string]string{
+		"/lib/a.pdf": "llm, ml/nlp",
+		"/lib/b.pdf": "ml",
+		"/lib/c.pdf": "language-models; vision",
+	}
+	for path, tag := range papers {
+		if err := store.Upsert(ctx, &meta.Metadata{Path: path, Tag: tag}); err != nil {
+			t.Fatalf("upsert %s: %v", path, err)
+		}
+	}
+
+	list, err := store.ListByTag(ctx, "ml")
+	if err != nil {
+		t.Fatalf("list by tag: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected ml to match itself and children, got %d papers", len(list))
+	}
+
+	if _, err := store.RenameTag(ctx, "llm", "language-models"); !errors.Is(err, meta.ErrTagExists) {
+		t.Fatalf("expected ErrTagExists, got %v", err)
+	}
+	n, err := store.MergeTags(ctx, []string{"llm"}, "language-models")
+	if err != nil || n != 1 {
+		t.Fatalf("merge: n=%d err=%v", n, err)
+	}
+	n, err = store.RenameTag(ctx, "ml", "ai")
+	if err != nil || n != 2 {
+		t.Fatalf("rename: n=%d err=%v", n, err)
+	}
+
+	a, err := store.Get(ctx, "/lib/a.pdf")
+	if err != nil {
+		t.Fatalf("get a: %v", err)
+	}
+	if a.Tag != "language-models, ai/nlp" {
+		t.Fatalf("unexpected tag field after rewrite: %q", a.Tag)
+	}
+
+	tags, err := store.ListTags(ctx)
+	if err != nil {
+		t.Fatalf("list tags: %v", err)
+	}
+	want := []meta.TagSummary{{"ai", 1}, {"ai/nlp", 1}, {"language-models", 2}, {"vision", 1}}
+	if !reflect.DeepEqual(tags, want) {
+		t.Fatalf("ListTags = %v, want %v", tags, want)
+	}
+}
@@ -0,0 +1,77 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// ErrConflict is returned by Upsert when the record was changed (by another
+// gorae process, say) since the version the caller read it at.
+var ErrConflict = errors.New("metadata was changed elsewhere since it was loaded")
+
+// versionedColumns are the metadata columns Upsert writes. Updating any of
+// them bumps the row version, whoever does it.
+var versionedColumns = []string{
+	"title", "author", "year", "published", "url", "doi", "abstract", "tag",
+	"reading_state", "favorite", "to_read", "rating", "priority",
+}
+
+// Generation returns a counter that grows with every change to the library
+// by any process: metadata, custom fields or collection membership. Callers
+// compare it with an earlier value to know whether their caches are stale.
+func (s *Store) Generation(ctx context.Context) (int64, error) {
+	var gen int64
+	err := s.db.QueryRowContext(ctx, `SELECT generation FROM store_generation WHERE id = 1`).Scan(&gen)
+	return gen, err
+}
+
+func migrateRowVersions(ctx context.Context, tx *sql.Tx) error {
+	if err := addColumnIfMissing(ctx, tx, "metadata", "version", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(ctx, tx, "metadata", "updated_at", "INTEGER"); err != nil {
+		return err
+	}
+
+	const bump = `UPDATE store_generation SET generation = generation + 1 WHERE id = 1;`
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS store_generation (
+  id         INTEGER PRIMARY KEY CHECK (id = 1),
+  generation INTEGER NOT NULL
+)`,
+		`INSERT OR IGNORE INTO store_generation (id, generation) VALUES (1, 0)`,
+		`CREATE TRIGGER IF NOT EXISTS metadata_version AFTER UPDATE OF ` + strings.Join(versionedColumns, ", ") + ` ON metadata
+BEGIN
+  UPDATE metadata SET version = OLD.version + 1, updated_at = CAST(strftime('%s', 'now') AS INTEGER) WHERE path = NEW.path;
+END`,
+		`CREATE TRIGGER IF NOT EXISTS metadata_inserted AFTER INSERT ON metadata
+BEGIN
+  UPDATE metadata SET updated_at = CAST(strftime('%s', 'now') AS INTEGER) WHERE path = NEW.path;
+  ` + bump + `
+END`,
+	}
+	// Every other change only needs to move the generation.
+	for _, t := range []struct{ table, event string }{
+		{"metadata", "UPDATE"},
+		{"metadata", "DELETE"},
+		{"custom_fields", "INSERT"},
+		{"custom_fields", "UPDATE"},
+		{"custom_fields", "DELETE"},
+		{"collection_items", "INSERT"},
+		{"collection_items", "DELETE"},
+	} {
+		stmts = append(stmts, `CREATE TRIGGER IF NOT EXISTS `+t.table+`_`+strings.ToLower(t.event)+`_generation
+AFTER `+t.event+` ON `+t.table+`
+BEGIN
+  `+bump+`
+END`)
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package meta_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gorae/internal/meta"
+)
+
+func TestUpsertDetectsConcurrentEdits(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "meta.db")
+	first, err := meta.Open(dbPath)
+	if err != nil {
+		t.Fatalf("open first: %v", err)
+	}
+	t.Cleanup(func() { _ = first.Close() })
+	second, err := meta.Open(dbPath)
+	if err != nil {
+		t.Fatalf("open second: %v", err)
+	}
+	t.Cleanup(func() { _ = second.Close() })
+	ctx := context.Background()
+
+	raw, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open raw db: %v", err)
+	}
+	defer raw.Close()
+	var mode string
+	if err := raw.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil || mode != "wal" {
+		t.Fatalf("expected WAL journal mode, got %q (%v)", mode, err)
+	}
+
+	md := meta.Metadata{Path: "/lib/paper.pdf", Title: "Draft"}
+	if err := first.Upsert(ctx, &md); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if md.Version != 1 || md.UpdatedAt.IsZero() {
+		t.Fatalf("expected version 1 with a timestamp, got %d %v", md.Version, md.UpdatedAt)
+	}
+	gen, err := first.Generation(ctx)
+	if err != nil {
+		t.Fatalf("generation: %v", err)
+	}
+
+	// Both processes read version 1; the second one saves first.
+	mine, err := first.Get(ctx, md.Path)
+	if err != nil || mine == nil {
+		t.Fatalf("get: %v", err)
+	}
+	theirs, err := second.Get(ctx, md.Path)
+	if err != nil || theirs == nil {
+		t.Fatalf("get: %v", err)
+	}
+	theirs.Title = "Their title"
+	if err := second.Upsert(ctx, theirs); err != nil {
+		t.Fatalf("their upsert: %v", err)
+	}
+	if theirs.Version != 2 {
+		t.Fatalf("expected version 2, got %d", theirs.Version)
+	}
+	if next, err := first.Generation(ctx); err != nil || next <= gen {
+		t.Fatalf("expected the generation to move past %d, got %d (%v)", gen, next, err)
+	}
+
+	mine.Title = "My title"
+	if err := first.Upsert(ctx, mine); !errors.Is(err, meta.ErrConflict) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	got, err := first.Get(ctx, md.Path)
+	if err != nil || got.Title != "Their title" {
+		t.Fatalf("expected their edit to survive, got %+v (%v)", got, err)
+	}
+
+	// A zero version skips the check.
+	blind := meta.Metadata{Path: md.Path, Title: "Blind write"}
+	if err := first.Upsert(ctx, &blind); err != nil {
+		t.Fatalf("blind upsert: %v", err)
+	}
+	if blind.Version != 3 {
+		t.Fatalf("expected version 3, got %d", blind.Version)
+	}
+}